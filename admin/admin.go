@@ -0,0 +1,157 @@
+// Package admin contains the operator facing http handlers used to inspect
+// and repair the state kept by the http server.
+package admin
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/dedup"
+	"github.com/dropezy/storefront-backend/http/errorlog"
+	"github.com/dropezy/storefront-backend/http/jobs"
+	"github.com/dropezy/storefront-backend/http/payload"
+	"github.com/dropezy/storefront-backend/http/selftest"
+	"github.com/dropezy/storefront-backend/http/throughput"
+)
+
+const handlerName = "admin"
+
+// Handler serves the admin endpoints, every request must carry the
+// X-Admin-Key header.
+type Handler struct {
+	authKey    string
+	dedupStore dedup.KVStore
+	jobStore   jobs.ResultStore
+
+	// throughput is the webhook counter reported by HandleThroughput.
+	throughput *throughput.Counter
+	// errorLog keeps the provider errors reported by HandleErrors.
+	errorLog *errorlog.Recorder
+	// selfTest runs the provider self tests of HandleSelfTest.
+	selfTest *selftest.Runner
+
+	// replayKey, replayLoader and replayHandlers serve HandleReplay.
+	replayKey      string
+	replayLoader   payload.Loader
+	replayHandlers map[string]http.Handler
+}
+
+// Option configures optional behaviour of the Handler.
+type Option func(*Handler)
+
+// WithThroughput reports the counts of c on the throughput endpoint.
+func WithThroughput(c *throughput.Counter) Option {
+	return func(h *Handler) {
+		h.throughput = c
+	}
+}
+
+// WithErrors reports the errors kept by rec on the errors endpoint.
+func WithErrors(rec *errorlog.Recorder) Option {
+	return func(h *Handler) {
+		h.errorLog = rec
+	}
+}
+
+// WithSelfTest runs the self tests of r on the self test endpoint.
+func WithSelfTest(r *selftest.Runner) Option {
+	return func(h *Handler) {
+		h.selfTest = r
+	}
+}
+
+// WithReplay replays the payloads of l through handlers, keyed by provider,
+// on the replay endpoint. Replays are authorized by authKey rather than the
+// admin auth key.
+func WithReplay(authKey string, l payload.Loader, handlers map[string]http.Handler) Option {
+	return func(h *Handler) {
+		h.replayKey = authKey
+		h.replayLoader = l
+		h.replayHandlers = handlers
+	}
+}
+
+// NewHandler returns a new admin handler.
+func NewHandler(authKey string, dedupStore dedup.KVStore, jobStore jobs.ResultStore, opts ...Option) (*Handler, error) {
+	if authKey == "" {
+		return nil, ErrAuthKeyNotFound
+	}
+	h := &Handler{
+		authKey:    authKey,
+		dedupStore: dedupStore,
+		jobStore:   jobStore,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
+}
+
+// DedupResponse describes the state of a dedup key.
+type DedupResponse struct {
+	Key     string `json:"key"`
+	Present bool   `json:"present"`
+	Outcome string `json:"outcome,omitempty"`
+	// TTLSeconds is the remaining time before the key is evicted.
+	TTLSeconds int64 `json:"ttl_seconds,omitempty"`
+}
+
+// HandleDedupGet reports whether the {key} path variable is present in the
+// dedup store, its stored outcome and remaining ttl.
+func (h *Handler) HandleDedupGet(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context()).With().Str("handler", handlerName).Logger()
+
+	if err := validateHeaders(logger, r.Header, h.authKey); err != nil {
+		responseJSON(logger, w, http.StatusUnauthorized, &Response{Message: err.Error()})
+		return
+	}
+
+	key := mux.Vars(r)["key"]
+	if key == "" {
+		responseJSON(logger, w, http.StatusBadRequest, &Response{Message: ErrKeyIsRequired.Error()})
+		return
+	}
+
+	e, ok, err := h.dedupStore.Get(r.Context(), key)
+	if err != nil {
+		logger.Err(err).Str("key", key).Msg("failed to get dedup key")
+		responseJSON(logger, w, http.StatusInternalServerError, &Response{Message: "failed to get dedup key"})
+		return
+	}
+
+	res := &DedupResponse{Key: key, Present: ok}
+	if ok {
+		res.Outcome = e.Outcome
+		res.TTLSeconds = int64(e.TTL(time.Now()).Seconds())
+	}
+	responseJSON(logger, w, http.StatusOK, res)
+}
+
+// HandleDedupDelete evicts the {key} path variable from the dedup store so
+// the next delivery of the same callback is processed again.
+func (h *Handler) HandleDedupDelete(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context()).With().Str("handler", handlerName).Logger()
+
+	if err := validateHeaders(logger, r.Header, h.authKey); err != nil {
+		responseJSON(logger, w, http.StatusUnauthorized, &Response{Message: err.Error()})
+		return
+	}
+
+	key := mux.Vars(r)["key"]
+	if key == "" {
+		responseJSON(logger, w, http.StatusBadRequest, &Response{Message: ErrKeyIsRequired.Error()})
+		return
+	}
+
+	if err := h.dedupStore.Delete(r.Context(), key); err != nil {
+		logger.Err(err).Str("key", key).Msg("failed to delete dedup key")
+		responseJSON(logger, w, http.StatusInternalServerError, &Response{Message: "failed to delete dedup key"})
+		return
+	}
+
+	logger.Info().Str("key", key).Msg("successfully evicted dedup key")
+	responseJSON(logger, w, http.StatusOK, &Response{Message: "success"})
+}
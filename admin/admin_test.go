@@ -0,0 +1,124 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dropezy/storefront-backend/http/dedup"
+	"github.com/dropezy/storefront-backend/http/jobs"
+)
+
+const validAdminKey = "valid-x-admin-key"
+
+func newTestRouter(t *testing.T, store dedup.KVStore) *mux.Router {
+	return newTestRouterWithJobs(t, store, jobs.NewMemoryStore())
+}
+
+func newTestRouterWithJobs(t *testing.T, store dedup.KVStore, jobStore jobs.ResultStore) *mux.Router {
+	h, err := NewHandler(validAdminKey, store, jobStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/dedup/{key}", h.HandleDedupGet).Methods(http.MethodGet)
+	router.HandleFunc("/admin/dedup/{key}", h.HandleDedupDelete).Methods(http.MethodDelete)
+	router.HandleFunc("/admin/jobs/{id}/results", h.HandleJobResults).Methods(http.MethodGet)
+	return router
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Parallel()
+
+	if _, err := NewHandler("", dedup.NewMemoryStore(), jobs.NewMemoryStore()); err != ErrAuthKeyNotFound {
+		t.Fatalf("NewHandler(), got = %v, want = %v", err, ErrAuthKeyNotFound)
+	}
+}
+
+func TestHandleDedup(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	store := dedup.NewMemoryStore()
+	if err := store.Set(ctx, "trx-1", "success", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter(t, store)
+
+	do := func(method, key, adminKey string) *http.Response {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest(method, "/admin/dedup/"+key, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if adminKey != "" {
+			r.Header.Set("X-Admin-Key", adminKey)
+		}
+		router.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	t.Run("Present", func(t *testing.T) {
+		resp := do(http.MethodGet, "trx-1", validAdminKey)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("HandleDedupGet(), got = %v, want = %v", resp.StatusCode, http.StatusOK)
+		}
+
+		got := &DedupResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+			t.Fatal(err)
+		}
+		if !got.Present || got.Outcome != "success" {
+			t.Fatalf("HandleDedupGet(), got = %+v, want present with outcome success", got)
+		}
+		if got.TTLSeconds <= 0 || got.TTLSeconds > int64(time.Hour.Seconds()) {
+			t.Fatalf("HandleDedupGet(), got ttl = %v, want within an hour", got.TTLSeconds)
+		}
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		resp := do(http.MethodGet, "trx-2", validAdminKey)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("HandleDedupGet(), got = %v, want = %v", resp.StatusCode, http.StatusOK)
+		}
+
+		got := &DedupResponse{}
+		if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Present {
+			t.Fatalf("HandleDedupGet(), got = %+v, want not present", got)
+		}
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		for _, key := range []string{"", "invalid-x-admin-key"} {
+			if resp := do(http.MethodGet, "trx-1", key); resp.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("HandleDedupGet(), got = %v, want = %v", resp.StatusCode, http.StatusUnauthorized)
+			}
+			if resp := do(http.MethodDelete, "trx-1", key); resp.StatusCode != http.StatusUnauthorized {
+				t.Fatalf("HandleDedupDelete(), got = %v, want = %v", resp.StatusCode, http.StatusUnauthorized)
+			}
+		}
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		if err := store.Set(ctx, "trx-3", "failed", time.Hour); err != nil {
+			t.Fatal(err)
+		}
+
+		resp := do(http.MethodDelete, "trx-3", validAdminKey)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("HandleDedupDelete(), got = %v, want = %v", resp.StatusCode, http.StatusOK)
+		}
+		if _, ok, _ := store.Get(ctx, "trx-3"); ok {
+			t.Fatal("HandleDedupDelete(), key still present in store")
+		}
+	})
+}
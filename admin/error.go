@@ -0,0 +1,20 @@
+package admin
+
+import "errors"
+
+var (
+	ErrXAdminKeyIsRequired = errors.New("x admin key is required")
+	ErrInvalidXAdminKey    = errors.New("invalid x admin key")
+	ErrKeyIsRequired       = errors.New("key is required")
+
+	ErrXReplayKeyIsRequired = errors.New("x replay key is required")
+	ErrInvalidXReplayKey    = errors.New("invalid x replay key")
+	ErrReplayNotConfigured  = errors.New("replay not configured")
+	ErrUnknownProvider      = errors.New("unknown provider")
+
+	// ErrAuthKeyNotFound happens when no auth key is passed when initializing a new handler.
+	ErrAuthKeyNotFound = errors.New("auth key not found")
+
+	ErrMarshallingUnsuccessful     = errors.New("marshalling unsuccessful")
+	ErrWriteToResponseUnsuccessful = errors.New("write to response unsuccessful")
+)
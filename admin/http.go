@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/dropezy/storefront-backend/http/internal/validate"
+)
+
+// Response is the default response for admin requests.
+type Response struct {
+	Message string `json:"message"`
+}
+
+// responseJSON marshals v and writes it as the response body.
+func responseJSON(logger zerolog.Logger, w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	res, err := json.Marshal(v)
+	if err != nil {
+		logger.Err(ErrMarshallingUnsuccessful).Msg(ErrMarshallingUnsuccessful.Error())
+	}
+
+	w.WriteHeader(code)
+
+	if _, err = w.Write(res); err != nil {
+		logger.Err(ErrWriteToResponseUnsuccessful).Msg(ErrWriteToResponseUnsuccessful.Error())
+	}
+}
+
+// validateHeaders checks that X-Admin-Key is given and valid.
+func validateHeaders(logger zerolog.Logger, h http.Header, authKey string) error {
+	switch validate.CheckAPIKey(h, "X-Admin-Key", authKey) {
+	case nil:
+		return nil
+	case validate.ErrAPIKeyIsRequired:
+		logger.Err(ErrXAdminKeyIsRequired).Msg(ErrXAdminKeyIsRequired.Error())
+		return ErrXAdminKeyIsRequired
+	default:
+		logger.Err(ErrInvalidXAdminKey).Msg(ErrInvalidXAdminKey.Error())
+		return ErrInvalidXAdminKey
+	}
+}
+
+// validateReplayHeaders checks that X-Replay-Key is given and valid.
+func validateReplayHeaders(logger zerolog.Logger, h http.Header, replayKey string) error {
+	switch validate.CheckAPIKey(h, "X-Replay-Key", replayKey) {
+	case nil:
+		return nil
+	case validate.ErrAPIKeyIsRequired:
+		logger.Err(ErrXReplayKeyIsRequired).Msg(ErrXReplayKeyIsRequired.Error())
+		return ErrXReplayKeyIsRequired
+	default:
+		logger.Err(ErrInvalidXReplayKey).Msg(ErrInvalidXReplayKey.Error())
+		return ErrInvalidXReplayKey
+	}
+}
@@ -0,0 +1,100 @@
+package admin
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/jobs"
+	"github.com/dropezy/storefront-backend/http/middleware"
+)
+
+// flushEvery is the number of results written between each flush, so the
+// memory used stays bounded for huge jobs.
+const flushEvery = 100
+
+// HandleJobResults streams the results of the {id} job as NDJSON, one result
+// per line. The stream is gzip compressed when the client accepts it.
+func (h *Handler) HandleJobResults(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context()).With().Str("handler", handlerName).Logger()
+
+	if err := validateHeaders(logger, r.Header, h.authKey); err != nil {
+		responseJSON(logger, w, http.StatusUnauthorized, &Response{Message: err.Error()})
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	logger = logger.With().Str("job_id", id).Logger()
+
+	var (
+		out     io.Writer = w
+		gz      *gzip.Writer
+		started bool
+		written int
+	)
+	flusher, _ := w.(http.Flusher)
+
+	err := h.jobStore.Results(r.Context(), id, func(result interface{}) error {
+		// headers are only written with the first result, so a missing job
+		// can still be reported as 404.
+		if !started {
+			started = true
+			w.Header().Set("Content-Type", "application/x-ndjson")
+			middleware.AddVary(w.Header(), "Accept-Encoding")
+			if middleware.AcceptsGzip(r.Header) {
+				w.Header().Set("Content-Encoding", "gzip")
+				gz = gzip.NewWriter(w)
+				out = gz
+			}
+			w.WriteHeader(http.StatusOK)
+		}
+
+		b, err := json.Marshal(result)
+		if err != nil {
+			return err
+		}
+		if _, err := out.Write(append(b, '\n')); err != nil {
+			return err
+		}
+
+		if written++; written%flushEvery == 0 {
+			if gz != nil {
+				if err := gz.Flush(); err != nil {
+					return err
+				}
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, jobs.ErrJobNotFound):
+		responseJSON(logger, w, http.StatusNotFound, &Response{Message: err.Error()})
+		return
+	case err != nil && !started:
+		logger.Err(err).Msg("failed to get job results")
+		responseJSON(logger, w, http.StatusInternalServerError, &Response{Message: "failed to get job results"})
+		return
+	case err != nil:
+		// the status is already sent, the client will see a truncated stream.
+		logger.Err(err).Int("written", written).Msg("failed to stream job results")
+	case !started:
+		// job without results
+		w.Header().Set("Content-Type", "application/x-ndjson")
+		w.WriteHeader(http.StatusOK)
+	}
+
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			logger.Err(err).Msg("failed to close gzip writer")
+		}
+	}
+}
@@ -0,0 +1,106 @@
+package admin
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/dropezy/storefront-backend/http/dedup"
+	"github.com/dropezy/storefront-backend/http/jobs"
+)
+
+type testResult struct {
+	ReferenceID string `json:"reference_id"`
+	Status      string `json:"status"`
+}
+
+func TestHandleJobResults(t *testing.T) {
+	t.Parallel()
+
+	const total = 250
+
+	jobStore := jobs.NewMemoryStore()
+	for i := 0; i < total; i++ {
+		jobStore.Add("job-1", &testResult{ReferenceID: fmt.Sprintf("ref-%d", i), Status: "success"})
+	}
+	router := newTestRouterWithJobs(t, dedup.NewMemoryStore(), jobStore)
+
+	do := func(id, acceptEncoding string) *http.Response {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest(http.MethodGet, "/admin/jobs/"+id+"/results", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("X-Admin-Key", validAdminKey)
+		if acceptEncoding != "" {
+			r.Header.Set("Accept-Encoding", acceptEncoding)
+		}
+		router.ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	readResults := func(t *testing.T, body io.Reader) []*testResult {
+		var got []*testResult
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			res := &testResult{}
+			if err := json.Unmarshal(scanner.Bytes(), res); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, res)
+		}
+		if err := scanner.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return got
+	}
+
+	t.Run("Gzip", func(t *testing.T) {
+		t.Parallel()
+
+		resp := do("job-1", "deflate, gzip")
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("HandleJobResults(), got = %v, want = %v", resp.StatusCode, http.StatusOK)
+		}
+		if got := resp.Header.Get("Content-Encoding"); got != "gzip" {
+			t.Fatalf("HandleJobResults(), got encoding = %q, want = gzip", got)
+		}
+
+		gz, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := readResults(t, gz)
+		if len(got) != total {
+			t.Fatalf("HandleJobResults(), got %d results, want %d", len(got), total)
+		}
+		if got[total-1].ReferenceID != fmt.Sprintf("ref-%d", total-1) {
+			t.Fatalf("HandleJobResults(), got last result %+v", got[total-1])
+		}
+	})
+
+	t.Run("Identity", func(t *testing.T) {
+		t.Parallel()
+
+		resp := do("job-1", "")
+		if got := resp.Header.Get("Content-Encoding"); got != "" {
+			t.Fatalf("HandleJobResults(), got encoding = %q, want none", got)
+		}
+		if got := readResults(t, resp.Body); len(got) != total {
+			t.Fatalf("HandleJobResults(), got %d results, want %d", len(got), total)
+		}
+	})
+
+	t.Run("NotFound", func(t *testing.T) {
+		t.Parallel()
+
+		if resp := do("missing", "gzip"); resp.StatusCode != http.StatusNotFound {
+			t.Fatalf("HandleJobResults(), got = %v, want = %v", resp.StatusCode, http.StatusNotFound)
+		}
+	})
+}
@@ -0,0 +1,42 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/errorlog"
+)
+
+// ErrorsResponse is the last errors logged by a provider handler.
+type ErrorsResponse struct {
+	Provider string           `json:"provider"`
+	Errors   []errorlog.Entry `json:"errors"`
+}
+
+// HandleErrors reports the last errors logged by the handler of the
+// {provider} path variable, newest first.
+func (h *Handler) HandleErrors(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context()).With().Str("handler", handlerName).Logger()
+
+	if err := validateHeaders(logger, r.Header, h.authKey); err != nil {
+		responseJSON(logger, w, http.StatusUnauthorized, &Response{Message: err.Error()})
+		return
+	}
+
+	provider := mux.Vars(r)["provider"]
+	var (
+		entries []errorlog.Entry
+		ok      bool
+	)
+	if h.errorLog != nil {
+		entries, ok = h.errorLog.Last(provider)
+	}
+	if !ok {
+		responseJSON(logger, w, http.StatusNotFound, &Response{Message: ErrUnknownProvider.Error()})
+		return
+	}
+
+	responseJSON(logger, w, http.StatusOK, &ErrorsResponse{Provider: provider, Errors: entries})
+}
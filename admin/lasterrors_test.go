@@ -0,0 +1,72 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dropezy/storefront-backend/http/dedup"
+	"github.com/dropezy/storefront-backend/http/errorlog"
+	"github.com/dropezy/storefront-backend/http/jobs"
+)
+
+func TestHandleErrors(t *testing.T) {
+	t.Parallel()
+
+	rec := errorlog.NewRecorder(10)
+	rec.Record("mileapp", errorlog.Entry{Message: "failed to get order task", RequestID: "req-1"})
+	rec.Record("mileapp", errorlog.Entry{Message: "failed to update order task", RequestID: "req-2"})
+
+	h, err := NewHandler(validAdminKey, dedup.NewMemoryStore(), jobs.NewMemoryStore(), WithErrors(rec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/errors/{provider}", h.HandleErrors).Methods(http.MethodGet)
+
+	tests := []struct {
+		provider string
+		adminKey string
+		wantCode int
+		wantIDs  []string
+	}{
+		{provider: "mileapp", wantCode: http.StatusUnauthorized},
+		{provider: "mileapp", adminKey: validAdminKey, wantCode: http.StatusOK, wantIDs: []string{"req-2", "req-1"}},
+		{provider: "unknown", adminKey: validAdminKey, wantCode: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest(http.MethodGet, "/admin/errors/"+test.provider, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if test.adminKey != "" {
+			r.Header.Set("X-Admin-Key", test.adminKey)
+		}
+		router.ServeHTTP(w, r)
+
+		if w.Code != test.wantCode {
+			t.Fatalf("HandleErrors(%s), got = %v, want = %v", test.provider, w.Code, test.wantCode)
+		}
+		if test.wantCode != http.StatusOK {
+			continue
+		}
+
+		got := &ErrorsResponse{}
+		if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Provider != test.provider || len(got.Errors) != len(test.wantIDs) {
+			t.Fatalf("HandleErrors(%s), got = %+v", test.provider, got)
+		}
+		for i, id := range test.wantIDs {
+			if got.Errors[i].RequestID != id || got.Errors[i].Time.IsZero() {
+				t.Fatalf("HandleErrors(%s) errors[%d], got = %+v, want request ID %s", test.provider, i, got.Errors[i], id)
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/payload"
+)
+
+// ReplayResponse is the report of a replayed callback payload.
+type ReplayResponse struct {
+	Provider string `json:"provider"`
+	ID       string `json:"id"`
+	// Code and Body are the response of the provider handler.
+	Code int             `json:"code"`
+	Body json.RawMessage `json:"body,omitempty"`
+}
+
+// HandleReplay loads the stored payload {id} of the {provider} path
+// variables and feeds it back through the provider handler. It responds with
+// the code of the provider handler. Replays carry X-Replay-Key instead of
+// X-Admin-Key.
+//
+// Callbacks deduplicated by the provider handler, like midtrans, are only
+// processed again once their dedup key is evicted.
+func (h *Handler) HandleReplay(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context()).With().Str("handler", handlerName).Logger()
+
+	if h.replayKey == "" || h.replayLoader == nil {
+		responseJSON(logger, w, http.StatusNotFound, &Response{Message: ErrReplayNotConfigured.Error()})
+		return
+	}
+	if err := validateReplayHeaders(logger, r.Header, h.replayKey); err != nil {
+		responseJSON(logger, w, http.StatusUnauthorized, &Response{Message: err.Error()})
+		return
+	}
+
+	vars := mux.Vars(r)
+	provider, id := vars["provider"], vars["id"]
+	next, ok := h.replayHandlers[provider]
+	if !ok {
+		responseJSON(logger, w, http.StatusNotFound, &Response{Message: ErrUnknownProvider.Error()})
+		return
+	}
+
+	rec, err := h.replayLoader.Load(r.Context(), provider, id)
+	if errors.Is(err, payload.ErrPayloadNotFound) {
+		responseJSON(logger, w, http.StatusNotFound, &Response{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Err(err).Str("provider", provider).Str("id", id).Msg("failed to load payload")
+		responseJSON(logger, w, http.StatusInternalServerError, &Response{Message: "failed to load payload"})
+		return
+	}
+
+	req, err := rec.Request(r.Context())
+	if err != nil {
+		logger.Err(err).Str("provider", provider).Str("id", id).Msg("failed to rebuild payload request")
+		responseJSON(logger, w, http.StatusInternalServerError, &Response{Message: "failed to rebuild payload request"})
+		return
+	}
+
+	rw := httptest.NewRecorder()
+	next.ServeHTTP(rw, req)
+
+	res := &ReplayResponse{Provider: provider, ID: id, Code: rw.Code}
+	if b := rw.Body.Bytes(); json.Valid(b) {
+		res.Body = b
+	}
+	logger.Info().Str("provider", provider).Str("id", id).Int("code", rw.Code).Msg("replayed payload")
+	responseJSON(logger, w, rw.Code, res)
+}
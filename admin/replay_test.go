@@ -0,0 +1,115 @@
+package admin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dropezy/storefront-backend/http/dedup"
+	"github.com/dropezy/storefront-backend/http/jobs"
+	"github.com/dropezy/storefront-backend/http/payload"
+)
+
+const validReplayKey = "valid-x-replay-key"
+
+func TestHandleReplay(t *testing.T) {
+	t.Parallel()
+
+	sink := payload.NewFileSink(t.TempDir())
+	meta := payload.Meta{ID: "payload-1", Method: http.MethodPost, Path: "/shoptree/stock-update"}
+	if err := sink.Store(context.Background(), "shoptree", []byte(`[{"sku":"1"}]`), meta); err != nil {
+		t.Fatal(err)
+	}
+
+	var replayed []byte
+	shoptree := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		replayed, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{"message":"success"}`))
+	})
+
+	h, err := NewHandler(validAdminKey, dedup.NewMemoryStore(), jobs.NewMemoryStore(),
+		WithReplay(validReplayKey, sink, map[string]http.Handler{"shoptree": shoptree}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/replay/{provider}/{id}", h.HandleReplay).Methods(http.MethodPost)
+
+	tests := []struct {
+		desc      string
+		provider  string
+		id        string
+		replayKey string
+		wantCode  int
+	}{
+		{desc: "Replayed", provider: "shoptree", id: "payload-1", replayKey: validReplayKey, wantCode: http.StatusOK},
+		{desc: "AdminKey", provider: "shoptree", id: "payload-1", replayKey: validAdminKey, wantCode: http.StatusUnauthorized},
+		{desc: "NoKey", provider: "shoptree", id: "payload-1", wantCode: http.StatusUnauthorized},
+		{desc: "UnknownProvider", provider: "unknown", id: "payload-1", replayKey: validReplayKey, wantCode: http.StatusNotFound},
+		{desc: "UnknownPayload", provider: "shoptree", id: "unknown", replayKey: validReplayKey, wantCode: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		replayed = nil
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest(http.MethodPost, "/admin/replay/"+test.provider+"/"+test.id, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("X-Admin-Key", validAdminKey)
+		if test.replayKey != "" {
+			r.Header.Set("X-Replay-Key", test.replayKey)
+		}
+		router.ServeHTTP(w, r)
+
+		if w.Code != test.wantCode {
+			t.Fatalf("HandleReplay(%s), got = %v, want = %v", test.desc, w.Code, test.wantCode)
+		}
+		if test.wantCode != http.StatusOK {
+			if replayed != nil {
+				t.Fatalf("HandleReplay(%s), replayed = %s, want none", test.desc, replayed)
+			}
+			continue
+		}
+
+		got := &ReplayResponse{}
+		if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Code != http.StatusOK || !bytes.Equal(got.Body, []byte(`{"message":"success"}`)) {
+			t.Fatalf("HandleReplay(%s), got = %+v", test.desc, got)
+		}
+		if string(replayed) != `[{"sku":"1"}]` {
+			t.Fatalf("HandleReplay(%s), replayed = %s", test.desc, replayed)
+		}
+	}
+}
+
+func TestHandleReplay_NotConfigured(t *testing.T) {
+	t.Parallel()
+
+	h, err := NewHandler(validAdminKey, dedup.NewMemoryStore(), jobs.NewMemoryStore())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/admin/replay/shoptree/payload-1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("X-Replay-Key", validReplayKey)
+	h.HandleReplay(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("HandleReplay(), got = %v, want = %v", w.Code, http.StatusNotFound)
+	}
+}
@@ -0,0 +1,59 @@
+package admin
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/selftest"
+)
+
+// SelfTestResponse is the report of a provider self test.
+type SelfTestResponse struct {
+	Provider string                 `json:"provider"`
+	OK       bool                   `json:"ok"`
+	Steps    []*selftest.StepResult `json:"steps"`
+}
+
+// HandleSelfTest runs the self test of the {provider} path variable and
+// reports the result of each step. It responds with http 503 when a step
+// failed so it can be used as a one-shot check.
+func (h *Handler) HandleSelfTest(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context()).With().Str("handler", handlerName).Logger()
+
+	if err := validateHeaders(logger, r.Header, h.authKey); err != nil {
+		responseJSON(logger, w, http.StatusUnauthorized, &Response{Message: err.Error()})
+		return
+	}
+
+	provider := mux.Vars(r)["provider"]
+	if h.selfTest == nil {
+		responseJSON(logger, w, http.StatusNotFound, &Response{Message: selftest.ErrUnknownProvider.Error()})
+		return
+	}
+
+	steps, err := h.selfTest.Run(r.Context(), provider)
+	if errors.Is(err, selftest.ErrUnknownProvider) {
+		responseJSON(logger, w, http.StatusNotFound, &Response{Message: err.Error()})
+		return
+	}
+	if err != nil {
+		logger.Err(err).Str("provider", provider).Msg("failed to run self test")
+		responseJSON(logger, w, http.StatusInternalServerError, &Response{Message: "failed to run self test"})
+		return
+	}
+
+	res := &SelfTestResponse{Provider: provider, OK: true, Steps: steps}
+	for _, s := range steps {
+		res.OK = res.OK && s.OK
+	}
+
+	code := http.StatusOK
+	if !res.OK {
+		code = http.StatusServiceUnavailable
+		logger.Warn().Str("provider", provider).Interface("steps", steps).Msg("self test failed")
+	}
+	responseJSON(logger, w, code, res)
+}
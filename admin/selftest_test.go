@@ -0,0 +1,66 @@
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dropezy/storefront-backend/http/dedup"
+	"github.com/dropezy/storefront-backend/http/jobs"
+	"github.com/dropezy/storefront-backend/http/selftest"
+)
+
+func TestHandleSelfTest(t *testing.T) {
+	t.Parallel()
+
+	runner := selftest.NewRunner()
+	runner.Register("shoptree", selftest.Step{Name: "ok", Check: func(ctx context.Context) error { return nil }})
+	runner.Register("midtrans", selftest.Step{Name: "broken", Check: func(ctx context.Context) error { return errors.New("broken") }})
+
+	h, err := NewHandler(validAdminKey, dedup.NewMemoryStore(), jobs.NewMemoryStore(), WithSelfTest(runner))
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := mux.NewRouter()
+	router.HandleFunc("/admin/selftest/{provider}", h.HandleSelfTest).Methods(http.MethodPost)
+
+	tests := []struct {
+		provider string
+		wantCode int
+		wantOK   bool
+	}{
+		{provider: "shoptree", wantCode: http.StatusOK, wantOK: true},
+		{provider: "midtrans", wantCode: http.StatusServiceUnavailable},
+		{provider: "unknown", wantCode: http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest(http.MethodPost, "/admin/selftest/"+test.provider, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("X-Admin-Key", validAdminKey)
+		router.ServeHTTP(w, r)
+
+		if w.Code != test.wantCode {
+			t.Fatalf("HandleSelfTest(%s), got = %v, want = %v", test.provider, w.Code, test.wantCode)
+		}
+		if test.wantCode == http.StatusNotFound {
+			continue
+		}
+
+		got := &SelfTestResponse{}
+		if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+			t.Fatal(err)
+		}
+		if got.OK != test.wantOK || len(got.Steps) != 1 {
+			t.Fatalf("HandleSelfTest(%s), got = %+v", test.provider, got)
+		}
+	}
+}
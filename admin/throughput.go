@@ -0,0 +1,30 @@
+package admin
+
+import (
+	"net/http"
+
+	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/throughput"
+)
+
+// ThroughputResponse is the number of webhooks received per provider.
+type ThroughputResponse struct {
+	Providers []*throughput.Counts `json:"providers"`
+}
+
+// HandleThroughput reports the webhooks received per provider over the last
+// minute, hour and day.
+func (h *Handler) HandleThroughput(w http.ResponseWriter, r *http.Request) {
+	logger := logging.FromContext(r.Context()).With().Str("handler", handlerName).Logger()
+
+	if err := validateHeaders(logger, r.Header, h.authKey); err != nil {
+		responseJSON(logger, w, http.StatusUnauthorized, &Response{Message: err.Error()})
+		return
+	}
+
+	res := &ThroughputResponse{Providers: []*throughput.Counts{}}
+	if h.throughput != nil {
+		res.Providers = h.throughput.Snapshot()
+	}
+	responseJSON(logger, w, http.StatusOK, res)
+}
@@ -0,0 +1,61 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dropezy/storefront-backend/http/dedup"
+	"github.com/dropezy/storefront-backend/http/jobs"
+	"github.com/dropezy/storefront-backend/http/throughput"
+)
+
+func TestHandleThroughput(t *testing.T) {
+	t.Parallel()
+
+	counter := throughput.NewCounter()
+	counter.Record("midtrans")
+	counter.Record("midtrans")
+	counter.Record("shoptree")
+
+	h, err := NewHandler(validAdminKey, dedup.NewMemoryStore(), jobs.NewMemoryStore(), WithThroughput(counter))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(adminKey string) *http.Response {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest(http.MethodGet, "/admin/throughput", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if adminKey != "" {
+			r.Header.Set("X-Admin-Key", adminKey)
+		}
+		http.HandlerFunc(h.HandleThroughput).ServeHTTP(w, r)
+		return w.Result()
+	}
+
+	if got := do("").StatusCode; got != http.StatusUnauthorized {
+		t.Fatalf("HandleThroughput(), got = %v, want = %v", got, http.StatusUnauthorized)
+	}
+
+	resp := do(validAdminKey)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("HandleThroughput(), got = %v, want = %v", resp.StatusCode, http.StatusOK)
+	}
+	got := &ThroughputResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	want := &ThroughputResponse{Providers: []*throughput.Counts{
+		{Provider: "midtrans", Minute: 2, Hour: 2, Day: 2},
+		{Provider: "shoptree", Minute: 1, Hour: 1, Day: 1},
+	}}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("HandleThroughput(), got = %v", cmp.Diff(want, got))
+	}
+}
@@ -0,0 +1,24 @@
+// Package alert notifies on-call when the callback handlers keep failing.
+package alert
+
+import "context"
+
+// Severity of an alert.
+type Severity string
+
+const (
+	SeverityWarning  Severity = "warning"
+	SeverityCritical Severity = "critical"
+)
+
+// Alerter sends an alert to on-call.
+type Alerter interface {
+	Alert(ctx context.Context, severity Severity, message string) error
+}
+
+// Nop is an Alerter that drops every alert, it is used when no alerting
+// is configured.
+type Nop struct{}
+
+// Alert implements Alerter.
+func (Nop) Alert(context.Context, Severity, string) error { return nil }
@@ -0,0 +1,95 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeAlerter struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (f *fakeAlerter) Alert(_ context.Context, _ Severity, message string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.messages = append(f.messages, message)
+	return nil
+}
+
+func TestTracker(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	alerter := &fakeAlerter{}
+	tracker := NewTracker(alerter, 3, time.Minute)
+	tracker.now = func() time.Time { return now }
+
+	failure := func(handler string) bool {
+		fired, err := tracker.Failure(ctx, handler)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return fired
+	}
+
+	// below the threshold
+	for i := 0; i < 2; i++ {
+		if failure("midtrans") {
+			t.Fatalf("Failure() #%d, got fired, want not fired", i+1)
+		}
+		now = now.Add(10 * time.Second)
+	}
+	// failures of other handlers are counted separately
+	if failure("shoptree") {
+		t.Fatal("Failure() shoptree, got fired, want not fired")
+	}
+
+	// crossing the threshold fires an alert
+	if !failure("midtrans") {
+		t.Fatal("Failure() crossing threshold, got not fired, want fired")
+	}
+	if len(alerter.messages) != 1 {
+		t.Fatalf("got %d alerts, want 1", len(alerter.messages))
+	}
+
+	// only alert once per window
+	if failure("midtrans") {
+		t.Fatal("Failure() after alert, got fired, want not fired")
+	}
+
+	// failures outside the window are forgotten
+	now = now.Add(2 * time.Minute)
+	if failure("midtrans") {
+		t.Fatal("Failure() after window, got fired, want not fired")
+	}
+}
+
+func TestWebhook(t *testing.T) {
+	t.Parallel()
+
+	got := make(chan webhookMessage, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		msg := webhookMessage{}
+		if err := json.NewDecoder(r.Body).Decode(&msg); err != nil {
+			t.Error(err)
+		}
+		got <- msg
+	}))
+	defer srv.Close()
+
+	if err := NewWebhook(srv.URL).Alert(context.Background(), SeverityCritical, "order service is down"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[critical] order service is down"; (<-got).Text != want {
+		t.Fatalf("Alert() did not post %q", want)
+	}
+}
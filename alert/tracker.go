@@ -0,0 +1,69 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Tracker counts handler failures and fires an alert once a handler fails
+// threshold times within window. A handler is alerted at most once per window.
+type Tracker struct {
+	alerter   Alerter
+	threshold int
+	window    time.Duration
+
+	mu       sync.Mutex
+	failures map[string][]time.Time
+	alerted  map[string]time.Time
+
+	// now is used to get the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewTracker returns a new Tracker, a zero threshold disables the alerts.
+func NewTracker(alerter Alerter, threshold int, window time.Duration) *Tracker {
+	return &Tracker{
+		alerter:   alerter,
+		threshold: threshold,
+		window:    window,
+		failures:  make(map[string][]time.Time),
+		alerted:   make(map[string]time.Time),
+		now:       time.Now,
+	}
+}
+
+// Failure records a failure of handler and reports whether an alert was fired.
+func (t *Tracker) Failure(ctx context.Context, handler string) (bool, error) {
+	if t.threshold <= 0 {
+		return false, nil
+	}
+
+	t.mu.Lock()
+	now := t.now()
+	since := now.Add(-t.window)
+
+	// only keep the failures within the window
+	failures := t.failures[handler][:0]
+	for _, f := range t.failures[handler] {
+		if f.After(since) {
+			failures = append(failures, f)
+		}
+	}
+	failures = append(failures, now)
+	t.failures[handler] = failures
+
+	fire := len(failures) >= t.threshold && !t.alerted[handler].After(since)
+	if fire {
+		t.alerted[handler] = now
+	}
+	t.mu.Unlock()
+
+	if !fire {
+		return false, nil
+	}
+
+	msg := fmt.Sprintf("%s handler failed %d times in the last %s", handler, len(failures), t.window)
+	return true, t.alerter.Alert(ctx, SeverityCritical, msg)
+}
@@ -0,0 +1,58 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const defaultWebhookTimeout = 5 * time.Second
+
+// Webhook is an Alerter posting a slack compatible message to a webhook url.
+type Webhook struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook returns a new Webhook alerter posting to url.
+func NewWebhook(url string) *Webhook {
+	return &Webhook{
+		url:    url,
+		client: &http.Client{Timeout: defaultWebhookTimeout},
+	}
+}
+
+// webhookMessage is the slack incoming webhook payload.
+type webhookMessage struct {
+	Text string `json:"text"`
+}
+
+// Alert implements Alerter.
+func (w *Webhook) Alert(ctx context.Context, severity Severity, message string) error {
+	body, err := json.Marshal(&webhookMessage{
+		Text: fmt.Sprintf("[%s] %s", severity, message),
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return fmt.Errorf("alert webhook responded with http %d", res.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+// Package breaker contains a circuit breaker protecting the backend during
+// an outage: once the gRPC calls keep failing, the callbacks are rejected
+// with http 503 and a Retry-After matching the breaker cooldown, so the
+// providers retry right as the backend is tried again.
+package breaker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Breaker opens after threshold consecutive backend failures and stays open
+// for cooldown, then lets a single trial call through: the breaker closes
+// when it succeeds and opens again when it fails. It is safe for concurrent
+// use.
+type Breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	failures int
+	openedAt time.Time
+	trial    bool
+
+	now func() time.Time
+}
+
+// New returns a new Breaker, it never opens when threshold is not positive.
+func New(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		now:       time.Now,
+	}
+}
+
+// Remaining returns how long the breaker stays open, it is 0 when the
+// breaker is closed or its cooldown is over.
+func (b *Breaker) Remaining() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.remaining()
+}
+
+func (b *Breaker) remaining() time.Duration {
+	if b.openedAt.IsZero() {
+		return 0
+	}
+	if d := b.cooldown - b.now().Sub(b.openedAt); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// allow reports whether a call can be made, only one trial call is let
+// through once the cooldown is over.
+func (b *Breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.openedAt.IsZero() {
+		return true
+	}
+	if b.remaining() > 0 || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// record records the outcome of a call.
+func (b *Breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !failed {
+		b.failures = 0
+		b.openedAt = time.Time{}
+		return
+	}
+
+	b.failures++
+	if b.threshold > 0 && (b.failures >= b.threshold || !b.openedAt.IsZero()) {
+		b.openedAt = b.now()
+	}
+}
+
+// isFailure reports whether err means the backend is unavailable, the
+// business errors don't count.
+func isFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// UnaryClientInterceptor fails the calls fast with codes.Unavailable while
+// the breaker is open.
+func (b *Breaker) UnaryClientInterceptor() grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if b.threshold <= 0 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		if !b.allow() {
+			return status.Error(codes.Unavailable, ErrOpen.Error())
+		}
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		b.record(isFailure(err))
+		return err
+	}
+}
+
+// Response is the response body of the requests rejected by Middleware.
+type Response struct {
+	Message string `json:"message"`
+}
+
+// Middleware rejects the requests with http 503 while the breaker is open,
+// the Retry-After header is the remaining cooldown in seconds.
+func (b *Breaker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		remaining := b.Remaining()
+		if remaining <= 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(remaining.Seconds()))))
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		_ = json.NewEncoder(w).Encode(&Response{Message: ErrOpen.Error()})
+	})
+}
@@ -0,0 +1,122 @@
+package breaker
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreaker(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	b := New(2, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	backendErr := status.Error(codes.Unavailable, "backend down")
+	calls := 0
+	invoke := func() error {
+		return b.UnaryClientInterceptor()(context.Background(), "/task.TaskService/UpdateOrderTask", nil, nil, nil,
+			func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				calls++
+				return backendErr
+			})
+	}
+
+	// business errors don't open the breaker
+	backendErr = status.Error(codes.NotFound, "not found")
+	for i := 0; i < 3; i++ {
+		_ = invoke()
+	}
+	if got := b.Remaining(); got != 0 {
+		t.Fatalf("Remaining(), got = %v, want = 0", got)
+	}
+
+	backendErr = status.Error(codes.Unavailable, "backend down")
+	_ = invoke()
+	_ = invoke()
+	if got := b.Remaining(); got != 30*time.Second {
+		t.Fatalf("Remaining(), got = %v, want = %v", got, 30*time.Second)
+	}
+
+	// open, the backend is not called
+	calls = 0
+	if err := invoke(); status.Code(err) != codes.Unavailable || calls != 0 {
+		t.Fatalf("invoke(), got = %v with %d calls, want fast failure", err, calls)
+	}
+
+	// the cooldown is over, a failing trial opens it again
+	now = now.Add(30 * time.Second)
+	_ = invoke()
+	if calls != 1 {
+		t.Fatalf("invoke(), got %d calls, want the trial call", calls)
+	}
+	if got := b.Remaining(); got != 30*time.Second {
+		t.Fatalf("Remaining(), got = %v, want = %v", got, 30*time.Second)
+	}
+
+	// a succeeding trial closes it
+	now = now.Add(30 * time.Second)
+	backendErr = nil
+	if err := invoke(); err != nil {
+		t.Fatal(err)
+	}
+	if got := b.Remaining(); got != 0 {
+		t.Fatalf("Remaining(), got = %v, want = 0", got)
+	}
+}
+
+func TestBreaker_Middleware(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	b := New(1, 30*time.Second)
+	b.now = func() time.Time { return now }
+
+	h := b.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	do := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		r, err := http.NewRequest(http.MethodPost, "/midtrans/transaction-update", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.ServeHTTP(w, r)
+		return w
+	}
+
+	if w := do(); w.Code != http.StatusOK {
+		t.Fatalf("Middleware(), got = %v, want = %v", w.Code, http.StatusOK)
+	}
+
+	b.record(true)
+
+	tests := []struct {
+		elapsed    time.Duration
+		retryAfter string
+	}{
+		{elapsed: 0, retryAfter: "30"},
+		{elapsed: 10 * time.Second, retryAfter: "20"},
+		{elapsed: 19*time.Second + 500*time.Millisecond, retryAfter: "1"},
+	}
+	for _, test := range tests {
+		now = now.Add(test.elapsed)
+		w := do()
+		if w.Code != http.StatusServiceUnavailable {
+			t.Fatalf("Middleware(), got = %v, want = %v", w.Code, http.StatusServiceUnavailable)
+		}
+		if got := w.Header().Get("Retry-After"); got != test.retryAfter {
+			t.Fatalf("Middleware() Retry-After, got = %v, want = %v", got, test.retryAfter)
+		}
+	}
+
+	now = now.Add(time.Second)
+	if w := do(); w.Code != http.StatusOK {
+		t.Fatalf("Middleware(), got = %v, want = %v after the cooldown", w.Code, http.StatusOK)
+	}
+}
@@ -0,0 +1,96 @@
+package midtrans
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dropezy/storefront-backend/internal/integrations/payment"
+)
+
+// ClientConfig configures the http client of the midtrans API calls.
+type ClientConfig struct {
+	// Timeout bounds a whole call, reading the response body included.
+	Timeout time.Duration
+	// DialTimeout bounds the connection to midtrans, TLS handshake included.
+	DialTimeout time.Duration
+	// ResponseHeaderTimeout bounds the wait for the response once the
+	// request is sent.
+	ResponseHeaderTimeout time.Duration
+
+	// MaxIdleConnsPerHost and IdleConnTimeout control the connections kept
+	// alive between the calls.
+	MaxIdleConnsPerHost int
+	IdleConnTimeout     time.Duration
+}
+
+// NewHTTPClient returns an http client for WithHTTPClient configured by cfg,
+// the zero fields keep the defaults of http.DefaultTransport.
+func NewHTTPClient(cfg ClientConfig) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if cfg.DialTimeout > 0 {
+		transport.DialContext = (&net.Dialer{
+			Timeout:   cfg.DialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext
+		transport.TLSHandshakeTimeout = cfg.DialTimeout
+	}
+	if cfg.ResponseHeaderTimeout > 0 {
+		transport.ResponseHeaderTimeout = cfg.ResponseHeaderTimeout
+	}
+	if cfg.MaxIdleConnsPerHost > 0 {
+		transport.MaxIdleConnsPerHost = cfg.MaxIdleConnsPerHost
+	}
+	if cfg.IdleConnTimeout > 0 {
+		transport.IdleConnTimeout = cfg.IdleConnTimeout
+	}
+	return &http.Client{Timeout: cfg.Timeout, Transport: transport}
+}
+
+// statusClient is a payment.TransactionGetter calling the midtrans get status
+// API with the client of WithHTTPClient, the transaction package doesn't take
+// a client. It is built per notification so the calls are also bounded by the
+// notification context.
+type statusClient struct {
+	ctx          context.Context
+	client       *http.Client
+	getStatusURL string
+	serverKey    string
+}
+
+// GetTransactionStatus implements payment.TransactionGetter.
+func (c *statusClient) GetTransactionStatus(orderID string) (*payment.TransactionStatus, error) {
+	req, err := http.NewRequestWithContext(c.ctx, http.MethodGet, fmt.Sprintf(c.getStatusURL, orderID), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	// midtrans authenticates with the server key as the basic auth user.
+	req.SetBasicAuth(c.serverKey, "")
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		return nil, fmt.Errorf("midtrans get status responded with http %d", res.StatusCode)
+	}
+
+	trx := &payment.TransactionStatus{}
+	if err := json.NewDecoder(res.Body).Decode(trx); err != nil {
+		return nil, err
+	}
+	// midtrans answers http 200 to the failed calls too, e.g. with status
+	// code 404 in the body for an unknown transaction. The expired
+	// transactions are found with status code 407.
+	if code := trx.StatusCode; !strings.HasPrefix(code, "2") && code != "407" {
+		return nil, fmt.Errorf("%w: midtrans get status responded with status code %q", ErrTransactionStatusNotFound, code)
+	}
+	return trx, nil
+}
@@ -0,0 +1,121 @@
+package midtrans
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/dropezy/storefront-backend/internal/integrations/payment"
+)
+
+func TestStatusClient(t *testing.T) {
+	t.Parallel()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if user, _, ok := r.BasicAuth(); !ok || user != testServerKey {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/v2/order-1/status":
+			json.NewEncoder(w).Encode(map[string]string{
+				"status_code":        "200",
+				"transaction_status": "settlement",
+			})
+		case "/v2/expired/status":
+			json.NewEncoder(w).Encode(map[string]string{
+				"status_code":        "407",
+				"transaction_status": "expire",
+			})
+		case "/v2/unknown-transaction/status":
+			// midtrans answers http 200 for an unknown transaction.
+			json.NewEncoder(w).Encode(map[string]string{
+				"status_code":    "404",
+				"status_message": "Transaction doesn't exist.",
+			})
+		case "/v2/hung/status":
+			// longer than the client timeout.
+			time.Sleep(200 * time.Millisecond)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+
+	client := NewHTTPClient(ClientConfig{
+		Timeout:             50 * time.Millisecond,
+		DialTimeout:         time.Second,
+		MaxIdleConnsPerHost: 4,
+	})
+
+	tests := []struct {
+		name      string
+		orderID   string
+		serverKey string
+		want      string
+		wantErr   bool
+		// wantNotFound is an error holding ErrTransactionStatusNotFound.
+		wantNotFound bool
+	}{
+		{name: "Success", orderID: "order-1", serverKey: testServerKey, want: "settlement"},
+		{name: "Expired", orderID: "expired", serverKey: testServerKey, want: "expire"},
+		{name: "UnknownTransaction", orderID: "unknown-transaction", serverKey: testServerKey, wantErr: true, wantNotFound: true},
+		{name: "Unauthorized", orderID: "order-1", serverKey: "other", wantErr: true},
+		{name: "NotFound", orderID: "unknown", serverKey: testServerKey, wantErr: true},
+		{name: "Timeout", orderID: "hung", serverKey: testServerKey, wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			c := &statusClient{
+				ctx:          context.Background(),
+				client:       client,
+				getStatusURL: srv.URL + "/v2/%s/status",
+				serverKey:    test.serverKey,
+			}
+			trx, err := c.GetTransactionStatus(test.orderID)
+			if test.wantErr {
+				if err == nil {
+					t.Fatalf("GetTransactionStatus(), got = %+v, want = error", trx)
+				}
+				if test.wantNotFound && !errors.Is(err, ErrTransactionStatusNotFound) {
+					t.Fatalf("GetTransactionStatus(), got = %v, want = %v", err, ErrTransactionStatusNotFound)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if trx.TransactionStatus != test.want {
+				t.Fatalf("GetTransactionStatus(), got = %q, want = %q", trx.TransactionStatus, test.want)
+			}
+		})
+	}
+}
+
+func TestWithHTTPClient(t *testing.T) {
+	t.Parallel()
+
+	client := NewHTTPClient(ClientConfig{Timeout: time.Second})
+	h, err := NewHandler(testServerKey, "http://localhost", "http://localhost/v2/%s/status", nil, nil, WithHTTPClient(client))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	getter, err := h.initializeTransactionGetter(context.Background(), zerolog.Nop(), &UpdateTransactionRequest{PaymentType: payment.PaymentMethod_Gopay})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c, ok := getter.(*statusClient); !ok || c.client != client {
+		t.Fatalf("initializeTransactionGetter(), got = %T, want = *statusClient with the handler client", getter)
+	}
+}
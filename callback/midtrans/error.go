@@ -1,16 +1,83 @@
 package midtrans
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dropezy/storefront-backend/http/internal/httpjson"
+)
 
 var (
 	ErrContenTypeIsRequired = errors.New("content type is required")
 	ErrInvalidContentType   = errors.New("content type should be application/json")
+	ErrAPIKeyIsRequired     = errors.New("api key is required")
+	ErrInvalidAPIKey        = errors.New("invalid api key")
 	ErrInvalidSignature     = errors.New("invalid signature")
 	ErrInvalidStatusCode    = errors.New("invalid status code")
+	ErrInvalidGrossAmount   = errors.New("invalid gross amount")
+	ErrAmountMismatch       = errors.New("gross amount does not match the order total")
+	ErrRefundUnpaidTask     = errors.New("refund of an unpaid payment task")
+
+	ErrTransactionStatusNotFound = errors.New("transaction status not found")
+
+	ErrTransactionIDIsRequired = errors.New("transaction id is required")
+	ErrInvalidTransactionID    = errors.New("invalid transaction id")
 
 	ErrMarshallingUnsuccessful     = errors.New("marshalling unsuccessful")
 	ErrWriteToResponseUnsuccessful = errors.New("write to response unsuccessful")
 	ErrUnsupportedPaymentMethod    = errors.New("unsupported payment method")
 
 	ErrInternalServerError = errors.New("internal server error")
+
+	ErrEmptyOrderTaskResponse = errors.New("empty order task response")
+	ErrEmptyOrderResponse     = errors.New("empty order response")
+
+	ErrOrderTaskNotFound   = errors.New("payment order task not found")
+	ErrPaymentTaskNotFound = errors.New("no payment task for order")
+	ErrOrderNotFound       = errors.New("order not found")
+
+	ErrUnknownTransactionStatus = errors.New("unknown transaction status")
+
+	// ErrInvalidURL happens when the midtrans API urls passed to NewHandler
+	// are not absolute http urls.
+	ErrInvalidURL = errors.New("invalid midtrans api url")
+
+	ErrMethodNotAllowed   = errors.New("expecting http method post")
+	ErrInvalidRequestData = errors.New("invalid request data")
 )
+
+// statusCodes are the http status of the errors written with writeError, any
+// other error is http 500.
+var statusCodes = httpjson.StatusCodes{
+	ErrMethodNotAllowed:        http.StatusMethodNotAllowed,
+	ErrContenTypeIsRequired:    http.StatusBadRequest,
+	ErrInvalidContentType:      http.StatusBadRequest,
+	ErrAPIKeyIsRequired:        http.StatusUnauthorized,
+	ErrInvalidAPIKey:           http.StatusUnauthorized,
+	ErrInvalidRequestData:      http.StatusBadRequest,
+	ErrTransactionIDIsRequired: http.StatusBadRequest,
+	ErrInvalidTransactionID:    http.StatusBadRequest,
+	ErrInvalidStatusCode:       http.StatusBadRequest,
+	ErrInvalidSignature:        http.StatusBadRequest,
+}
+
+// acceptedErrorCodes are the errors we give up on: midtrans gets http 200 to
+// stop retrying the notification, with the internal code in the body. Any
+// other error keeps its http status so midtrans retries.
+var acceptedErrorCodes = map[error]string{
+	ErrOrderTaskNotFound:        "order_task_not_found",
+	ErrOrderNotFound:            "order_not_found",
+	ErrUnknownTransactionStatus: "unknown_transaction_status",
+	ErrRefundUnpaidTask:         "refund_unpaid_task",
+}
+
+// acceptedErrorCode returns the internal code of err when it is acknowledged
+// with http 200.
+func acceptedErrorCode(err error) (string, bool) {
+	for target, code := range acceptedErrorCodes {
+		if errors.Is(err, target) {
+			return code, true
+		}
+	}
+	return "", false
+}
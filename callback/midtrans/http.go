@@ -1,23 +1,93 @@
 package midtrans
 
 import (
+	"encoding/json"
 	"net/http"
 
 	"github.com/rs/zerolog"
+
+	"github.com/dropezy/storefront-backend/http/internal/httpjson"
+	"github.com/dropezy/storefront-backend/http/internal/validate"
+	"github.com/dropezy/storefront-backend/http/middleware"
+	"github.com/dropezy/storefront-backend/internal/integrations/payment/midtrans/auth"
 )
 
-func validateHeaders(logger zerolog.Logger, header http.Header) error {
-	ct := header.Get("Content-Type")
-	if ct == "" {
-		return ErrContenTypeIsRequired
+// headerErrors keeps the error messages of the package for the shared
+// header checks.
+var headerErrors = map[error]error{
+	validate.ErrContentTypeIsRequired: ErrContenTypeIsRequired,
+	validate.ErrInvalidContentType:    ErrInvalidContentType,
+	validate.ErrAPIKeyIsRequired:      ErrAPIKeyIsRequired,
+	validate.ErrInvalidAPIKey:         ErrInvalidAPIKey,
+}
+
+// validateHeaders checks the content type and the api key when WithAPIKey
+// is set.
+func (h *Handler) validateHeaders(header http.Header) error {
+	var opts []validate.Option
+	if h.apiKeyHeader != "" {
+		opts = append(opts, validate.APIKey(h.apiKeyHeader, h.apiKey))
 	}
-	if ct != "application/json" {
-		return ErrInvalidContentType
+	if err := validate.Headers(header, opts...); err != nil {
+		if e, ok := headerErrors[err]; ok {
+			return e
+		}
+		return err
 	}
 	return nil
 }
 
-func writeJSONResponse(w http.ResponseWriter, code int) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(code)
+// Verifier returns the middleware.Verifier of the midtrans routes, checking
+// the api key when WithAPIKey is set and the callback signature over body, so
+// the unsigned requests are rejected before their payload is stored. The
+// handler checks them again with the decoded notification.
+func (h *Handler) Verifier() middleware.Verifier {
+	return middleware.VerifierFunc(func(r *http.Request, body []byte) error {
+		if h.apiKeyHeader != "" {
+			if err := validate.CheckAPIKey(r.Header, h.apiKeyHeader, h.apiKey); err != nil {
+				if e, ok := headerErrors[err]; ok {
+					err = e
+				}
+				return err
+			}
+		}
+
+		req := &UpdateTransactionRequest{}
+		if err := json.Unmarshal(body, req); err != nil {
+			return requestDataError(err)
+		}
+		if err := auth.ValidateCallbackSignature(
+			h.signature(r, req), req.OrderID, req.StatusCode, req.GrossAmount, h.serverKey); err != nil {
+			return ErrInvalidSignature
+		}
+		return nil
+	})
+}
+
+// writeJSONResponse writes message as the response body.
+func writeJSONResponse(logger zerolog.Logger, w http.ResponseWriter, code int, message string) {
+	if err := httpjson.WriteError(w, code, message); err != nil {
+		logger.Err(err).Msg(ErrWriteToResponseUnsuccessful.Error())
+	}
+}
+
+// writeError writes err with its http status from statusCodes.
+func writeError(logger zerolog.Logger, w http.ResponseWriter, err error) {
+	if err := httpjson.WriteErr(w, statusCodes, err); err != nil {
+		logger.Err(err).Msg(ErrWriteToResponseUnsuccessful.Error())
+	}
+}
+
+// writeSuccess writes the http 200 response of a notification.
+func writeSuccess(logger zerolog.Logger, w http.ResponseWriter) {
+	if err := httpjson.WriteOK(w, "success"); err != nil {
+		logger.Err(err).Msg(ErrWriteToResponseUnsuccessful.Error())
+	}
+}
+
+// writeJSONBody marshals v and writes it as the response body.
+func writeJSONBody(logger zerolog.Logger, w http.ResponseWriter, code int, v interface{}) {
+	if err := httpjson.Write(w, code, v); err != nil {
+		logger.Err(err).Msg(ErrWriteToResponseUnsuccessful.Error())
+	}
 }
@@ -2,16 +2,27 @@ package midtrans
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"math"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/sync/singleflight"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/dedup"
+	"github.com/dropezy/storefront-backend/http/internal/httpjson"
+	"github.com/dropezy/storefront-backend/http/internal/jsonschema"
+	"github.com/dropezy/storefront-backend/http/internal/tracing"
+	"github.com/dropezy/storefront-backend/http/middleware"
 	"github.com/dropezy/storefront-backend/internal/integrations/payment"
 	"github.com/dropezy/storefront-backend/internal/integrations/payment/midtrans/auth"
 	"github.com/dropezy/storefront-backend/internal/integrations/payment/midtrans/transaction"
@@ -21,27 +32,47 @@ import (
 	tpb "github.com/dropezy/proto/v1/task"
 )
 
-const handlerName = "midtrans"
+// HandlerName is the name of the handler used in logs, metrics and alerts.
+const HandlerName = "midtrans"
 
 const (
 	TransactionUpdatePath = "/midtrans/transaction-update"
 
 	defaultContextTimeout = 15 * time.Second
 
-	PendingTransactionStatus           = "pending"
-	AuthorizedTransactionStatus        = "authorized"
-	CaptureTransactionStatus           = "capture"
-	SettlementTransactionStatus        = "settlement"
-	DenyTransactionStatus              = "deny"
-	CancelTransactionStatus            = "cancel"
-	RefundTransactionStatus            = "refund"
-	PartialRefundTransactionStatus     = "partial_refund"
-	ChargebackTransactionStatus        = "chargeback"
-	PartialChargebackTransactionStatus = "partial_chargeback"
-	ExpireTransactionStatus            = "expire"
-	FailureTransactionStatus           = "failure"
-
-	FraudStatusAccept = "accept"
+	defaultTerminalUpdateAttempts = 3
+	defaultTerminalUpdateBackoff  = 200 * time.Millisecond
+
+	defaultStatusAttempts  = 3
+	defaultStatusBaseDelay = 100 * time.Millisecond
+
+	FraudStatusAccept    = "accept"
+	FraudStatusChallenge = "challenge"
+	FraudStatusDeny      = "deny"
+)
+
+// payment types supported on top of the payment integration methods, they
+// share the midtrans get status API.
+const (
+	PaymentTypeQRIS      = "qris"
+	PaymentTypeShopeePay = "shopeepay"
+)
+
+// RefundStatusKey and RefundAmountKey are the additional data of a refunded
+// payment task, RefundStatusKey holds the refund transaction status and
+// RefundAmountKey the refunded amount.
+const (
+	RefundStatusKey = "refund_status"
+	RefundAmountKey = "refund_amount"
+)
+
+// decisions returned in the enriched response.
+const (
+	decisionSuccess          = "success"
+	decisionFailed           = "failed"
+	decisionAlreadyProcessed = "already_processed"
+	decisionIgnored          = "ignored"
+	decisionRefunded         = "refunded"
 )
 
 type Handler struct {
@@ -49,26 +80,300 @@ type Handler struct {
 	chargeURL    string
 	getStatusURL string
 
+	// enrichedResponse adds the resulting order task state to the
+	// response body of successfully processed notifications.
+	enrichedResponse bool
+	// signatureHeader is the header holding the callback signature, the
+	// signature_key body field is used when empty.
+	signatureHeader string
+	// apiKeyHeader and apiKey are the shared secret checked on top of the
+	// signature, the check is disabled when apiKeyHeader is empty.
+	apiKeyHeader string
+	apiKey       string
+
+	// httpClient calls the midtrans API, the transaction package makes the
+	// calls with its own client when nil.
+	httpClient *http.Client
+
+	// contextTimeout bounds the downstream calls of a notification.
+	contextTimeout time.Duration
+
+	// tracer starts the span of each notification.
+	tracer trace.Tracer
+
+	// statusAttempts and statusBaseDelay control the retry of the midtrans get
+	// status call, the delay doubles after each attempt.
+	statusAttempts  int
+	statusBaseDelay time.Duration
+
+	// terminalUpdateAttempts and terminalUpdateBackoff control the internal
+	// retry of persisting a failed task for terminal transaction statuses.
+	terminalUpdateAttempts int
+	terminalUpdateBackoff  time.Duration
+
+	// forbiddenOrderStates are the order states we refuse to update.
+	forbiddenOrderStates map[opb.OrderState]bool
+	// forbiddenRefundOrderStates are the order states we refuse to refund.
+	forbiddenRefundOrderStates map[opb.OrderState]bool
+
+	// schemaValidation checks the notification bodies against the embedded
+	// json schema before decoding them.
+	schemaValidation bool
+	// disallowUnknownFields rejects the notification fields missing from
+	// UpdateTransactionRequest.
+	disallowUnknownFields bool
+
+	// inFlight coalesces concurrent deliveries of the same notification.
+	inFlight singleflight.Group
+	// dedupStore remembers the processed notifications for dedupTTL, it is
+	// nil when disabled.
+	dedupStore dedup.KVStore
+	dedupTTL   time.Duration
+
 	orderService opb.OrderServiceClient
 	taskService  tpb.TaskServiceClient
 }
 
+// Option configures optional behaviour of the Handler.
+type Option func(*Handler)
+
+// WithEnrichedResponse returns the order id, task state and the decision we
+// took in the response body of successfully processed notifications. It is
+// disabled by default since some providers are strict about the response shape.
+func WithEnrichedResponse(enabled bool) Option {
+	return func(h *Handler) {
+		h.enrichedResponse = enabled
+	}
+}
+
+// WithSignatureHeader reads the callback signature from the given header
+// instead of the signature_key body field.
+func WithSignatureHeader(name string) Option {
+	return func(h *Handler) {
+		h.signatureHeader = name
+	}
+}
+
+// WithAPIKey requires the header named header to hold key, the requests
+// missing it or holding another key are rejected with http 401. It is
+// disabled when header or key is empty.
+func WithAPIKey(header, key string) Option {
+	return func(h *Handler) {
+		if header == "" || key == "" {
+			h.apiKeyHeader, h.apiKey = "", ""
+			return
+		}
+		h.apiKeyHeader = header
+		h.apiKey = key
+	}
+}
+
+// WithHTTPClient makes the midtrans get status calls with c, e.g. built by
+// NewHTTPClient, so a hung call is cut by its timeouts and the connections
+// are reused between the notifications. The calls are made by the
+// transaction package when c is nil.
+func WithHTTPClient(c *http.Client) Option {
+	return func(h *Handler) {
+		h.httpClient = c
+	}
+}
+
+// WithTracerProvider starts the span of each notification with a tracer of
+// tp, the spans are no-ops by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(h *Handler) {
+		h.tracer = tracing.Tracer(tp)
+	}
+}
+
+// WithContextTimeout sets the time allowed to process a notification, which
+// covers the midtrans get status call and the order and task service calls.
+// The default of 15s is kept when timeout is not positive.
+func WithContextTimeout(timeout time.Duration) Option {
+	return func(h *Handler) {
+		if timeout > 0 {
+			h.contextTimeout = timeout
+		}
+	}
+}
+
+// WithStatusRetry sets how many times the midtrans get status call is
+// attempted and the delay before the first retry, doubled for each next one.
+// Values below 1 attempt are ignored.
+func WithStatusRetry(attempts int, baseDelay time.Duration) Option {
+	return func(h *Handler) {
+		if attempts > 0 {
+			h.statusAttempts = attempts
+		}
+		h.statusBaseDelay = baseDelay
+	}
+}
+
+// WithDedup short-circuits with http 200 the redeliveries of a notification
+// already processed with the same transaction_id and transaction_status in
+// the last ttl. Only the notifications acknowledged with http 200 are stored,
+// the ones midtrans must retry are processed again. A key is evicted once its
+// ttl is reached, or earlier through the admin dedup endpoint. It is disabled
+// when store is nil or ttl is not positive.
+func WithDedup(store dedup.KVStore, ttl time.Duration) Option {
+	return func(h *Handler) {
+		if store == nil || ttl <= 0 {
+			h.dedupStore = nil
+			return
+		}
+		h.dedupStore = store
+		h.dedupTTL = ttl
+	}
+}
+
+// DedupKey returns the dedup store key of a notification.
+func DedupKey(transactionID, transactionStatus string) string {
+	return HandlerName + ":" + transactionID + ":" + strings.ToLower(transactionStatus)
+}
+
+// WithTerminalUpdateRetry sets how many times persisting a failed task is
+// attempted for terminal statuses (deny, expire, failure, cancel) and the
+// delay between the attempts.
+func WithTerminalUpdateRetry(attempts int, backoff time.Duration) Option {
+	return func(h *Handler) {
+		h.terminalUpdateAttempts = attempts
+		h.terminalUpdateBackoff = backoff
+	}
+}
+
+// DefaultForbiddenOrderStates are the order states refusing updates by default,
+// we don't want to update an order that already failed or succeeded.
+var DefaultForbiddenOrderStates = []opb.OrderState{
+	opb.OrderState_ORDER_STATE_PAID,
+	opb.OrderState_ORDER_STATE_CANCELLED,
+	opb.OrderState_ORDER_STATE_DONE,
+}
+
+// WithForbiddenOrderStates replaces the order states refusing updates,
+// DefaultForbiddenOrderStates is used when states is empty.
+func WithForbiddenOrderStates(states []opb.OrderState) Option {
+	return func(h *Handler) {
+		if len(states) == 0 {
+			return
+		}
+		h.forbiddenOrderStates = make(map[opb.OrderState]bool, len(states))
+		for _, s := range states {
+			h.forbiddenOrderStates[s] = true
+		}
+	}
+}
+
+// DefaultForbiddenRefundOrderStates are the order states refusing refunds by
+// default, an order that was never paid can't be refunded.
+var DefaultForbiddenRefundOrderStates = []opb.OrderState{
+	opb.OrderState_ORDER_STATE_UNSPECIFIED,
+	opb.OrderState_ORDER_STATE_WAITING_FOR_PAYMENT,
+}
+
+// WithForbiddenRefundOrderStates replaces the order states refusing refunds,
+// DefaultForbiddenRefundOrderStates is used when states is empty.
+func WithForbiddenRefundOrderStates(states []opb.OrderState) Option {
+	return func(h *Handler) {
+		if len(states) == 0 {
+			return
+		}
+		h.forbiddenRefundOrderStates = make(map[opb.OrderState]bool, len(states))
+		for _, s := range states {
+			h.forbiddenRefundOrderStates[s] = true
+		}
+	}
+}
+
+// WithSchemaValidation checks the notification bodies against their json
+// schema before decoding them, so a field with the wrong type is rejected
+// with its path.
+func WithSchemaValidation(enabled bool) Option {
+	return func(h *Handler) {
+		h.schemaValidation = enabled
+	}
+}
+
+// WithDisallowUnknownFields rejects the notifications holding a field unknown
+// to the handler with the name of the field instead of ignoring it.
+func WithDisallowUnknownFields(enabled bool) Option {
+	return func(h *Handler) {
+		h.disallowUnknownFields = enabled
+	}
+}
+
+// ParseOrderStates parses a comma separated list of order state names,
+// e.g. "ORDER_STATE_PAID,ORDER_STATE_DONE".
+func ParseOrderStates(s string) ([]opb.OrderState, error) {
+	var states []opb.OrderState
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		v, ok := opb.OrderState_value[name]
+		if !ok {
+			return nil, fmt.Errorf("invalid order state: %q", name)
+		}
+		states = append(states, opb.OrderState(v))
+	}
+	return states, nil
+}
+
 func NewHandler(serverKey string,
 	chargeURL, getStatusURL string,
 	orderService opb.OrderServiceClient,
-	taskService tpb.TaskServiceClient) (*Handler, error) {
+	taskService tpb.TaskServiceClient,
+	opts ...Option) (*Handler, error) {
 	if serverKey == "" {
 		return nil, errors.New("serverKey not found")
 	}
+	if err := validateURL("chargeURL", chargeURL); err != nil {
+		return nil, err
+	}
+	if err := validateURL("getStatusURL", getStatusURL); err != nil {
+		return nil, err
+	}
 
-	return &Handler{
+	h := &Handler{
 		serverKey:    serverKey,
 		chargeURL:    chargeURL,
 		getStatusURL: getStatusURL,
 
+		contextTimeout: defaultContextTimeout,
+		tracer:         tracing.Tracer(nil),
+
+		statusAttempts:  defaultStatusAttempts,
+		statusBaseDelay: defaultStatusBaseDelay,
+
+		terminalUpdateAttempts: defaultTerminalUpdateAttempts,
+		terminalUpdateBackoff:  defaultTerminalUpdateBackoff,
+
 		orderService: orderService,
 		taskService:  taskService,
-	}, nil
+	}
+	WithForbiddenOrderStates(DefaultForbiddenOrderStates)(h)
+	WithForbiddenRefundOrderStates(DefaultForbiddenRefundOrderStates)(h)
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
+}
+
+// validateURL returns an ErrInvalidURL error unless raw is an absolute http or
+// https url, so a typo fails at startup rather than on every status call. The
+// %s placeholder of the transaction ID is allowed.
+func validateURL(name, raw string) error {
+	if raw == "" {
+		return fmt.Errorf("%w: %s is required", ErrInvalidURL, name)
+	}
+	u, err := url.Parse(strings.ReplaceAll(raw, "%s", "transaction-id"))
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", ErrInvalidURL, name, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: %s %q is not an absolute http url", ErrInvalidURL, name, raw)
+	}
+	return nil
 }
 
 // HandlePaymentNotification handle payment notification from midtrans to
@@ -81,64 +386,179 @@ func NewHandler(serverKey string,
 //
 // TODO (novian): Add call to geofencing API for success payment
 func (h *Handler) HandleTransactionUpdate(w http.ResponseWriter, r *http.Request) {
-	logger := logging.FromContext(r.Context()).With().Str("handler", handlerName).Logger()
+	w, r, span := tracing.Start(h.tracer, w, r, HandlerName, "midtrans.HandleTransactionUpdate")
+	defer span.End()
 
-	ctx, cancelFn := context.WithTimeout(r.Context(), defaultContextTimeout)
+	logger := logging.FromContext(r.Context()).With().Str("handler", HandlerName).Logger()
+
+	ctx, cancelFn := context.WithTimeout(r.Context(), h.contextTimeout)
 	defer cancelFn()
 
 	if r.Method != http.MethodPost {
-		err := fmt.Errorf("expecting http method post, got: %s", r.Method)
-		logger.Err(err).Send()
+		err := fmt.Errorf("%w, got: %s", ErrMethodNotAllowed, r.Method)
+		logger.Err(err).Msg("invalid request method")
+		writeError(logger, w, err)
+		return
 	}
 
-	if err := validateHeaders(logger, r.Header); err != nil {
-		writeJSONResponse(w, http.StatusBadRequest)
+	if err := h.validateHeaders(r.Header); err != nil {
+		logger.Err(err).Msg("invalid request headers")
+		writeError(logger, w, err)
 		return
 	}
 
+	dec := jsonschema.Decoder{DisallowUnknownFields: h.disallowUnknownFields}
+	if h.schemaValidation {
+		dec.Schema = transactionUpdateSchema
+	}
 	req := &UpdateTransactionRequest{}
-	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+	if err := dec.Decode(r.Body, req); err != nil {
 		logger.Err(err).Msg("failed to decode request data")
-		writeJSONResponse(w, http.StatusBadRequest)
+		writeError(logger, w, requestDataError(err))
 		return
 	}
 
+	// check the request before its fields are used in logs.
+	if err := req.Validate(); err != nil {
+		logger.Err(err).Msg("invalid request data")
+		writeError(logger, w, err)
+		return
+	}
+
+	// the order id of midtrans is our payment task id.
+	span.SetAttributes(tracing.TaskIDKey.String(req.OrderID))
+
+	// the signature is redacted from the logs, the payload is logged with
+	// a redacted copy of it.
+	sig := h.signature(r, req)
+	payload := *req
+	payload.SignatureKey = redactSignature(req.SignatureKey)
 	logger = logger.With().Fields(map[string]interface{}{
 		"task_id":         req.OrderID,
 		"transaction_id":  req.TransactionID,
-		"signature":       req.SignatureKey,
-		"request_payload": req,
+		"signature":       redactSignature(sig),
+		"request_payload": &payload,
 	}).Logger()
 
 	if err := auth.ValidateCallbackSignature(
-		req.SignatureKey, req.OrderID, req.StatusCode, req.GrossAmount, h.serverKey); err != nil {
-		logger.Err(ErrInvalidSignature).Msg("invalid callbak signature")
-		writeJSONResponse(w, http.StatusBadRequest)
+		sig, req.OrderID, req.StatusCode, req.GrossAmount, h.serverKey); err != nil {
+		// the signed fields are enough to recompute the expected signature
+		// with the server key when debugging a mismatch.
+		logger.Err(ErrInvalidSignature).Fields(map[string]interface{}{
+			"status_code":      req.StatusCode,
+			"gross_amount":     req.GrossAmount,
+			"signature_length": len(sig),
+		}).Msg("invalid callbak signature")
+		writeError(logger, w, ErrInvalidSignature)
 		return
 	}
 
 	// only check for pending transaction because it will be skipped.
-	// the other status will be check below.
-	if strings.ToLower(req.TransactionStatus) == PendingTransactionStatus {
-		writeJSONResponse(w, http.StatusOK)
+	// the other status will be check below with the one of midtrans API.
+	trxStatus, ok := ParseTransactionStatus(req.TransactionStatus)
+	if !ok {
+		logger.Warn().Str("raw_transaction_status", req.TransactionStatus).Msg("unknown notification transaction status")
+	}
+	if trxStatus == PendingTransactionStatus {
+		writeSuccess(logger, w)
 		return
 	}
 
-	transactionGetter, err := h.initializeTransactionGetter(logger, req)
-	if err != nil {
-		writeJSONResponse(w, http.StatusInternalServerError)
+	dedupKey := DedupKey(req.TransactionID, req.TransactionStatus)
+	if h.dedupStore != nil {
+		e, ok, err := h.dedupStore.Get(ctx, dedupKey)
+		if err != nil {
+			// a failing store must not block the notifications.
+			logger.Err(err).Str("dedup_key", dedupKey).Msg("failed to get dedup key")
+		}
+		if ok {
+			logger.Info().Str("outcome", e.Outcome).Msg("notification already processed, ignoring")
+			writeSuccess(logger, w)
+			return
+		}
+	}
+
+	// concurrent deliveries of the same notification share one execution,
+	// it runs on its own deadline so the first caller going away doesn't
+	// cancel it for the others.
+	v, _, _ := h.inFlight.Do(dedupKey, func() (interface{}, error) {
+		sharedCtx, cancelFn := context.WithTimeout(detachedContext{r.Context()}, h.contextTimeout)
+		defer cancelFn()
+
+		res := h.processTransaction(sharedCtx, logger, req)
+		if h.dedupStore != nil && res.code == http.StatusOK {
+			if err := h.dedupStore.Set(sharedCtx, dedupKey, res.outcome(), h.dedupTTL); err != nil {
+				logger.Err(err).Str("dedup_key", dedupKey).Msg("failed to set dedup key")
+			}
+		}
+		return res, nil
+	})
+
+	res := v.(*result)
+	if code, ok := acceptedErrorCode(res.err); ok {
+		logger.Err(res.err).Str("code", code).Msg("accepted notification with error")
+		middleware.AcceptedWithError(r.Context(), code)
+		writeJSONBody(logger, w, http.StatusOK, &AcceptedWithErrorResponse{
+			Status: AcceptedWithErrorStatus,
+			Code:   code,
+		})
 		return
 	}
+	if res.code != http.StatusOK {
+		message := strings.ToLower(http.StatusText(res.code))
+		if res.err != nil {
+			message = res.err.Error()
+		}
+		writeJSONResponse(logger, w, res.code, message)
+		return
+	}
+	h.writeSuccessResponse(logger, w, res.res)
+}
+
+// detachedContext keeps the values of its parent, e.g. the logger and the
+// request id, without its deadline and cancellation.
+type detachedContext struct{ context.Context }
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
+
+// result is the outcome of processing a transaction update.
+type result struct {
+	code int
+	res  *Response
+	// err is set when the update is acknowledged with an error, see
+	// acceptedErrorCodes, or else it is the message of an error code.
+	err error
+}
+
+// outcome returns the outcome stored in the dedup store.
+func (r *result) outcome() string {
+	if code, ok := acceptedErrorCode(r.err); ok {
+		return code
+	}
+	if r.res != nil {
+		return r.res.Decision
+	}
+	return decisionIgnored
+}
+
+// processTransaction gets the reliable transaction status from midtrans and
+// updates the payment order task accordingly.
+func (h *Handler) processTransaction(ctx context.Context, logger zerolog.Logger, req *UpdateTransactionRequest) *result {
+	transactionGetter, err := h.initializeTransactionGetter(ctx, logger, req)
+	if err != nil {
+		return &result{code: http.StatusInternalServerError}
+	}
 
 	// ONLY USE REQUEST UNTIL THIS POINT.
 	// FOR THE REST, WE WILL USE THE DATA FROM getTransactionStatus RESPONSE!!!
-	trx, err := transactionGetter.GetTransactionStatus(req.OrderID)
+	trx, err := h.getTransactionStatus(ctx, logger, transactionGetter, req.OrderID)
 	if err != nil {
 		logger.Err(err).Msg("failed to get transaction from midtrans API")
 		// return http 400 to trigger retry from midtrans system.
 		// refer to: https://api-docs.midtrans.com/?go#best-practices-to-handle-notification
-		writeJSONResponse(w, http.StatusBadRequest)
-		return
+		return &result{code: http.StatusBadRequest}
 	}
 
 	// get order id from order task
@@ -147,49 +567,65 @@ func (h *Handler) HandleTransactionUpdate(w http.ResponseWriter, r *http.Request
 	})
 	if err != nil {
 		logger.Err(err).Msg("invalid task")
-		writeJSONResponse(w, http.StatusInternalServerError)
-		return
+		return &result{code: httpjson.GRPCToHTTP(err)}
+	}
+	if tasks == nil {
+		logger.Err(ErrEmptyOrderTaskResponse).Msg("invalid task")
+		return &result{code: http.StatusInternalServerError}
 	}
 
-	orderTask := &tpb.OrderTask{}
+	var orderTask *tpb.OrderTask
 	for _, t := range tasks.Tasks {
 		if t.TaskType == tpb.OrderTaskType_ORDER_TASK_TYPE_PAYMENT {
 			orderTask = t
 			break
 		}
 	}
+	// the task is unknown, midtrans redeliveries won't change that so the
+	// notification is acknowledged.
+	if len(tasks.Tasks) == 0 {
+		return &result{code: http.StatusOK, err: ErrOrderTaskNotFound}
+	}
+	// the order has tasks but none to pay it, there is no order to update.
+	if orderTask == nil || orderTask.TaskId == "" {
+		logger.Err(ErrPaymentTaskNotFound).Str("request_order_id", req.OrderID).Int("tasks", len(tasks.Tasks)).Msg("invalid task")
+		return &result{code: http.StatusNotFound, err: ErrPaymentTaskNotFound}
+	}
+	trace.SpanFromContext(ctx).SetAttributes(tracing.OrderIDKey.String(orderTask.OrderId))
+
+	trxStatus, ok := ParseTransactionStatus(trx.TransactionStatus)
+	if !ok {
+		// a status added by midtrans since, it is acknowledged so midtrans
+		// doesn't retry forever and counted as an accepted error to surface it.
+		logger.Warn().Str("raw_transaction_status", trx.TransactionStatus).Msg("unknown transaction status, order task left unchanged")
+		return &result{code: http.StatusOK, err: ErrUnknownTransactionStatus}
+	}
+
+	// refunds apply to already paid orders, they have their own checks.
+	switch trxStatus {
+	case RefundTransactionStatus, PartialRefundTransactionStatus:
+		return h.refundTask(ctx, logger, req, trx, trxStatus, orderTask)
+	}
 
 	// prevent update to already success tasks.
 	if orderTask.State == tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS {
 		logger.Info().Msg("order task is already marked successfull, ignoring")
-		writeJSONResponse(w, http.StatusOK)
-		return
+		return &result{code: http.StatusOK, res: &Response{
+			OrderID:   orderTask.OrderId,
+			TaskState: orderTask.State.String(),
+			Decision:  decisionAlreadyProcessed,
+		}}
 	}
 
 	logger = logger.With().Fields(map[string]interface{}{
 		"order_id": orderTask.OrderId,
 	}).Logger()
 
-	getRes, err := h.orderService.Get(ctx, &opb.GetRequest{OrderId: orderTask.OrderId})
-	if err != nil {
-		logger.Err(err).Msg("invalid order")
-		writeJSONResponse(w, http.StatusInternalServerError)
-		return
-	}
-	order := getRes.GetOrderData().Order
-
 	// check the transaction status should not success or failed.
 	// we don't want to update the transaction that already failed or success.
-	switch order.State {
-	case opb.OrderState_ORDER_STATE_PAID,
-		opb.OrderState_ORDER_STATE_CANCELLED,
-		opb.OrderState_ORDER_STATE_DONE:
-		logger = logger.With().Fields(map[string]interface{}{
-			"order_state": order.GetState().String(),
-		}).Logger()
-		logger.Err(payment.ErrInvalidOrder).Msg("invalid order state")
-		writeJSONResponse(w, http.StatusBadRequest)
-		return
+	order, failed := h.getOrder(ctx, logger, orderTask.OrderId, h.forbiddenOrderStates)
+	if failed != nil {
+		return failed
 	}
 
 	logger = logger.With().Fields(map[string]interface{}{
@@ -198,52 +634,275 @@ func (h *Handler) HandleTransactionUpdate(w http.ResponseWriter, r *http.Request
 		"transaction_fraud_status": trx.FraudStatus,
 	}).Logger()
 
+	res := &Response{
+		OrderID:   orderTask.OrderId,
+		TaskState: orderTask.State.String(),
+		Decision:  decisionIgnored,
+	}
+
+	// the timestamps are informational, they are recorded from the signature
+	// verified notification.
+	timestamps := req.Timestamps()
+
 	updateFn := func(s tpb.OrderTaskState) error {
 		logger.Info().Msg("updating order task")
 		if _, err := h.taskService.UpdateOrderTask(ctx, &tpb.UpdateOrderTaskRequest{
-			TaskId: orderTask.TaskId,
-			State:  s,
+			TaskId:         orderTask.TaskId,
+			State:          s,
+			AdditionalData: timestamps,
 		}); err != nil {
 			return err
 		}
 		logger.Info().Msg("successfully updating order task")
+
+		res.TaskState = s.String()
+		res.Decision = decisionFailed
+		if s == tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS {
+			res.Decision = decisionSuccess
+		}
 		return nil
 	}
 
-	switch strings.ToLower(trx.TransactionStatus) {
-	case CaptureTransactionStatus, SettlementTransactionStatus:
-		if trx.FraudStatus != "" && strings.ToLower(trx.FraudStatus) != FraudStatusAccept {
-			if err := updateFn(tpb.OrderTaskState_ORDER_TASK_STATE_FAILED); err != nil {
-				logger.Err(err).Msg("failed to update failed task")
-				writeJSONResponse(w, http.StatusInternalServerError)
-				return
+	// terminalUpdateFn persists a failed task for terminal statuses. The
+	// update is retried internally, returning an error to midtrans would only
+	// make it redeliver a notification we already know the outcome of.
+	terminalUpdateFn := func() error {
+		var err error
+		for attempt := 1; attempt <= h.terminalUpdateAttempts; attempt++ {
+			if err = updateFn(tpb.OrderTaskState_ORDER_TASK_STATE_FAILED); err == nil {
+				return nil
+			}
+			logger.Err(err).Int("attempt", attempt).Msg("failed to update failed task, retrying")
+
+			if attempt == h.terminalUpdateAttempts {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(h.terminalUpdateBackoff):
 			}
 		}
+		return err
+	}
 
-		// capture for VA and settlement for Gopay
-		if err := updateFn(tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS); err != nil {
-			logger.Err(err).Msg("failed to update success task")
-			writeJSONResponse(w, http.StatusInternalServerError)
-			return
+	switch trxStatus {
+	case CaptureTransactionStatus, SettlementTransactionStatus:
+		switch strings.ToLower(trx.FraudStatus) {
+		case "", FraudStatusAccept:
+			// never mark paid an order with a different total.
+			if !amountMatches(trx.GrossAmount, order.GetTotalAmount()) {
+				logger.Err(ErrAmountMismatch).
+					Str("gross_amount", trx.GrossAmount).
+					Int64("order_total_amount", order.GetTotalAmount()).
+					Msg("invalid transaction amount")
+				return &result{code: http.StatusBadRequest, err: ErrAmountMismatch}
+			}
+
+			// capture for VA and settlement for Gopay
+			if err := updateFn(tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS); err != nil {
+				logger.Err(err).Msg("failed to update success task")
+				return &result{code: httpjson.GRPCToHTTP(err)}
+			}
+		case FraudStatusChallenge:
+			// the transaction is held for review, midtrans notifies again
+			// once it is accepted or denied so the task is left pending.
+			logger.Info().Msg("transaction challenged by fraud detection, leaving order task pending")
+		default:
+			if err := terminalUpdateFn(); err != nil {
+				logger.Err(err).Msg("failed to update failed task")
+				return &result{code: httpjson.GRPCToHTTP(err)}
+			}
 		}
 	case ExpireTransactionStatus, FailureTransactionStatus,
 		CancelTransactionStatus, DenyTransactionStatus:
-		if err := updateFn(tpb.OrderTaskState_ORDER_TASK_STATE_FAILED); err != nil {
+		if err := terminalUpdateFn(); err != nil {
 			logger.Err(err).Msg("failed to update failed task")
-			writeJSONResponse(w, http.StatusInternalServerError)
-			return
+			return &result{code: httpjson.GRPCToHTTP(err)}
 		}
+	case PendingTransactionStatus, AuthorizedTransactionStatus,
+		ChargebackTransactionStatus, PartialChargebackTransactionStatus:
+		// the order task is not updated for these statuses.
+	case RefundTransactionStatus, PartialRefundTransactionStatus:
+		// handled by refundTask above.
 	}
 
 	logger.Info().Msg("successfully processing update transaction status request")
-	writeJSONResponse(w, http.StatusOK)
+	return &result{code: http.StatusOK, res: res}
 }
 
-func (h *Handler) initializeTransactionGetter(logger zerolog.Logger, req *UpdateTransactionRequest) (payment.TransactionGetter, error) {
+// getTransactionStatus gets the transaction status from midtrans, retrying
+// with exponential backoff so a network blip doesn't fail the notification.
+func (h *Handler) getTransactionStatus(ctx context.Context, logger zerolog.Logger, transactionGetter payment.TransactionGetter, orderID string) (*payment.TransactionStatus, error) {
+	delay := h.statusBaseDelay
+	for attempt := 1; ; attempt++ {
+		trx, err := transactionGetter.GetTransactionStatus(orderID)
+		if err == nil {
+			return trx, nil
+		}
+		if attempt >= h.statusAttempts {
+			return nil, err
+		}
+		logger.Err(err).Int("attempt", attempt).Msg("failed to get transaction from midtrans API, retrying")
+
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// getOrder gets the order of a payment task, it returns the result of the
+// notification instead when the order is missing or in one of the forbidden
+// states.
+func (h *Handler) getOrder(ctx context.Context, logger zerolog.Logger, orderID string, forbidden map[opb.OrderState]bool) (*opb.Order, *result) {
+	getRes, err := h.orderService.Get(ctx, &opb.GetRequest{OrderId: orderID})
+	if status.Code(err) == codes.NotFound {
+		return nil, &result{code: http.StatusOK, err: ErrOrderNotFound}
+	}
+	if err != nil {
+		logger.Err(err).Msg("invalid order")
+		return nil, &result{code: httpjson.GRPCToHTTP(err)}
+	}
+	order := getRes.GetOrderData().GetOrder()
+	if order == nil {
+		logger.Err(ErrEmptyOrderResponse).Msg("invalid order")
+		return nil, &result{code: http.StatusInternalServerError}
+	}
+
+	if forbidden[order.GetState()] {
+		logger = logger.With().Fields(map[string]interface{}{
+			"order_state": order.GetState().String(),
+		}).Logger()
+		logger.Err(payment.ErrInvalidOrder).Msg("invalid order state")
+		return nil, &result{code: http.StatusBadRequest}
+	}
+	return order, nil
+}
+
+// refundTask records the refund of trx in the additional data of the paid
+// payment task, the refunded amount is the transaction gross amount.
+func (h *Handler) refundTask(ctx context.Context, logger zerolog.Logger, req *UpdateTransactionRequest, trx *payment.TransactionStatus, trxStatus TransactionStatus, orderTask *tpb.OrderTask) *result {
+	logger = logger.With().Fields(map[string]interface{}{
+		"order_id":           orderTask.OrderId,
+		"transaction_status": trx.TransactionStatus,
+		"gross_amount":       trx.GrossAmount,
+	}).Logger()
+
+	// only a paid payment task can be refunded.
+	if orderTask.State != tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS {
+		// midtrans redeliveries won't pay the task, the notification is
+		// acknowledged.
+		logger.Warn().Str("task_state", orderTask.State.String()).Msg("refund of an unpaid task, order task left unchanged")
+		return &result{code: http.StatusOK, err: ErrRefundUnpaidTask}
+	}
+
+	if _, res := h.getOrder(ctx, logger, orderTask.OrderId, h.forbiddenRefundOrderStates); res != nil {
+		return res
+	}
+
+	amount, err := parseAmount(trx.GrossAmount)
+	if err != nil {
+		logger.Err(err).Msg("invalid refund amount")
+		return &result{code: http.StatusBadRequest}
+	}
+
+	data := req.Timestamps()
+	if data == nil {
+		data = make(map[string]string, 2)
+	}
+	data[RefundStatusKey] = string(trxStatus)
+	data[RefundAmountKey] = strconv.FormatFloat(amount, 'f', 2, 64)
+
+	logger.Info().Msg("refunding order task")
+	if _, err := h.taskService.UpdateOrderTask(ctx, &tpb.UpdateOrderTaskRequest{
+		TaskId:         orderTask.TaskId,
+		State:          tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+		AdditionalData: data,
+	}); err != nil {
+		logger.Err(err).Msg("failed to refund order task")
+		return &result{code: httpjson.GRPCToHTTP(err)}
+	}
+	logger.Info().Msg("successfully refunding order task")
+
+	return &result{code: http.StatusOK, res: &Response{
+		OrderID:   orderTask.OrderId,
+		TaskState: tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS.String(),
+		Decision:  decisionRefunded,
+	}}
+}
+
+// parseAmount parses a midtrans amount, e.g. "100000.00".
+func parseAmount(s string) (float64, error) {
+	amount, err := strconv.ParseFloat(s, 64)
+	if err != nil || amount < 0 || math.IsInf(amount, 0) || math.IsNaN(amount) {
+		return 0, ErrInvalidGrossAmount
+	}
+	return amount, nil
+}
+
+// amountMatches reports whether the midtrans gross amount equals the order
+// total, compared in cents.
+func amountMatches(grossAmount string, total int64) bool {
+	amount, err := parseAmount(grossAmount)
+	if err != nil {
+		return false
+	}
+	return int64(math.Round(amount*100)) == total*100
+}
+
+// writeSuccessResponse writes http 200, the body is only included when the
+// enriched response is enabled.
+func (h *Handler) writeSuccessResponse(logger zerolog.Logger, w http.ResponseWriter, res *Response) {
+	if !h.enrichedResponse {
+		writeSuccess(logger, w)
+		return
+	}
+	writeJSONBody(logger, w, http.StatusOK, res)
+}
+
+// signature returns the callback signature from the configured source.
+func (h *Handler) signature(r *http.Request, req *UpdateTransactionRequest) string {
+	if h.signatureHeader != "" {
+		return r.Header.Get(h.signatureHeader)
+	}
+	return req.SignatureKey
+}
+
+// redactedSignaturePrefix is the number of leading characters of a signature
+// kept in the logs.
+const redactedSignaturePrefix = 8
+
+// redactSignature returns the signature to log, only its first characters and
+// its length are kept. A signature too short to be truncated is fully
+// redacted.
+func redactSignature(sig string) string {
+	if sig == "" {
+		return ""
+	}
+	if len(sig) <= 2*redactedSignaturePrefix {
+		return fmt.Sprintf("[redacted](%d)", len(sig))
+	}
+	return fmt.Sprintf("%s...(%d)", sig[:redactedSignaturePrefix], len(sig))
+}
+
+func (h *Handler) initializeTransactionGetter(ctx context.Context, logger zerolog.Logger, req *UpdateTransactionRequest) (payment.TransactionGetter, error) {
 	var transactionGetter payment.TransactionGetter
 	var err error
 	switch req.PaymentType {
-	case payment.PaymentMethod_Gopay, payment.PaymentMethod_VirtualAccount:
+	case payment.PaymentMethod_Gopay, payment.PaymentMethod_VirtualAccount,
+		PaymentTypeQRIS, PaymentTypeShopeePay:
+		if h.httpClient != nil {
+			transactionGetter = &statusClient{
+				ctx:          ctx,
+				client:       h.httpClient,
+				getStatusURL: h.getStatusURL,
+				serverKey:    h.serverKey,
+			}
+			break
+		}
 		transactionGetter, err = transaction.NewTransaction(logger, h.chargeURL, h.getStatusURL, h.serverKey)
 		if err != nil {
 			logger.Err(ErrInternalServerError).Msg("error initialize transactionGetter")
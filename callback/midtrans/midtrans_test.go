@@ -2,18 +2,80 @@ package midtrans
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
 	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dropezy/storefront-backend/http/dedup"
+	"github.com/dropezy/storefront-backend/http/internal/httpjson"
+	"github.com/dropezy/storefront-backend/http/middleware"
+	"github.com/dropezy/storefront-backend/http/selftest"
+	"github.com/dropezy/storefront-backend/internal/integrations/payment"
 
 	opbmock "github.com/dropezy/proto/mock/order"
 	tpbmock "github.com/dropezy/proto/mock/task"
+	opb "github.com/dropezy/proto/v1/order"
+	tpb "github.com/dropezy/proto/v1/task"
 )
 
+const testServerKey = "askvnoibnosifnboseofinbofinfgbiufglnbfg"
+
+// testTotalAmount is the order total matching the "100000.00" gross amount
+// of the test transactions.
+const testTotalAmount = 100000
+
+// signature generates the midtrans callback signature for the given request.
+func signature(req UpdateTransactionRequest) string {
+	sum := sha512.Sum512([]byte(req.OrderID + req.StatusCode + req.GrossAmount + testServerKey))
+	return hex.EncodeToString(sum[:])
+}
+
+// newTransactionStatusServer returns a fake midtrans get status API
+// responding with trx, the returned string is the get status url.
+func newTransactionStatusServer(t *testing.T, trx map[string]string) string {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(trx); err != nil {
+			t.Error(err)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL + "/v2/%s/status"
+}
+
+// newTransactionUpdateRequest returns a signed transaction update request.
+func newTransactionUpdateRequest(t *testing.T, req UpdateTransactionRequest) *http.Request {
+	req.SignatureKey = signature(req)
+	breq, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.NewRequest(http.MethodPost, TransactionUpdatePath, bytes.NewBuffer(breq))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+	return r
+}
+
 func TestHandleTransactionUpdate(t *testing.T) {
 	serverKey := "askvnoibnosifnboseofinbofinfgbiufglnbfg"
 	t.Parallel()
@@ -45,7 +107,7 @@ func TestHandleTransactionUpdate(t *testing.T) {
 		}
 
 		r.Header.Set("Content-Type", "application/json")
-		h, err := NewHandler(serverKey, "localhost", "localhost", orderClient, taskClient)
+		h, err := NewHandler(serverKey, "http://localhost", "http://localhost", orderClient, taskClient)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -60,3 +122,1945 @@ func TestHandleTransactionUpdate(t *testing.T) {
 
 	})
 }
+
+func TestHandleTransactionUpdate_EnrichedResponse(t *testing.T) {
+	t.Parallel()
+
+	settlement := UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}
+	getStatusURL := newTransactionStatusServer(t, map[string]string{
+		"status_code":        "200",
+		"transaction_status": string(SettlementTransactionStatus),
+		"fraud_status":       FraudStatusAccept,
+		"gross_amount":       "100000.00",
+	})
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		wantBody *Response
+	}{
+		{
+			name: "Disabled",
+		},
+		{
+			name: "Enabled",
+			opts: []Option{WithEnrichedResponse(true)},
+			wantBody: &Response{
+				OrderID:   "order-id",
+				TaskState: tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS.String(),
+				Decision:  decisionSuccess,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+			taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+			taskClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{
+				Tasks: []*tpb.OrderTask{{
+					TaskId:   "payment-task-id",
+					OrderId:  "order-id",
+					TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PAYMENT,
+				}},
+			}, nil)
+			orderClient.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&opb.GetResponse{
+				OrderData: &opb.OrderData{Order: &opb.Order{TotalAmount: testTotalAmount}},
+			}, nil)
+			taskClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.UpdateOrderTaskResponse{}, nil)
+
+			h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, settlement))
+
+			resp := w.Result()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("want http 200, got : %v", resp.StatusCode)
+			}
+
+			if test.wantBody == nil {
+				got := &httpjson.Response{}
+				if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+					t.Fatal(err)
+				}
+				if got.Message != "success" {
+					t.Fatalf("want message success, got : %q", got.Message)
+				}
+				return
+			}
+
+			got := &Response{}
+			if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.wantBody) {
+				t.Fatalf("want body %+v, got : %+v", test.wantBody, got)
+			}
+		})
+	}
+}
+
+func TestHandleTransactionUpdate_SignatureHeader(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+	taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+	h, err := NewHandler(testServerKey, "http://localhost", "http://localhost", orderClient, taskClient,
+		WithSignatureHeader("X-Signature"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// pending transactions are acknowledged right after the signature check.
+	pending := UpdateTransactionRequest{
+		OrderID:           "1111",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(PendingTransactionStatus),
+		GrossAmount:       "100000.00",
+		StatusCode:        "201",
+	}
+
+	tests := []struct {
+		name     string
+		header   string
+		wantCode int
+	}{
+		{
+			name:     "ValidSignatureHeader",
+			header:   signature(pending),
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "InvalidSignatureHeader",
+			header:   "invalid-signature",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "MissingSignatureHeader",
+			wantCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			// the signature is only read from the configured header.
+			r := newTransactionUpdateRequest(t, pending)
+			if test.header != "" {
+				r.Header.Set("X-Signature", test.header)
+			}
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != test.wantCode {
+				t.Fatalf("want http %v, got : %v", test.wantCode, got)
+			}
+		})
+	}
+}
+
+func TestHandleTransactionUpdate_DenyRetry(t *testing.T) {
+	t.Parallel()
+
+	deny := UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(DenyTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "202",
+	}
+	getStatusURL := newTransactionStatusServer(t, map[string]string{
+		"status_code":        "202",
+		"transaction_status": string(DenyTransactionStatus),
+		"gross_amount":       "100000.00",
+	})
+
+	tests := []struct {
+		name        string
+		updateCalls []error
+		wantCode    int
+	}{
+		{
+			name:        "FailedThenSuccess",
+			updateCalls: []error{errors.New("unavailable"), nil},
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "RetriesExhausted",
+			updateCalls: []error{errors.New("unavailable"), errors.New("unavailable")},
+			wantCode:    http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+			taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+			taskClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{
+				Tasks: []*tpb.OrderTask{{
+					TaskId:   "payment-task-id",
+					OrderId:  "order-id",
+					TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PAYMENT,
+				}},
+			}, nil)
+			orderClient.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&opb.GetResponse{
+				OrderData: &opb.OrderData{Order: &opb.Order{TotalAmount: testTotalAmount}},
+			}, nil)
+
+			calls := make([]*gomock.Call, 0, len(test.updateCalls))
+			for _, err := range test.updateCalls {
+				call := taskClient.EXPECT().UpdateOrderTask(gomock.Any(), &tpb.UpdateOrderTaskRequest{
+					TaskId: "payment-task-id",
+					State:  tpb.OrderTaskState_ORDER_TASK_STATE_FAILED,
+				})
+				if err != nil {
+					call.Return(nil, err)
+				} else {
+					call.Return(&tpb.UpdateOrderTaskResponse{}, nil)
+				}
+				calls = append(calls, call)
+			}
+			gomock.InOrder(calls...)
+
+			h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient,
+				WithTerminalUpdateRetry(len(test.updateCalls), time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, deny))
+
+			if got := w.Result().StatusCode; got != test.wantCode {
+				t.Fatalf("want http %v, got : %v", test.wantCode, got)
+			}
+		})
+	}
+}
+
+func TestHandleTransactionUpdate_CoalesceDuplicates(t *testing.T) {
+	t.Parallel()
+
+	const duplicates = 5
+
+	settlement := UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}
+
+	// the fake midtrans API blocks until every duplicate is in-flight.
+	var hits int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+		<-release
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(map[string]string{
+			"status_code":        "200",
+			"transaction_status": string(SettlementTransactionStatus),
+			"fraud_status":       FraudStatusAccept,
+			"gross_amount":       "100000.00",
+		}); err != nil {
+			t.Error(err)
+		}
+	}))
+	defer srv.Close()
+
+	ctrl := gomock.NewController(t)
+	orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+	taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+	taskClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{
+		Tasks: []*tpb.OrderTask{{
+			TaskId:   "payment-task-id",
+			OrderId:  "order-id",
+			TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PAYMENT,
+		}},
+	}, nil).Times(1)
+	orderClient.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&opb.GetResponse{
+		OrderData: &opb.OrderData{Order: &opb.Order{TotalAmount: testTotalAmount}},
+	}, nil).Times(1)
+	taskClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.UpdateOrderTaskResponse{}, nil).Times(1)
+
+	h, err := NewHandler(testServerKey, "http://localhost", srv.URL+"/v2/%s/status", orderClient, taskClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	codes := make(chan int, duplicates)
+	for i := 0; i < duplicates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, settlement))
+			codes <- w.Result().StatusCode
+		}()
+	}
+
+	// give the duplicates time to join the in-flight execution.
+	time.Sleep(100 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	for code := range codes {
+		if code != http.StatusOK {
+			t.Errorf("want http 200, got : %v", code)
+		}
+	}
+	if got := atomic.LoadInt32(&hits); got != 1 {
+		t.Fatalf("want 1 call to midtrans API, got : %v", got)
+	}
+}
+
+// expectPaymentTask mocks GetOrderTask returning a pending payment task.
+func expectPaymentTask(taskClient *tpbmock.MockTaskServiceClient) *gomock.Call {
+	return taskClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{
+		Tasks: []*tpb.OrderTask{{
+			TaskId:   "payment-task-id",
+			OrderId:  "order-id",
+			TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PAYMENT,
+		}},
+	}, nil)
+}
+
+// expectOrder mocks the order service Get returning an order in state.
+func expectOrder(orderClient *opbmock.MockOrderServiceClient, state opb.OrderState) *gomock.Call {
+	return orderClient.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&opb.GetResponse{
+		OrderData: &opb.OrderData{Order: &opb.Order{State: state, TotalAmount: testTotalAmount}},
+	}, nil)
+}
+
+func TestDetachedContext(t *testing.T) {
+	t.Parallel()
+
+	type key struct{}
+	parent, cancelFn := context.WithCancel(context.WithValue(context.Background(), key{}, "value"))
+	cancelFn()
+
+	ctx, cancelFn := context.WithTimeout(detachedContext{parent}, time.Minute)
+	defer cancelFn()
+
+	if err := ctx.Err(); err != nil {
+		t.Fatalf("Err() got = %v, want = nil", err)
+	}
+	if _, ok := ctx.Deadline(); !ok {
+		t.Fatal("Deadline() want own deadline")
+	}
+	if got := ctx.Value(key{}); got != "value" {
+		t.Fatalf("Value() got = %v, want = value", got)
+	}
+}
+
+func TestHandleTransactionUpdate_ForbiddenOrderStates(t *testing.T) {
+	t.Parallel()
+
+	settlement := UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}
+	getStatusURL := newTransactionStatusServer(t, map[string]string{
+		"status_code":        "200",
+		"transaction_status": string(SettlementTransactionStatus),
+		"fraud_status":       FraudStatusAccept,
+		"gross_amount":       "100000.00",
+	})
+
+	states, err := ParseOrderStates("ORDER_STATE_DONE")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		opts       []Option
+		orderState opb.OrderState
+		wantUpdate bool
+		wantCode   int
+	}{
+		{
+			name:       "DefaultPaidBlocked",
+			orderState: opb.OrderState_ORDER_STATE_PAID,
+			wantCode:   http.StatusBadRequest,
+		},
+		{
+			name:       "ConfiguredDoneBlocked",
+			opts:       []Option{WithForbiddenOrderStates(states)},
+			orderState: opb.OrderState_ORDER_STATE_DONE,
+			wantCode:   http.StatusBadRequest,
+		},
+		{
+			name:       "ConfiguredPaidAllowed",
+			opts:       []Option{WithForbiddenOrderStates(states)},
+			orderState: opb.OrderState_ORDER_STATE_PAID,
+			wantUpdate: true,
+			wantCode:   http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+			taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+			expectPaymentTask(taskClient)
+			expectOrder(orderClient, test.orderState)
+			if test.wantUpdate {
+				taskClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.UpdateOrderTaskResponse{}, nil)
+			}
+
+			h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, settlement))
+
+			if got := w.Result().StatusCode; got != test.wantCode {
+				t.Fatalf("want http %v, got : %v", test.wantCode, got)
+			}
+		})
+	}
+}
+
+func TestParseOrderStates(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseOrderStates("ORDER_STATE_PAID, ORDER_STATE_DONE")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []opb.OrderState{opb.OrderState_ORDER_STATE_PAID, opb.OrderState_ORDER_STATE_DONE}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("ParseOrderStates(), got = %v, want = %v", got, want)
+	}
+
+	if _, err := ParseOrderStates("ORDER_STATE_WHATEVER"); err == nil {
+		t.Fatal("ParseOrderStates(), got nil error for an unknown state")
+	}
+}
+
+func TestHandleTransactionUpdate_AcceptedWithError(t *testing.T) {
+	t.Parallel()
+
+	settlement := UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}
+	getStatusURL := newTransactionStatusServer(t, map[string]string{
+		"status_code":        "200",
+		"transaction_status": string(SettlementTransactionStatus),
+		"fraud_status":       FraudStatusAccept,
+		"gross_amount":       "100000.00",
+	})
+
+	tests := []struct {
+		name     string
+		mockFn   func(*opbmock.MockOrderServiceClient, *tpbmock.MockTaskServiceClient)
+		wantCode int
+		want     *AcceptedWithErrorResponse
+	}{
+		{
+			name: "OrderTaskNotFound",
+			mockFn: func(orderClient *opbmock.MockOrderServiceClient, taskClient *tpbmock.MockTaskServiceClient) {
+				taskClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{}, nil)
+			},
+			wantCode: http.StatusOK,
+			want:     &AcceptedWithErrorResponse{Status: AcceptedWithErrorStatus, Code: "order_task_not_found"},
+		},
+		{
+			name: "OrderNotFound",
+			mockFn: func(orderClient *opbmock.MockOrderServiceClient, taskClient *tpbmock.MockTaskServiceClient) {
+				expectPaymentTask(taskClient)
+				orderClient.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.NotFound, "order not found"))
+			},
+			wantCode: http.StatusOK,
+			want:     &AcceptedWithErrorResponse{Status: AcceptedWithErrorStatus, Code: "order_not_found"},
+		},
+		{
+			name: "OrderServiceUnavailable",
+			mockFn: func(orderClient *opbmock.MockOrderServiceClient, taskClient *tpbmock.MockTaskServiceClient) {
+				expectPaymentTask(taskClient)
+				orderClient.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Unavailable, "unavailable"))
+			},
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name: "OrderServiceInternal",
+			mockFn: func(orderClient *opbmock.MockOrderServiceClient, taskClient *tpbmock.MockTaskServiceClient) {
+				expectPaymentTask(taskClient)
+				orderClient.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Internal, "internal"))
+			},
+			wantCode: http.StatusInternalServerError,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+			taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+			test.mockFn(orderClient, taskClient)
+
+			h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, settlement))
+
+			resp := w.Result()
+			if resp.StatusCode != test.wantCode {
+				t.Fatalf("want http %v, got : %v", test.wantCode, resp.StatusCode)
+			}
+			if test.want == nil {
+				return
+			}
+
+			got := &AcceptedWithErrorResponse{}
+			if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Fatalf("HandleTransactionUpdate(), got = %v, want = %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHandleTransactionUpdate_UnknownTransactionStatus(t *testing.T) {
+	t.Parallel()
+
+	getStatusURL := newTransactionStatusServer(t, map[string]string{
+		"status_code":        "200",
+		"transaction_status": "something_new",
+		"gross_amount":       "100000.00",
+	})
+
+	ctrl := gomock.NewController(t)
+	orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+	taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+	// neither the order is read nor the order task updated.
+	expectPaymentTask(taskClient)
+
+	h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: "something_new",
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}))
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("want http %v, got : %v", http.StatusOK, resp.StatusCode)
+	}
+	got := &AcceptedWithErrorResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	want := &AcceptedWithErrorResponse{Status: AcceptedWithErrorStatus, Code: "unknown_transaction_status"}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("HandleTransactionUpdate(), got = %v, want = %v", got, want)
+	}
+}
+
+func TestHandleTransactionUpdate_NilResponse(t *testing.T) {
+	t.Parallel()
+
+	settlement := UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}
+	getStatusURL := newTransactionStatusServer(t, map[string]string{
+		"status_code":        "200",
+		"transaction_status": string(SettlementTransactionStatus),
+		"fraud_status":       FraudStatusAccept,
+		"gross_amount":       "100000.00",
+	})
+
+	tests := []struct {
+		name   string
+		mockFn func(*opbmock.MockOrderServiceClient, *tpbmock.MockTaskServiceClient)
+	}{
+		{
+			name: "NilOrderTaskResponse",
+			mockFn: func(orderClient *opbmock.MockOrderServiceClient, taskClient *tpbmock.MockTaskServiceClient) {
+				taskClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(nil, nil)
+			},
+		},
+		{
+			name: "NilOrderResponse",
+			mockFn: func(orderClient *opbmock.MockOrderServiceClient, taskClient *tpbmock.MockTaskServiceClient) {
+				expectPaymentTask(taskClient)
+				orderClient.EXPECT().Get(gomock.Any(), gomock.Any()).Return(nil, nil)
+			},
+		},
+		{
+			name: "EmptyOrderData",
+			mockFn: func(orderClient *opbmock.MockOrderServiceClient, taskClient *tpbmock.MockTaskServiceClient) {
+				expectPaymentTask(taskClient)
+				orderClient.EXPECT().Get(gomock.Any(), gomock.Any()).Return(&opb.GetResponse{OrderData: &opb.OrderData{}}, nil)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+			taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+			test.mockFn(orderClient, taskClient)
+
+			h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, settlement))
+
+			if got := w.Result().StatusCode; got != http.StatusInternalServerError {
+				t.Fatalf("want http %v, got : %v", http.StatusInternalServerError, got)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		transactionID string
+		// statusCode defaults to 200.
+		statusCode string
+		wantErr    error
+	}{
+		{
+			name:          "UUID",
+			transactionID: uuid.NewString(),
+		},
+		{
+			name:          "Alphanumeric",
+			transactionID: "trx_12345-A",
+		},
+		{
+			name:          "ExpiredStatusCode",
+			transactionID: "trx_12345-A",
+			statusCode:    "407",
+		},
+		{
+			name:          "GarbageStatusCode",
+			transactionID: "trx_12345-A",
+			statusCode:    "20x",
+			wantErr:       ErrInvalidStatusCode,
+		},
+		{
+			name:          "UnknownStatusCode",
+			transactionID: "trx_12345-A",
+			statusCode:    "299",
+			wantErr:       ErrInvalidStatusCode,
+		},
+		{
+			name:    "Empty",
+			wantErr: ErrTransactionIDIsRequired,
+		},
+		{
+			name:          "TooLong",
+			transactionID: strings.Repeat("a", maxTransactionIDLen+1),
+			wantErr:       ErrInvalidTransactionID,
+		},
+		{
+			name:          "InvalidCharset",
+			transactionID: "trx-1\n{\"level\":\"info\"}",
+			wantErr:       ErrInvalidTransactionID,
+		},
+		{
+			name:          "MalformedUUID",
+			transactionID: "zzzzzzzz-e29b-41d4-a716-446655440000",
+			wantErr:       ErrInvalidTransactionID,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := &UpdateTransactionRequest{TransactionID: test.transactionID, StatusCode: test.statusCode}
+			if req.StatusCode == "" {
+				req.StatusCode = "200"
+			}
+			if err := req.Validate(); err != test.wantErr {
+				t.Fatalf("Validate(), got = %v, want = %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestParseTransactionStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		in     string
+		want   TransactionStatus
+		wantOK bool
+	}{
+		{in: "settlement", want: SettlementTransactionStatus, wantOK: true},
+		{in: " Partial_Refund ", want: PartialRefundTransactionStatus, wantOK: true},
+		{in: "PENDING", want: PendingTransactionStatus, wantOK: true},
+		{in: "Something_New", want: "something_new"},
+		{in: ""},
+	}
+
+	for _, test := range tests {
+		got, ok := ParseTransactionStatus(test.in)
+		if got != test.want || ok != test.wantOK {
+			t.Fatalf("ParseTransactionStatus(%q), got = %q %v, want = %q %v", test.in, got, ok, test.want, test.wantOK)
+		}
+	}
+}
+
+func TestTimestamps(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name            string
+		transactionTime string
+		settlementTime  string
+		want            map[string]string
+	}{
+		{
+			name:            "Both",
+			transactionTime: "2022-06-21 14:05:00",
+			settlementTime:  "2022-06-21 14:06:30",
+			want: map[string]string{
+				"transaction_time": "2022-06-21T14:05:00+07:00",
+				"settlement_time":  "2022-06-21T14:06:30+07:00",
+			},
+		},
+		{
+			name:            "NotSettled",
+			transactionTime: "2022-06-21 14:05:00",
+			want:            map[string]string{"transaction_time": "2022-06-21T14:05:00+07:00"},
+		},
+		{
+			name:            "Unparseable",
+			transactionTime: "21/06/2022 14:05",
+			settlementTime:  "2022-06-21 14:06:30",
+			want:            map[string]string{"settlement_time": "2022-06-21T14:06:30+07:00"},
+		},
+		{
+			name: "Empty",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := &UpdateTransactionRequest{TransactionTime: test.transactionTime, SettlementTime: test.settlementTime}
+			if got := req.Timestamps(); !cmp.Equal(got, test.want) {
+				t.Fatalf("Timestamps(), got = %v, want = %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHandleTransactionUpdate_Timestamps(t *testing.T) {
+	t.Parallel()
+
+	getStatusURL := newTransactionStatusServer(t, map[string]string{
+		"status_code":        "200",
+		"transaction_status": string(SettlementTransactionStatus),
+		"fraud_status":       FraudStatusAccept,
+		"gross_amount":       "100000.00",
+	})
+
+	ctrl := gomock.NewController(t)
+	orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+	taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+	expectPaymentTask(taskClient)
+	expectOrder(orderClient, opb.OrderState_ORDER_STATE_WAITING_FOR_PAYMENT)
+	taskClient.EXPECT().UpdateOrderTask(gomock.Any(), &tpb.UpdateOrderTaskRequest{
+		TaskId: "payment-task-id",
+		State:  tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+		AdditionalData: map[string]string{
+			"transaction_time": "2022-06-21T14:05:00+07:00",
+			"settlement_time":  "2022-06-21T14:06:30+07:00",
+		},
+	}).Return(&tpb.UpdateOrderTaskResponse{}, nil)
+
+	h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		TransactionTime:   "2022-06-21 14:05:00",
+		SettlementTime:    "2022-06-21 14:06:30",
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}))
+
+	if got := w.Result().StatusCode; got != http.StatusOK {
+		t.Fatalf("want http %v, got : %v", http.StatusOK, got)
+	}
+}
+
+func TestHandleTransactionUpdate_InvalidTransactionID(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+	taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+	h, err := NewHandler(testServerKey, "http://localhost", "http://localhost/%s/status", orderClient, taskClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}
+	w := httptest.NewRecorder()
+	http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, req))
+
+	if got := w.Result().StatusCode; got != http.StatusBadRequest {
+		t.Fatalf("want http %v, got : %v", http.StatusBadRequest, got)
+	}
+}
+
+func TestHandleTransactionUpdate_InvalidStatusCode(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+	taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+	// midtrans get status must not be called.
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected get status call: %s", r.URL.Path)
+	}))
+	t.Cleanup(srv.Close)
+
+	h, err := NewHandler(testServerKey, "http://localhost", srv.URL+"/%s/status", orderClient, taskClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "OK",
+	}
+	w := httptest.NewRecorder()
+	http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, req))
+
+	if got := w.Result().StatusCode; got != http.StatusBadRequest {
+		t.Fatalf("want http %v, got : %v", http.StatusBadRequest, got)
+	}
+	got := &httpjson.Response{}
+	if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Message != ErrInvalidStatusCode.Error() {
+		t.Fatalf("want message %q, got : %q", ErrInvalidStatusCode.Error(), got.Message)
+	}
+}
+
+func TestRedactSignature(t *testing.T) {
+	t.Parallel()
+
+	sig := strings.Repeat("ab", 64)
+	tests := []struct {
+		name string
+		sig  string
+		want string
+	}{
+		{name: "Empty", sig: "", want: ""},
+		{name: "Short", sig: "abcdef", want: "[redacted](6)"},
+		{name: "SHA512", sig: sig, want: "abababab...(128)"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := redactSignature(test.sig); got != test.want {
+				t.Fatalf("redactSignature(), got = %q, want = %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestHandleTransactionUpdate_RedactedSignatureLogs(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	h, err := NewHandler(testServerKey, "http://localhost", "http://localhost",
+		opbmock.NewMockOrderServiceClient(ctrl), tpbmock.NewMockTaskServiceClient(ctrl))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := UpdateTransactionRequest{
+		OrderID:           "1111",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}
+	// signed with another server key so the mismatch is logged.
+	sum := sha512.Sum512([]byte(req.OrderID + req.StatusCode + req.GrossAmount + "other-server-key"))
+	req.SignatureKey = hex.EncodeToString(sum[:])
+	body, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var logs bytes.Buffer
+	r, err := http.NewRequest(http.MethodPost, TransactionUpdatePath, bytes.NewBuffer(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+	logger := zerolog.New(&logs)
+	r = r.WithContext(logger.WithContext(r.Context()))
+
+	w := httptest.NewRecorder()
+	http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, r)
+
+	if got := w.Result().StatusCode; got != http.StatusBadRequest {
+		t.Fatalf("want http %v, got : %v", http.StatusBadRequest, got)
+	}
+	if strings.Contains(logs.String(), req.SignatureKey) {
+		t.Fatalf("logs contain the signature: %s", logs.String())
+	}
+	if strings.Contains(logs.String(), testServerKey) {
+		t.Fatalf("logs contain the server key: %s", logs.String())
+	}
+	if !strings.Contains(logs.String(), redactSignature(req.SignatureKey)) {
+		t.Fatalf("logs, want the redacted signature in %s", logs.String())
+	}
+}
+
+func TestSelfTestRequest(t *testing.T) {
+	t.Parallel()
+
+	for _, opts := range [][]Option{
+		nil,
+		{WithSignatureHeader("X-Signature")},
+		{WithAPIKey("X-Api-Key", "api-key")},
+	} {
+		opts = append(opts, WithHTTPClient(SelfTestHTTPClient()))
+		h, err := NewHandler(testServerKey, "http://localhost", "http://localhost/%s/status",
+			&selftest.OrderClient{}, &selftest.TaskClient{TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PAYMENT}, opts...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, err := h.SelfTestRequest(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		middleware.Verify(h.Verifier())(http.HandlerFunc(h.HandleTransactionUpdate)).ServeHTTP(w, r)
+		if w.Code != http.StatusOK {
+			t.Fatalf("HandleTransactionUpdate(), got = %v, want = %v, body = %s", w.Code, http.StatusOK, w.Body)
+		}
+	}
+}
+
+func TestHandleTransactionUpdate_MethodNotPost(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	// no call is expected on the clients, a GET must not be processed.
+	orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+	taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+	h, err := NewHandler(testServerKey, "http://localhost", "http://localhost/%s/status", orderClient, taskClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := newTransactionUpdateRequest(t, UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	})
+	r.Method = http.MethodGet
+
+	w := httptest.NewRecorder()
+	http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("want http %v, got : %v", http.StatusMethodNotAllowed, resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "application/json" {
+		t.Fatalf("want content type %v, got : %v", "application/json", got)
+	}
+}
+
+func TestHandleTransactionUpdate_OnlyNonPaymentTasks(t *testing.T) {
+	t.Parallel()
+
+	getStatusURL := newTransactionStatusServer(t, map[string]string{
+		"status_code":        "200",
+		"transaction_status": string(SettlementTransactionStatus),
+		"fraud_status":       FraudStatusAccept,
+		"gross_amount":       "100000.00",
+	})
+
+	ctrl := gomock.NewController(t)
+	// orderService.Get must not be called with an empty order id.
+	orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+	taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+	taskClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{
+		Tasks: []*tpb.OrderTask{
+			{TaskId: "picking-task-id", OrderId: "order-id", TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PICKING},
+			{TaskId: "delivery-task-id", OrderId: "order-id", TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_DELIVERY},
+		},
+	}, nil)
+
+	h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}))
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("want http %v, got : %v", http.StatusNotFound, resp.StatusCode)
+	}
+	got := &httpjson.Response{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Message != ErrPaymentTaskNotFound.Error() {
+		t.Fatalf("HandleTransactionUpdate(), got = %q, want = %q", got.Message, ErrPaymentTaskNotFound.Error())
+	}
+}
+
+func TestHandleTransactionUpdate_Refund(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		status      TransactionStatus
+		grossAmount string
+		taskState   tpb.OrderTaskState
+		// orderState is the state of the order, the order is not fetched
+		// when unspecified.
+		orderState opb.OrderState
+		wantUpdate bool
+		wantAmount string
+		wantCode   int
+		// wantAccepted is the code of a notification accepted with an error.
+		wantAccepted string
+	}{
+		{
+			name:        "Refund",
+			status:      RefundTransactionStatus,
+			grossAmount: "100000.00",
+			taskState:   tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+			orderState:  opb.OrderState_ORDER_STATE_PAID,
+			wantUpdate:  true,
+			wantAmount:  "100000.00",
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "PartialRefund",
+			status:      PartialRefundTransactionStatus,
+			grossAmount: "2500.5",
+			taskState:   tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+			orderState:  opb.OrderState_ORDER_STATE_DONE,
+			wantUpdate:  true,
+			wantAmount:  "2500.50",
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "InvalidGrossAmount",
+			status:      RefundTransactionStatus,
+			grossAmount: "abc",
+			taskState:   tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+			orderState:  opb.OrderState_ORDER_STATE_PAID,
+			wantCode:    http.StatusBadRequest,
+		},
+		{
+			name:         "UnpaidTask",
+			status:       RefundTransactionStatus,
+			grossAmount:  "100000.00",
+			taskState:    tpb.OrderTaskState_ORDER_TASK_STATE_UNSPECIFIED,
+			wantCode:     http.StatusOK,
+			wantAccepted: "refund_unpaid_task",
+		},
+		{
+			name:        "ForbiddenOrderState",
+			status:      RefundTransactionStatus,
+			grossAmount: "100000.00",
+			taskState:   tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+			orderState:  opb.OrderState_ORDER_STATE_WAITING_FOR_PAYMENT,
+			wantCode:    http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			getStatusURL := newTransactionStatusServer(t, map[string]string{
+				"status_code":        "200",
+				"transaction_status": string(test.status),
+				"gross_amount":       test.grossAmount,
+			})
+
+			ctrl := gomock.NewController(t)
+			orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+			taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+			taskClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{
+				Tasks: []*tpb.OrderTask{{
+					TaskId:   "payment-task-id",
+					OrderId:  "order-id",
+					TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PAYMENT,
+					State:    test.taskState,
+				}},
+			}, nil)
+			if test.orderState != opb.OrderState_ORDER_STATE_UNSPECIFIED {
+				expectOrder(orderClient, test.orderState)
+			}
+			if test.wantUpdate {
+				taskClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, req *tpb.UpdateOrderTaskRequest, _ ...interface{}) (*tpb.UpdateOrderTaskResponse, error) {
+						if req.TaskId != "payment-task-id" {
+							t.Errorf("UpdateOrderTask() task id, got = %v, want = payment-task-id", req.TaskId)
+						}
+						want := map[string]string{
+							RefundStatusKey: string(test.status),
+							RefundAmountKey: test.wantAmount,
+						}
+						if diff := cmp.Diff(want, req.AdditionalData); diff != "" {
+							t.Errorf("UpdateOrderTask() additional data mismatch (-want +got):\n%s", diff)
+						}
+						return &tpb.UpdateOrderTaskResponse{}, nil
+					})
+			}
+
+			h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient,
+				WithEnrichedResponse(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, UpdateTransactionRequest{
+				OrderID:           "payment-task-id",
+				TransactionID:     uuid.NewString(),
+				TransactionStatus: string(test.status),
+				PaymentType:       payment.PaymentMethod_Gopay,
+				GrossAmount:       test.grossAmount,
+				StatusCode:        "200",
+			}))
+
+			if got := w.Result().StatusCode; got != test.wantCode {
+				t.Fatalf("want http %v, got : %v", test.wantCode, got)
+			}
+			if test.wantAccepted != "" {
+				got := &AcceptedWithErrorResponse{}
+				if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+					t.Fatal(err)
+				}
+				want := &AcceptedWithErrorResponse{Status: AcceptedWithErrorStatus, Code: test.wantAccepted}
+				if diff := cmp.Diff(want, got); diff != "" {
+					t.Fatalf("response mismatch (-want +got):\n%s", diff)
+				}
+			}
+			if !test.wantUpdate {
+				return
+			}
+
+			got := &Response{}
+			if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			want := &Response{
+				OrderID:   "order-id",
+				TaskState: tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS.String(),
+				Decision:  decisionRefunded,
+			}
+			if diff := cmp.Diff(want, got); diff != "" {
+				t.Fatalf("response mismatch (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHandleTransactionUpdate_FraudStatus(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name         string
+		fraudStatus  string
+		wantState    tpb.OrderTaskState
+		wantDecision string
+	}{
+		{
+			name:         "Accept",
+			fraudStatus:  FraudStatusAccept,
+			wantState:    tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+			wantDecision: decisionSuccess,
+		},
+		{
+			name:         "Challenge",
+			fraudStatus:  FraudStatusChallenge,
+			wantState:    tpb.OrderTaskState_ORDER_TASK_STATE_UNSPECIFIED,
+			wantDecision: decisionIgnored,
+		},
+		{
+			name:         "Deny",
+			fraudStatus:  FraudStatusDeny,
+			wantState:    tpb.OrderTaskState_ORDER_TASK_STATE_FAILED,
+			wantDecision: decisionFailed,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			getStatusURL := newTransactionStatusServer(t, map[string]string{
+				"status_code":        "200",
+				"transaction_status": string(CaptureTransactionStatus),
+				"fraud_status":       test.fraudStatus,
+				"gross_amount":       "100000.00",
+			})
+
+			ctrl := gomock.NewController(t)
+			orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+			taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+			expectPaymentTask(taskClient)
+			expectOrder(orderClient, opb.OrderState_ORDER_STATE_WAITING_FOR_PAYMENT)
+			// a single update with the final state, a challenged
+			// transaction is not updated at all.
+			if test.wantState != tpb.OrderTaskState_ORDER_TASK_STATE_UNSPECIFIED {
+				taskClient.EXPECT().UpdateOrderTask(gomock.Any(), &tpb.UpdateOrderTaskRequest{
+					TaskId: "payment-task-id",
+					State:  test.wantState,
+				}).Return(&tpb.UpdateOrderTaskResponse{}, nil)
+			}
+
+			h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient,
+				WithEnrichedResponse(true))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, UpdateTransactionRequest{
+				OrderID:           "payment-task-id",
+				TransactionID:     uuid.NewString(),
+				TransactionStatus: string(CaptureTransactionStatus),
+				FraudStatus:       test.fraudStatus,
+				PaymentType:       payment.PaymentMethod_VirtualAccount,
+				GrossAmount:       "100000.00",
+				StatusCode:        "200",
+			}))
+
+			if got := w.Result().StatusCode; got != http.StatusOK {
+				t.Fatalf("want http %v, got : %v", http.StatusOK, got)
+			}
+			got := &Response{}
+			if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Decision != test.wantDecision {
+				t.Fatalf("decision, got = %v, want = %v", got.Decision, test.wantDecision)
+			}
+		})
+	}
+}
+
+func TestHandleTransactionUpdate_AmountMismatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		grossAmount string
+		wantUpdate  bool
+		wantCode    int
+		wantMessage string
+	}{
+		{
+			name:        "Match",
+			grossAmount: "100000.00",
+			wantUpdate:  true,
+			wantCode:    http.StatusOK,
+		},
+		{
+			name:        "Mismatch",
+			grossAmount: "1000.00",
+			wantCode:    http.StatusBadRequest,
+			wantMessage: ErrAmountMismatch.Error(),
+		},
+		{
+			name:        "FractionalMismatch",
+			grossAmount: "100000.50",
+			wantCode:    http.StatusBadRequest,
+			wantMessage: ErrAmountMismatch.Error(),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			getStatusURL := newTransactionStatusServer(t, map[string]string{
+				"status_code":        "200",
+				"transaction_status": string(SettlementTransactionStatus),
+				"fraud_status":       FraudStatusAccept,
+				"gross_amount":       test.grossAmount,
+			})
+
+			ctrl := gomock.NewController(t)
+			orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+			taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+			expectPaymentTask(taskClient)
+			expectOrder(orderClient, opb.OrderState_ORDER_STATE_WAITING_FOR_PAYMENT)
+			if test.wantUpdate {
+				taskClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.UpdateOrderTaskResponse{}, nil)
+			}
+
+			h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, UpdateTransactionRequest{
+				OrderID:           "payment-task-id",
+				TransactionID:     uuid.NewString(),
+				TransactionStatus: string(SettlementTransactionStatus),
+				PaymentType:       payment.PaymentMethod_Gopay,
+				GrossAmount:       test.grossAmount,
+				StatusCode:        "200",
+			}))
+
+			resp := w.Result()
+			if got := resp.StatusCode; got != test.wantCode {
+				t.Fatalf("want http %v, got : %v", test.wantCode, got)
+			}
+			if test.wantMessage == "" {
+				return
+			}
+			got := &httpjson.Response{}
+			if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Message != test.wantMessage {
+				t.Fatalf("HandleTransactionUpdate() message, got = %v, want = %v", got.Message, test.wantMessage)
+			}
+		})
+	}
+}
+
+func TestNewHandler(t *testing.T) {
+	t.Parallel()
+
+	const statusURL = "https://api.sandbox.midtrans.com/v2/%s/status"
+
+	tests := []struct {
+		name         string
+		chargeURL    string
+		getStatusURL string
+		wantErr      bool
+	}{
+		{name: "Valid", chargeURL: "https://api.sandbox.midtrans.com/v2/charge", getStatusURL: statusURL},
+		{name: "EmptyChargeURL", getStatusURL: statusURL, wantErr: true},
+		{name: "EmptyGetStatusURL", chargeURL: "http://localhost/charge", wantErr: true},
+		{name: "NoScheme", chargeURL: "api.sandbox.midtrans.com/v2/charge", getStatusURL: statusURL, wantErr: true},
+		{name: "NotHTTP", chargeURL: "http://localhost/charge", getStatusURL: "ftp://api.sandbox.midtrans.com/v2/%s/status", wantErr: true},
+		{name: "NoHost", chargeURL: "http:///charge", getStatusURL: statusURL, wantErr: true},
+		{name: "Malformed", chargeURL: "http://localhost/charge", getStatusURL: "https://api.sandbox.midtrans.com/v2/%zz/status", wantErr: true},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewHandler(testServerKey, test.chargeURL, test.getStatusURL, nil, nil)
+			if !test.wantErr {
+				if err != nil {
+					t.Fatalf("NewHandler(), got = %v, want = nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidURL) {
+				t.Fatalf("NewHandler(), got = %v, want = %v", err, ErrInvalidURL)
+			}
+		})
+	}
+}
+
+func TestWithContextTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{name: "Default", timeout: 0, want: defaultContextTimeout},
+		{name: "Negative", timeout: -time.Second, want: defaultContextTimeout},
+		{name: "Configured", timeout: 30 * time.Second, want: 30 * time.Second},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			h, err := NewHandler(testServerKey, "http://localhost", "http://localhost", nil, nil, WithContextTimeout(test.timeout))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if h.contextTimeout != test.want {
+				t.Fatalf("contextTimeout, got = %v, want = %v", h.contextTimeout, test.want)
+			}
+		})
+	}
+}
+
+func TestHandleTransactionUpdate_StatusRetry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		failures  int32
+		wantCalls int32
+		wantCode  int
+	}{
+		{
+			name:      "RecoveredAfterRetry",
+			failures:  2,
+			wantCalls: 3,
+			wantCode:  http.StatusOK,
+		},
+		{
+			name:      "Exhausted",
+			failures:  3,
+			wantCalls: 3,
+			wantCode:  http.StatusBadRequest,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var calls int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if atomic.AddInt32(&calls, 1) <= test.failures {
+					w.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(map[string]string{
+					"status_code":        "200",
+					"transaction_status": string(SettlementTransactionStatus),
+					"fraud_status":       FraudStatusAccept,
+					"gross_amount":       "100000.00",
+				})
+			}))
+			t.Cleanup(srv.Close)
+
+			ctrl := gomock.NewController(t)
+			orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+			taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+			if test.wantCode == http.StatusOK {
+				expectPaymentTask(taskClient)
+				expectOrder(orderClient, opb.OrderState_ORDER_STATE_WAITING_FOR_PAYMENT)
+				taskClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.UpdateOrderTaskResponse{}, nil)
+			}
+
+			h, err := NewHandler(testServerKey, "http://localhost", srv.URL+"/v2/%s/status", orderClient, taskClient,
+				WithStatusRetry(3, time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, UpdateTransactionRequest{
+				OrderID:           "payment-task-id",
+				TransactionID:     uuid.NewString(),
+				TransactionStatus: string(SettlementTransactionStatus),
+				PaymentType:       payment.PaymentMethod_Gopay,
+				GrossAmount:       "100000.00",
+				StatusCode:        "200",
+			}))
+
+			if got := w.Result().StatusCode; got != test.wantCode {
+				t.Fatalf("want http %v, got : %v", test.wantCode, got)
+			}
+			if got := atomic.LoadInt32(&calls); got != test.wantCalls {
+				t.Fatalf("get status calls, got = %v, want = %v", got, test.wantCalls)
+			}
+		})
+	}
+}
+
+func TestHandleTransactionUpdate_Dedup(t *testing.T) {
+	t.Parallel()
+
+	settlement := UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}
+	getStatusURL := newTransactionStatusServer(t, map[string]string{
+		"status_code":        "200",
+		"transaction_status": string(SettlementTransactionStatus),
+		"fraud_status":       FraudStatusAccept,
+		"gross_amount":       "100000.00",
+	})
+
+	ctrl := gomock.NewController(t)
+	orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+	taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+	// the first delivery fails to update the task and is processed again,
+	// the redelivery after the success is short-circuited.
+	expectPaymentTask(taskClient).Times(2)
+	expectOrder(orderClient, opb.OrderState_ORDER_STATE_WAITING_FOR_PAYMENT).Times(2)
+	gomock.InOrder(
+		taskClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).Return(nil, errors.New("task service unavailable")),
+		taskClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.UpdateOrderTaskResponse{}, nil),
+	)
+
+	store := dedup.NewMemoryStore()
+	h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient,
+		WithDedup(store, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, wantCode := range []int{http.StatusInternalServerError, http.StatusOK, http.StatusOK} {
+		w := httptest.NewRecorder()
+		http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, settlement))
+		if got := w.Result().StatusCode; got != wantCode {
+			t.Fatalf("delivery %d, want http %v, got : %v", i, wantCode, got)
+		}
+	}
+
+	e, ok, err := store.Get(context.Background(), DedupKey(settlement.TransactionID, settlement.TransactionStatus))
+	if err != nil || !ok {
+		t.Fatalf("dedup Get(), got = %v %v, want = true nil", ok, err)
+	}
+	if e.Outcome != decisionSuccess {
+		t.Fatalf("dedup outcome, got = %v, want = %v", e.Outcome, decisionSuccess)
+	}
+}
+
+func TestHandleTransactionUpdate_APIKey(t *testing.T) {
+	t.Parallel()
+
+	const apiKeyHeader = "X-Callback-Key"
+
+	pending := UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(PendingTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "201",
+	}
+
+	tests := []struct {
+		name     string
+		opts     []Option
+		apiKey   string
+		wantCode int
+	}{
+		{
+			name:     "Disabled",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "ValidKey",
+			opts:     []Option{WithAPIKey(apiKeyHeader, "callback-key")},
+			apiKey:   "callback-key",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "MissingKey",
+			opts:     []Option{WithAPIKey(apiKeyHeader, "callback-key")},
+			wantCode: http.StatusUnauthorized,
+		},
+		{
+			name:     "WrongKey",
+			opts:     []Option{WithAPIKey(apiKeyHeader, "callback-key")},
+			apiKey:   "guessed-key",
+			wantCode: http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			h, err := NewHandler(testServerKey, "http://localhost", "http://localhost", nil, nil, test.opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := newTransactionUpdateRequest(t, pending)
+			if test.apiKey != "" {
+				r.Header.Set(apiKeyHeader, test.apiKey)
+			}
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != test.wantCode {
+				t.Fatalf("want http %v, got : %v", test.wantCode, got)
+			}
+		})
+	}
+}
+
+func TestHandleTransactionUpdate_PaymentTypes(t *testing.T) {
+	t.Parallel()
+
+	getStatusURL := newTransactionStatusServer(t, map[string]string{
+		"status_code":        "200",
+		"transaction_status": string(SettlementTransactionStatus),
+		"fraud_status":       FraudStatusAccept,
+		"gross_amount":       "100000.00",
+	})
+
+	for _, paymentType := range []string{
+		payment.PaymentMethod_Gopay,
+		payment.PaymentMethod_VirtualAccount,
+		PaymentTypeQRIS,
+		PaymentTypeShopeePay,
+	} {
+		paymentType := paymentType
+		t.Run(paymentType, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+			taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+			expectPaymentTask(taskClient)
+			expectOrder(orderClient, opb.OrderState_ORDER_STATE_WAITING_FOR_PAYMENT)
+			taskClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.UpdateOrderTaskResponse{}, nil)
+
+			h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, orderClient, taskClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			w := httptest.NewRecorder()
+			http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, UpdateTransactionRequest{
+				OrderID:           "payment-task-id",
+				TransactionID:     uuid.NewString(),
+				TransactionStatus: string(SettlementTransactionStatus),
+				PaymentType:       paymentType,
+				GrossAmount:       "100000.00",
+				StatusCode:        "200",
+			}))
+
+			if got := w.Result().StatusCode; got != http.StatusOK {
+				t.Fatalf("want http %v, got : %v", http.StatusOK, got)
+			}
+		})
+	}
+
+	t.Run("Unsupported", func(t *testing.T) {
+		t.Parallel()
+
+		h, err := NewHandler(testServerKey, "http://localhost", getStatusURL, nil, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		w := httptest.NewRecorder()
+		http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, newTransactionUpdateRequest(t, UpdateTransactionRequest{
+			OrderID:           "payment-task-id",
+			TransactionID:     uuid.NewString(),
+			TransactionStatus: string(SettlementTransactionStatus),
+			PaymentType:       "credit_card",
+			GrossAmount:       "100000.00",
+			StatusCode:        "200",
+		}))
+
+		if got := w.Result().StatusCode; got != http.StatusInternalServerError {
+			t.Fatalf("want http %v, got : %v", http.StatusInternalServerError, got)
+		}
+	})
+}
+
+func TestHandleTransactionUpdate_SchemaValidation(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	// no call is expected, the notification is rejected before decoding.
+	orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+	taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+	h, err := NewHandler(testServerKey, "http://localhost", "http://localhost/%s/status", orderClient, taskClient,
+		WithSchemaValidation(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const in = `{"order_id": "payment-task-id", "status_code": "200", "gross_amount": 100000, "transaction_status": "settlement", "signature_key": "sig"}`
+	r, err := http.NewRequest(http.MethodPost, TransactionUpdatePath, bytes.NewBufferString(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("want http %v, got : %v", http.StatusBadRequest, resp.StatusCode)
+	}
+	got := &httpjson.Response{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	if want := "invalid request data: /gross_amount: expected string, got number"; got.Message != want {
+		t.Fatalf("want message %q, got : %q", want, got.Message)
+	}
+}
+
+func TestHandleTransactionUpdate_DisallowUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	// no call is expected, the notification is rejected while decoding.
+	orderClient := opbmock.NewMockOrderServiceClient(ctrl)
+	taskClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+	h, err := NewHandler(testServerKey, "http://localhost", "http://localhost/%s/status", orderClient, taskClient,
+		WithDisallowUnknownFields(true),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const in = `{"order_id": "payment-task-id", "status_code": "200", "gross_amount": "100000.00", "transaction_status": "settlement", "signature_key": "sig", "channel_response_code": "00"}`
+	r, err := http.NewRequest(http.MethodPost, TransactionUpdatePath, bytes.NewBufferString(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	http.HandlerFunc(h.HandleTransactionUpdate).ServeHTTP(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("want http %v, got : %v", http.StatusBadRequest, resp.StatusCode)
+	}
+	got := &httpjson.Response{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	if want := `invalid request data: unknown field "channel_response_code"`; got.Message != want {
+		t.Fatalf("want message %q, got : %q", want, got.Message)
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	t.Parallel()
+
+	notification := UpdateTransactionRequest{
+		OrderID:           "payment-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       "100000.00",
+		StatusCode:        "200",
+	}
+
+	tests := []struct {
+		name    string
+		body    func() []byte
+		apiKey  string
+		wantErr error
+	}{
+		{
+			name: "Signed",
+			body: func() []byte {
+				return readBody(t, newTransactionUpdateRequest(t, notification))
+			},
+		},
+		{
+			name: "InvalidSignature",
+			body: func() []byte {
+				req := notification
+				req.SignatureKey = "invalid"
+				b, _ := json.Marshal(req)
+				return b
+			},
+			wantErr: ErrInvalidSignature,
+		},
+		{
+			name:    "NotJSON",
+			body:    func() []byte { return []byte("not json") },
+			wantErr: ErrInvalidRequestData,
+		},
+		{
+			name: "MissingAPIKey",
+			body: func() []byte {
+				return readBody(t, newTransactionUpdateRequest(t, notification))
+			},
+			apiKey:  "api-key",
+			wantErr: ErrAPIKeyIsRequired,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var opts []Option
+			if test.apiKey != "" {
+				opts = append(opts, WithAPIKey("X-Api-Key", test.apiKey))
+			}
+			h, err := NewHandler(testServerKey, "http://localhost", "http://localhost/v2/%s/status", nil, nil, opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodPost, TransactionUpdatePath, nil)
+			if err := h.Verifier().Verify(r, test.body()); !errors.Is(err, test.wantErr) {
+				t.Fatalf("Verify(), got = %v, want = %v", err, test.wantErr)
+			}
+		})
+	}
+}
+
+// readBody returns the body of r.
+func readBody(t *testing.T, r *http.Request) []byte {
+	t.Helper()
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
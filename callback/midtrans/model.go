@@ -1,5 +1,73 @@
 package midtrans
 
+import (
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// maxTransactionIDLen is the maximum length of a transaction id, midtrans
+// uses 36 characters UUIDs.
+const maxTransactionIDLen = 64
+
+// transactionIDPattern is the charset allowed in a transaction id.
+var transactionIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// notificationStatusCodes are the status codes documented by midtrans for
+// the transaction results.
+var notificationStatusCodes = map[string]bool{
+	"200": true, "201": true, "202": true,
+	"300": true,
+	"400": true, "401": true, "402": true, "403": true, "404": true, "405": true,
+	"406": true, "407": true, "408": true, "409": true, "410": true, "411": true,
+	"412": true, "413": true,
+	"500": true, "501": true, "502": true, "503": true, "504": true, "505": true,
+}
+
+// TransactionStatus is the status of a midtrans transaction.
+type TransactionStatus string
+
+const (
+	PendingTransactionStatus           TransactionStatus = "pending"
+	AuthorizedTransactionStatus        TransactionStatus = "authorized"
+	CaptureTransactionStatus           TransactionStatus = "capture"
+	SettlementTransactionStatus        TransactionStatus = "settlement"
+	DenyTransactionStatus              TransactionStatus = "deny"
+	CancelTransactionStatus            TransactionStatus = "cancel"
+	RefundTransactionStatus            TransactionStatus = "refund"
+	PartialRefundTransactionStatus     TransactionStatus = "partial_refund"
+	ChargebackTransactionStatus        TransactionStatus = "chargeback"
+	PartialChargebackTransactionStatus TransactionStatus = "partial_chargeback"
+	ExpireTransactionStatus            TransactionStatus = "expire"
+	FailureTransactionStatus           TransactionStatus = "failure"
+)
+
+// ParseTransactionStatus parses a midtrans transaction status, ignoring the
+// case and surrounding spaces. ok is false for an unknown status, the
+// returned status is then the normalized s.
+func ParseTransactionStatus(s string) (status TransactionStatus, ok bool) {
+	status = TransactionStatus(strings.ToLower(strings.TrimSpace(s)))
+	switch status {
+	case PendingTransactionStatus, AuthorizedTransactionStatus,
+		CaptureTransactionStatus, SettlementTransactionStatus,
+		DenyTransactionStatus, CancelTransactionStatus,
+		RefundTransactionStatus, PartialRefundTransactionStatus,
+		ChargebackTransactionStatus, PartialChargebackTransactionStatus,
+		ExpireTransactionStatus, FailureTransactionStatus:
+		return status, true
+	}
+	return status, false
+}
+
+// transactionTimeLayout is the layout of the midtrans timestamps, they are
+// in GMT+7, e.g. "2022-06-21 14:05:00".
+const transactionTimeLayout = "2006-01-02 15:04:05"
+
+// transactionTimeZone is the time zone of the midtrans timestamps.
+var transactionTimeZone = time.FixedZone("GMT+7", 7*60*60)
+
 // UpdateTransactionRequest holds all data used for charge response and payment notification request
 // from midtrans.
 type UpdateTransactionRequest struct {
@@ -35,3 +103,69 @@ type UpdateTransactionRequest struct {
 	// Currency is currency used in the transaction.
 	Currency string `json:"currency"`
 }
+
+// Validate checks the UpdateTransactionRequest fields used for correlation,
+// the transaction id ends up in logs and downstream calls, and that the
+// status code is one of notificationStatusCodes.
+func (u *UpdateTransactionRequest) Validate() error {
+	switch id := u.TransactionID; {
+	case id == "":
+		return ErrTransactionIDIsRequired
+	case len(id) > maxTransactionIDLen, !transactionIDPattern.MatchString(id):
+		return ErrInvalidTransactionID
+	case len(id) == 36 && strings.Count(id, "-") == 4:
+		// looks like a UUID, it must be a valid one.
+		if _, err := uuid.Parse(id); err != nil {
+			return ErrInvalidTransactionID
+		}
+	}
+	if !notificationStatusCodes[u.StatusCode] {
+		return ErrInvalidStatusCode
+	}
+	return nil
+}
+
+// Timestamps returns the transaction and settlement times as RFC3339 strings
+// keyed by transaction_time and settlement_time, the empty or unparseable
+// ones are skipped. It returns nil when there is none.
+func (u *UpdateTransactionRequest) Timestamps() map[string]string {
+	var data map[string]string
+	for key, v := range map[string]string{
+		"transaction_time": u.TransactionTime,
+		"settlement_time":  u.SettlementTime,
+	} {
+		t, err := time.ParseInLocation(transactionTimeLayout, strings.TrimSpace(v), transactionTimeZone)
+		if err != nil {
+			continue
+		}
+		if data == nil {
+			data = make(map[string]string)
+		}
+		data[key] = t.Format(time.RFC3339)
+	}
+	return data
+}
+
+// AcceptedWithErrorStatus is the status of AcceptedWithErrorResponse.
+const AcceptedWithErrorStatus = "accepted_with_error"
+
+// AcceptedWithErrorResponse is the response body of notifications acknowledged
+// with http 200 to stop midtrans retries while they were not processed.
+type AcceptedWithErrorResponse struct {
+	// Status is always accepted_with_error.
+	Status string `json:"status"`
+	// Code is the internal code of the error, e.g. order_not_found.
+	Code string `json:"code"`
+}
+
+// Response is the enriched response body returned to midtrans when
+// WithEnrichedResponse is enabled.
+type Response struct {
+	// OrderID is our internal order id of the transaction.
+	OrderID string `json:"order_id"`
+	// TaskState is the resulting state of the payment order task.
+	TaskState string `json:"task_state"`
+	// Decision is what we did with the notification, possible values are
+	// success, failed, already_processed, ignored and refunded.
+	Decision string `json:"decision"`
+}
@@ -0,0 +1,29 @@
+package midtrans
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/dropezy/storefront-backend/http/internal/jsonschema"
+)
+
+// transactionUpdateSchema checks the structure of the payment
+// notifications.
+var (
+	//go:embed schema/transaction_update.json
+	transactionUpdateSchemaJSON []byte
+
+	transactionUpdateSchema = jsonschema.MustParse(transactionUpdateSchemaJSON)
+)
+
+// requestDataError returns ErrInvalidRequestData for a request body that
+// failed to decode, wrapping the schema validation and unknown field errors
+// so the response points at the offending field.
+func requestDataError(err error) error {
+	var verr *jsonschema.ValidationError
+	if errors.As(err, &verr) || errors.Is(err, jsonschema.ErrUnknownField) {
+		return fmt.Errorf("%w: %v", ErrInvalidRequestData, err)
+	}
+	return ErrInvalidRequestData
+}
@@ -0,0 +1,82 @@
+package midtrans
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha512"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/dropezy/storefront-backend/internal/integrations/payment"
+)
+
+// selfTestGrossAmount is the amount of the self-test transaction, it matches
+// the total of the order returned by selftest.OrderClient.
+const selfTestGrossAmount = "1.00"
+
+// SelfTestRequest returns a canned valid settlement notification signed with
+// the handler server key, it is used to dry-run the handler with the clients
+// of the selftest package and SelfTestHTTPClient.
+func (h *Handler) SelfTestRequest(ctx context.Context) (*http.Request, error) {
+	req := &UpdateTransactionRequest{
+		OrderID:           "selftest-task-id",
+		TransactionID:     uuid.NewString(),
+		TransactionStatus: string(SettlementTransactionStatus),
+		PaymentType:       payment.PaymentMethod_Gopay,
+		GrossAmount:       selfTestGrossAmount,
+		StatusCode:        "200",
+	}
+	// signature_key is sha512(order_id+status_code+gross_amount+server_key).
+	sum := sha512.Sum512([]byte(req.OrderID + req.StatusCode + req.GrossAmount + h.serverKey))
+	signature := hex.EncodeToString(sum[:])
+	req.SignatureKey = signature
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, TransactionUpdatePath, bytes.NewBuffer(body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	if h.signatureHeader != "" {
+		r.Header.Set(h.signatureHeader, signature)
+	}
+	if h.apiKeyHeader != "" {
+		r.Header.Set(h.apiKeyHeader, h.apiKey)
+	}
+	return r, nil
+}
+
+// SelfTestHTTPClient returns the client of the dry-run handler for
+// WithHTTPClient, the get status calls are answered with the settlement of
+// SelfTestRequest without calling midtrans.
+func SelfTestHTTPClient() *http.Client {
+	return &http.Client{Transport: selfTestTransport{}}
+}
+
+// selfTestTransport answers every request with a settled transaction.
+type selfTestTransport struct{}
+
+func (selfTestTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	body, err := json.Marshal(&payment.TransactionStatus{
+		StatusCode:        "200",
+		TransactionStatus: string(SettlementTransactionStatus),
+		FraudStatus:       FraudStatusAccept,
+		GrossAmount:       selfTestGrossAmount,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(bytes.NewReader(body)),
+		Request:    r,
+	}, nil
+}
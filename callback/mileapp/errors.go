@@ -1,6 +1,11 @@
 package mileapp
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dropezy/storefront-backend/http/internal/httpjson"
+)
 
 var (
 	ErrTaskRefIDIsRequired         = errors.New("taskRefId is required")
@@ -13,4 +18,33 @@ var (
 	ErrInvalidXAPIKey              = errors.New("invalid x-api-key")
 	ErrMarshallingUnsuccessful     = errors.New("marshalling unsuccessful")
 	ErrWriteToResponseUnsuccessful = errors.New("write to response unsuccessful")
+	ErrEmptyOrderTaskResponse      = errors.New("empty order task response")
+	ErrNoMatchingTask              = errors.New("no matching task for order")
+	ErrUnsupportedTaskType         = errors.New("unsupported task type")
+	ErrMethodNotAllowed            = errors.New("expecting http method post")
+	ErrInvalidRequestData          = errors.New("invalid request data")
+	ErrTaskServiceTimeout          = errors.New("task service timeout")
+	ErrOrderNotFound               = errors.New("order not found")
+	ErrEmptyPayload                = errors.New("empty payload")
 )
+
+// statusCodes are the http status of the errors written with writeError, any
+// other error is http 500.
+var statusCodes = httpjson.StatusCodes{
+	ErrUnsupportedTaskType:    http.StatusBadRequest,
+	ErrMethodNotAllowed:       http.StatusMethodNotAllowed,
+	ErrContenTypeIsRequired:   http.StatusBadRequest,
+	ErrInvalidContentType:     http.StatusBadRequest,
+	ErrXAPIKeyIsRequired:      http.StatusUnauthorized,
+	ErrInvalidXAPIKey:         http.StatusUnauthorized,
+	ErrInvalidRequestData:     http.StatusBadRequest,
+	ErrEmptyPayload:           http.StatusBadRequest,
+	ErrTaskRefIDIsRequired:    http.StatusBadRequest,
+	ErrStatusIsRequired:       http.StatusBadRequest,
+	ErrOrderNumberIsRequired:  http.StatusBadRequest,
+	ErrInvalidStatus:          http.StatusBadRequest,
+	ErrEmptyOrderTaskResponse: http.StatusInternalServerError,
+	ErrNoMatchingTask:         http.StatusNotFound,
+	ErrOrderNotFound:          http.StatusNotFound,
+	ErrTaskServiceTimeout:     http.StatusGatewayTimeout,
+}
@@ -1,78 +1,213 @@
 package mileapp
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/dropezy/internal/logging"
 	tpb "github.com/dropezy/proto/v1/task"
+	"github.com/dropezy/storefront-backend/http/internal/httpjson"
+	"github.com/dropezy/storefront-backend/http/internal/jsonschema"
+	"github.com/dropezy/storefront-backend/http/internal/tracing"
+	"github.com/dropezy/storefront-backend/http/internal/validate"
+	"github.com/dropezy/storefront-backend/http/middleware"
 )
 
-const handlerName = "mileapp"
+// HandlerName is the name of the handler used in logs, metrics and alerts.
+const HandlerName = "mileapp"
+
+// defaultCallTimeout is the default time allowed for each call to the task
+// service.
+const defaultCallTimeout = 10 * time.Second
 
 type MileappHandlers struct {
 	grpcClient tpb.TaskServiceClient
 	authKey    string
+	// authKeys are the keys accepted on top of authKey while rotating it.
+	authKeys []string
+
+	// statusStates overrides the order task state each task status is
+	// mapped to, see WithStatusStates.
+	statusStates map[string]tpb.OrderTaskState
+	// taskTypes maps the task-type path variable to the order task type.
+	taskTypes map[string]tpb.OrderTaskType
+	// callTimeout bounds each call to the task service.
+	callTimeout time.Duration
+	// schemaValidation checks the request bodies against the embedded json
+	// schema before decoding them.
+	schemaValidation bool
+	// disallowUnknownFields rejects the request body fields missing from the
+	// request structs.
+	disallowUnknownFields bool
+	// tracer starts the span of each status update.
+	tracer trace.Tracer
+}
+
+// Option configures optional behaviour of MileappHandlers.
+type Option func(*MileappHandlers)
+
+// WithStatusStates maps the mileapp task statuses to the given order task
+// states instead of the default ones from ToPB, e.g. so ongoing and done can
+// target distinct states. Statuses missing from states keep the default.
+func WithStatusStates(states map[string]tpb.OrderTaskState) Option {
+	return func(m *MileappHandlers) {
+		m.statusStates = states
+	}
+}
+
+// WithTaskTypes maps the given task-type path variables to order task types
+// on top of the default picking, packing, shipping and delivery ones, so a
+// new mileapp task type can be enabled without a code change.
+func WithTaskTypes(types map[string]tpb.OrderTaskType) Option {
+	return func(m *MileappHandlers) {
+		for task, taskType := range types {
+			m.taskTypes[task] = taskType
+		}
+	}
 }
 
-func NewMileappHandlers(authKey string, client tpb.TaskServiceClient) *MileappHandlers {
-	return &MileappHandlers{
+// WithCallTimeout sets the time allowed for each call to the task service.
+// The default of 10s is kept when timeout is not positive.
+func WithCallTimeout(timeout time.Duration) Option {
+	return func(m *MileappHandlers) {
+		if timeout > 0 {
+			m.callTimeout = timeout
+		}
+	}
+}
+
+// WithAuthKeys accepts keys on top of the auth key, so a new key can be
+// rolled out to mileapp before the old one is removed.
+func WithAuthKeys(keys ...string) Option {
+	return func(m *MileappHandlers) {
+		m.authKeys = keys
+	}
+}
+
+// WithSchemaValidation checks the status update bodies against their json
+// schema before decoding them, so a field with the wrong type is rejected
+// with its path.
+func WithSchemaValidation(enabled bool) Option {
+	return func(m *MileappHandlers) {
+		m.schemaValidation = enabled
+	}
+}
+
+// WithDisallowUnknownFields rejects the status updates holding a field
+// unknown to the handler, e.g. taskRefId renamed to task_ref_id, with the
+// name of the field instead of ignoring it.
+func WithDisallowUnknownFields(enabled bool) Option {
+	return func(m *MileappHandlers) {
+		m.disallowUnknownFields = enabled
+	}
+}
+
+// WithTracerProvider starts the span of each status update with a tracer of
+// tp, the spans are no-ops by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(m *MileappHandlers) {
+		m.tracer = tracing.Tracer(tp)
+	}
+}
+
+func NewMileappHandlers(authKey string, client tpb.TaskServiceClient, opts ...Option) *MileappHandlers {
+	m := &MileappHandlers{
 		grpcClient: client,
 		authKey:    authKey,
+		taskTypes:  make(map[string]tpb.OrderTaskType, len(defaultTaskTypes)),
+
+		callTimeout: defaultCallTimeout,
+		tracer:      tracing.Tracer(nil),
+	}
+	for task, taskType := range defaultTaskTypes {
+		m.taskTypes[task] = taskType
 	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
 }
 
-// HandlerStatusUpdate handle callback from MileApp to update the delivery status, method is POST
-func (m *MileappHandlers) HandleStatusUpdate(w http.ResponseWriter, r *http.Request) {
-	logger := logging.FromContext(r.Context()).With().Str("handler", handlerName).Logger()
-
-	logger.Info().Msgf("received status update from: %s", r.RemoteAddr)
-
-	var taskType tpb.OrderTaskType
-	switch task := mux.Vars(r)["task-type"]; task {
-	case taskTypePicking:
-		taskType = tpb.OrderTaskType_ORDER_TASK_TYPE_PICKING
-	case taskTypePacking:
-		taskType = tpb.OrderTaskType_ORDER_TASK_TYPE_PACKING
-	case taskTypeShipping:
-		taskType = tpb.OrderTaskType_ORDER_TASK_TYPE_SHIPPING
-	case taskTypeDelivery:
-		taskType = tpb.OrderTaskType_ORDER_TASK_TYPE_DELIVERY
-	default:
-		err := fmt.Errorf("unsupported task type: %s", task)
-		logger.Err(err).Send()
-		m.responseJSON(logger, w, http.StatusBadRequest, err.Error())
-		return
+// ParseStatusStates parses a comma separated list of status=state pairs,
+// e.g. "ongoing=ORDER_TASK_STATE_SUCCESS,done=ORDER_TASK_STATE_SUCCESS".
+// An empty string returns no overrides.
+func ParseStatusStates(s string) (map[string]tpb.OrderTaskState, error) {
+	states := make(map[string]tpb.OrderTaskState)
+	if strings.TrimSpace(s) == "" {
+		return states, nil
 	}
 
-	logger = logger.With().Str("taskType", taskType.String()).Logger()
+	for _, pair := range strings.Split(s, ",") {
+		status, state, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid status state pair: %q", pair)
+		}
 
-	if r.Method != http.MethodPost {
-		err := fmt.Errorf("expecting http method post, got: %s", r.Method)
-		logger.Err(err).Send()
-		m.responseJSON(logger, w, http.StatusBadRequest, err.Error())
-		return
+		v, ok := tpb.OrderTaskState_value[strings.TrimSpace(state)]
+		if !ok {
+			return nil, fmt.Errorf("invalid order task state: %q", state)
+		}
+		states[normalizeStatus(status)] = tpb.OrderTaskState(v)
 	}
-	if err := m.validateHeaders(logger, r.Header); err != nil {
-		m.responseJSON(logger, w, http.StatusBadRequest, err.Error())
+	return states, nil
+}
+
+// ParseTaskTypes parses a comma separated list of task-type=type pairs,
+// e.g. "returning=ORDER_TASK_TYPE_DELIVERY". An empty string returns no
+// task types.
+func ParseTaskTypes(s string) (map[string]tpb.OrderTaskType, error) {
+	types := make(map[string]tpb.OrderTaskType)
+	if strings.TrimSpace(s) == "" {
+		return types, nil
+	}
+
+	for _, pair := range strings.Split(s, ",") {
+		task, taskType, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid task type pair: %q", pair)
+		}
+
+		v, ok := tpb.OrderTaskType_value[strings.TrimSpace(taskType)]
+		if !ok {
+			return nil, fmt.Errorf("invalid order task type: %q", taskType)
+		}
+		types[strings.TrimSpace(task)] = tpb.OrderTaskType(v)
+	}
+	return types, nil
+}
+
+// HandlerStatusUpdate handle callback from MileApp to update the delivery status, method is POST
+func (m *MileappHandlers) HandleStatusUpdate(w http.ResponseWriter, r *http.Request) {
+	w, r, span := tracing.Start(m.tracer, w, r, HandlerName, "mileapp.HandleStatusUpdate")
+	defer span.End()
+
+	logger, taskType, ok := m.startStatusUpdate(w, r, "status update")
+	if !ok {
 		return
 	}
 
 	req := &HandleStatusUpdateRequest{}
-	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+	if err := m.decoder(statusUpdateSchema).Decode(r.Body, req); err != nil {
 		logger.Err(err).Msg("failed to decode request data")
-		m.responseJSON(logger, w, http.StatusBadRequest, "invalid request data")
+		m.writeError(logger, w, requestDataError(err))
 		return
 	}
 
 	// check if the request contains all required fields
 	if err := req.Validate(logger); err != nil {
-		logger.Err(err).Send()
-		m.responseJSON(logger, w, http.StatusBadRequest, err.Error())
+		logger.Err(err).Msg("invalid request data")
+		m.writeError(logger, w, err)
 		return
 	}
 
@@ -81,23 +216,172 @@ func (m *MileappHandlers) HandleStatusUpdate(w http.ResponseWriter, r *http.Requ
 		"taskStatus":  req.TaskStatus,
 		"orderNumber": req.UserVar.OrderNumber,
 	}).Logger()
+	span.SetAttributes(tracing.OrderIDKey.String(req.UserVar.OrderNumber))
+
+	taskID, err := m.updateStatus(r.Context(), logger, taskType, req)
+	if taskID != "" {
+		span.SetAttributes(tracing.TaskIDKey.String(taskID))
+	}
+	if err != nil {
+		m.writeUpdateError(logger, w, err)
+		return
+	}
+	m.responseJSON(logger, w, http.StatusOK, "success")
+}
+
+// HandleBatchStatusUpdate handles the callback from MileApp holding an array
+// of status updates, method is POST. The updates are all validated before
+// any of them is applied, then applied in order. The response holds the
+// result of each update, it is an error only when all of them failed.
+func (m *MileappHandlers) HandleBatchStatusUpdate(w http.ResponseWriter, r *http.Request) {
+	w, r, span := tracing.Start(m.tracer, w, r, HandlerName, "mileapp.HandleBatchStatusUpdate")
+	defer span.End()
+
+	logger, taskType, ok := m.startStatusUpdate(w, r, "batch status update")
+	if !ok {
+		return
+	}
+
+	var reqs []*HandleStatusUpdateRequest
+	if err := m.decoder(statusUpdateBatchSchema).Decode(r.Body, &reqs); err != nil {
+		logger.Err(err).Msg("failed to decode request data")
+		m.writeError(logger, w, requestDataError(err))
+		return
+	}
+	span.SetAttributes(tracing.ItemsKey.Int(len(reqs)))
+
+	if len(reqs) == 0 {
+		logger.Err(ErrEmptyPayload).Msg("empty payload")
+		m.writeError(logger, w, ErrEmptyPayload)
+		return
+	}
+
+	// check if every update contains all required fields, so a rejected
+	// batch can be fixed and sent again as a whole.
+	for i, req := range reqs {
+		if req == nil {
+			req = &HandleStatusUpdateRequest{}
+			reqs[i] = req
+		}
+		if err := req.Validate(logger); err != nil {
+			err = fmt.Errorf("item %d: %w", i, err)
+			logger.Err(err).Msg("invalid request item")
+			m.writeError(logger, w, err)
+			return
+		}
+	}
+
+	results := make([]*StatusUpdateResult, len(reqs))
+	statuses := make([]int, len(reqs))
+	failed := 0
+	for i, req := range reqs {
+		logger := logger.With().Fields(map[string]interface{}{
+			"taskRefId":   req.TaskRefID,
+			"taskStatus":  req.TaskStatus,
+			"orderNumber": req.UserVar.OrderNumber,
+		}).Logger()
+
+		results[i] = &StatusUpdateResult{
+			TaskRefID:   req.TaskRefID,
+			OrderNumber: req.UserVar.OrderNumber,
+			Status:      StatusUpdateStatusSuccess,
+		}
+		if _, err := m.updateStatus(r.Context(), logger, taskType, req); err != nil {
+			failed++
+			statuses[i], results[i].Error = updateErrorStatus(err)
+			results[i].Status = StatusUpdateStatusError
+		}
+	}
+
+	if failed == len(results) {
+		code := httpjson.FailureCode(statuses)
+		logger.Error().Int("failed", failed).Int("code", code).Msg("failed to update all order tasks")
+		m.responseResultsJSON(logger, w, code, results)
+		return
+	}
+
+	logger.Info().Int("failed", failed).Msg("successfully processing batch update task status")
+	m.responseResultsJSON(logger, w, http.StatusOK, results)
+}
+
+// startStatusUpdate checks the task type of the path, the method and the
+// headers of a status update of kind. It returns the handler logger with the
+// task type and false once the error is written.
+func (m *MileappHandlers) startStatusUpdate(w http.ResponseWriter, r *http.Request, kind string) (zerolog.Logger, tpb.OrderTaskType, bool) {
+	logger := logging.FromContext(r.Context()).With().Str("handler", HandlerName).Logger()
+
+	logger.Info().Msgf("received %s from: %s", kind, r.RemoteAddr)
+
+	task := mux.Vars(r)["task-type"]
+	taskType, ok := m.taskTypes[task]
+	if !ok {
+		err := fmt.Errorf("%w: %s", ErrUnsupportedTaskType, task)
+		logger.Err(err).Msg("unsupported task type")
+		m.writeError(logger, w, err)
+		return logger, taskType, false
+	}
 
-	tasks, err := m.grpcClient.GetOrderTask(r.Context(), &tpb.GetOrderTaskRequest{
+	logger = logger.With().Str("taskType", taskType.String()).Logger()
+
+	if r.Method != http.MethodPost {
+		err := fmt.Errorf("%w, got: %s", ErrMethodNotAllowed, r.Method)
+		logger.Err(err).Msg("invalid request method")
+		m.writeError(logger, w, err)
+		return logger, taskType, false
+	}
+	if err := m.validateHeaders(logger, r.Header); err != nil {
+		m.writeError(logger, w, err)
+		return logger, taskType, false
+	}
+	return logger, taskType, true
+}
+
+// decoder returns the decoder of the request bodies, checking them against
+// schema when WithSchemaValidation is set.
+func (m *MileappHandlers) decoder(schema *jsonschema.Schema) jsonschema.Decoder {
+	dec := jsonschema.Decoder{DisallowUnknownFields: m.disallowUnknownFields}
+	if m.schemaValidation {
+		dec.Schema = schema
+	}
+	return dec
+}
+
+// updateStatus applies the validated status update req to the order task of
+// taskType. It returns the ID of the order task once it is found, and an
+// error for writeUpdateError when the update failed.
+func (m *MileappHandlers) updateStatus(ctx context.Context, logger zerolog.Logger, taskType tpb.OrderTaskType, req *HandleStatusUpdateRequest) (string, error) {
+	getCtx, cancel := context.WithTimeout(ctx, m.callTimeout)
+	defer cancel()
+	tasks, err := m.grpcClient.GetOrderTask(getCtx, &tpb.GetOrderTaskRequest{
 		OrderId: req.UserVar.OrderNumber,
 	})
 	if err != nil {
 		logger.Err(err).Msg("failed to get order task")
-		m.responseJSON(logger, w, http.StatusInternalServerError, "failed to update order task")
-		return
+		if isDeadlineExceeded(err) {
+			return "", ErrTaskServiceTimeout
+		}
+		// the order number is unknown to the task service.
+		if status.Code(err) == codes.NotFound {
+			return "", ErrOrderNotFound
+		}
+		return "", &taskServiceError{err: err}
+	}
+	if tasks == nil {
+		logger.Err(ErrEmptyOrderTaskResponse).Msg("failed to get order task")
+		return "", ErrEmptyOrderTaskResponse
 	}
 
-	orderTask := &tpb.OrderTask{}
+	var orderTask *tpb.OrderTask
 	for _, t := range tasks.Tasks {
 		if t.TaskType == taskType {
 			orderTask = t
 			break
 		}
 	}
+	if orderTask == nil {
+		logger.Err(ErrNoMatchingTask).Msg("no matching task")
+		return "", ErrNoMatchingTask
+	}
 
 	logger = logger.With().Fields(map[string]interface{}{
 		"taskID": orderTask.TaskId,
@@ -107,12 +391,14 @@ func (m *MileappHandlers) HandleStatusUpdate(w http.ResponseWriter, r *http.Requ
 	// ignore if we already updated the task state to done.
 	if orderTask.State == tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS {
 		logger.Info().Msg("order task is already marked successfull, ignoring")
-		m.responseJSON(logger, w, http.StatusOK, "success")
-		return
+		return orderTask.TaskId, nil
 	}
 
 	updateReq := req.ToPB()
 	updateReq.TaskId = orderTask.TaskId
+	if state, ok := m.statusStates[req.TaskStatus]; ok {
+		updateReq.State = state
+	}
 
 	// send driver info so we can add it to order data
 	// when taskType is shipping and its status is ongoing
@@ -123,6 +409,11 @@ func (m *MileappHandlers) HandleStatusUpdate(w http.ResponseWriter, r *http.Requ
 			"driver_name":  req.AssignedTo.FullName,
 			"driver_phone": req.UserVar.DriverPhone,
 		}
+		// the driver location is optional, only sent when both are known.
+		if lat, long := req.UserVar.DriverLatitude, req.UserVar.DriverLongitude; lat != nil && long != nil {
+			updateReq.AdditionalData["driver_latitude"] = strconv.FormatFloat(*lat, 'f', -1, 64)
+			updateReq.AdditionalData["driver_longitude"] = strconv.FormatFloat(*long, 'f', -1, 64)
+		}
 	}
 
 	// send recipient info so we can add it to order data
@@ -137,57 +428,103 @@ func (m *MileappHandlers) HandleStatusUpdate(w http.ResponseWriter, r *http.Requ
 	}
 
 	// using grpc to store the status update to the database, the grpc response is currently empty
-	if _, err := m.grpcClient.UpdateOrderTask(r.Context(), updateReq); err != nil {
+	updateCtx, cancel := context.WithTimeout(ctx, m.callTimeout)
+	defer cancel()
+	if _, err := m.grpcClient.UpdateOrderTask(updateCtx, updateReq); err != nil {
 		logger.Err(err).Msg("failed to update order task")
-		m.responseJSON(logger, w, http.StatusInternalServerError, "failed to update order task")
-		return
+		if isDeadlineExceeded(err) {
+			return orderTask.TaskId, ErrTaskServiceTimeout
+		}
+		return orderTask.TaskId, &taskServiceError{err: err}
 	}
 
 	logger.Info().Msg("successfully processing update task status")
-	m.responseJSON(logger, w, http.StatusOK, "success")
+	return orderTask.TaskId, nil
 }
 
-// responseJSON is used for responsding to the http caller
-func (m *MileappHandlers) responseJSON(logger zerolog.Logger, w http.ResponseWriter, statusCode int, message string) {
-	w.Header().Set("Content-Type", "application/json")
-
-	res, err := json.Marshal(&HandleStatusUpdateResponse{Message: message})
-	if err != nil {
-		logger.Err(ErrMarshallingUnsuccessful).Msg(ErrMarshallingUnsuccessful.Error())
-	}
+// taskServiceError is a failed task service call, its http status is
+// derived from the gRPC status of err.
+type taskServiceError struct {
+	err error
+}
 
-	w.WriteHeader(statusCode)
+func (e *taskServiceError) Error() string { return "failed to update order task" }
+func (e *taskServiceError) Unwrap() error { return e.err }
 
-	if _, err = w.Write(res); err != nil {
-		logger.Err(ErrWriteToResponseUnsuccessful).Msg(ErrWriteToResponseUnsuccessful.Error())
+// updateErrorStatus returns the http status and message of an updateStatus
+// error.
+func updateErrorStatus(err error) (int, string) {
+	var serr *taskServiceError
+	if errors.As(err, &serr) {
+		return httpjson.GRPCToHTTP(serr.err), serr.Error()
 	}
+	return statusCodes.Code(err), err.Error()
 }
 
-// validateHeaders to check if Content-Type and X-Api-Key is given and not empty.
-func (m *MileappHandlers) validateHeaders(logger zerolog.Logger, h http.Header) error {
+// writeUpdateError writes an updateStatus error with its http status.
+func (m *MileappHandlers) writeUpdateError(logger zerolog.Logger, w http.ResponseWriter, err error) {
+	code, message := updateErrorStatus(err)
+	m.responseJSON(logger, w, code, message)
+}
 
-	ct := h.Get("content-type")
-	if ct == "" {
-		logger.Err(ErrContenTypeIsRequired).Msg(ErrContenTypeIsRequired.Error())
-		return ErrContenTypeIsRequired
+// responseResultsJSON writes the per update results of a batch status update.
+func (m *MileappHandlers) responseResultsJSON(logger zerolog.Logger, w http.ResponseWriter, code int, results []*StatusUpdateResult) {
+	if err := httpjson.Write(w, code, results); err != nil {
+		logger.Err(err).Msg(ErrWriteToResponseUnsuccessful.Error())
 	}
+}
 
-	if ct != "application/json" {
-		logger.Err(ErrInvalidContentType).Msg(ErrInvalidContentType.Error())
-		return ErrInvalidContentType
+// responseJSON is used for responsding to the http caller
+func (m *MileappHandlers) responseJSON(logger zerolog.Logger, w http.ResponseWriter, statusCode int, message string) {
+	if err := httpjson.WriteError(w, statusCode, message); err != nil {
+		logger.Err(err).Msg(ErrWriteToResponseUnsuccessful.Error())
 	}
+}
 
-	apiKey := h.Get("x-api-key")
-	if apiKey == "" {
-		logger.Err(ErrXAPIKeyIsRequired).Msg(ErrXAPIKeyIsRequired.Error())
-		return ErrXAPIKeyIsRequired
+// writeError writes err with its http status from statusCodes.
+func (m *MileappHandlers) writeError(logger zerolog.Logger, w http.ResponseWriter, err error) {
+	if err := httpjson.WriteErr(w, statusCodes, err); err != nil {
+		logger.Err(err).Msg(ErrWriteToResponseUnsuccessful.Error())
 	}
+}
 
-	if apiKey != m.authKey {
-		logger.Err(ErrInvalidXAPIKey).Msg(ErrInvalidXAPIKey.Error())
-		return ErrInvalidXAPIKey
-	}
+// isDeadlineExceeded reports whether err is a task service call that ran out
+// of time, either locally or as a gRPC deadline exceeded status.
+func isDeadlineExceeded(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded
+}
 
+// headerErrors keeps the error messages of the package for the shared
+// header checks.
+var headerErrors = map[error]error{
+	validate.ErrContentTypeIsRequired: ErrContenTypeIsRequired,
+	validate.ErrInvalidContentType:    ErrInvalidContentType,
+	validate.ErrAPIKeyIsRequired:      ErrXAPIKeyIsRequired,
+	validate.ErrInvalidAPIKey:         ErrInvalidXAPIKey,
+}
+
+// Verifier returns the middleware.Verifier of the mileapp routes, checking
+// the X-Api-Key header holds one of the auth keys before the payload is
+// stored.
+func (m *MileappHandlers) Verifier() middleware.Verifier {
+	return middleware.VerifierFunc(func(r *http.Request, _ []byte) error {
+		err := validate.CheckAPIKey(r.Header, "x-api-key", append([]string{m.authKey}, m.authKeys...)...)
+		if e, ok := headerErrors[err]; ok {
+			err = e
+		}
+		return err
+	})
+}
+
+// validateHeaders to check if Content-Type and X-Api-Key is given and not empty.
+func (m *MileappHandlers) validateHeaders(logger zerolog.Logger, h http.Header) error {
+	if err := validate.Headers(h, validate.APIKey("x-api-key", append([]string{m.authKey}, m.authKeys...)...)); err != nil {
+		if e, ok := headerErrors[err]; ok {
+			err = e
+		}
+		logger.Err(err).Msg(err.Error())
+		return err
+	}
 	return nil
 }
 
@@ -196,6 +533,10 @@ type UserVar struct {
 	Receiver     string `json:"receiver"`
 	ReceiverName string `json:"receiverName"`
 	DriverPhone  string `json:"driverPhone"`
+	// DriverLatitude and DriverLongitude are the live driver location, nil
+	// when mileapp doesn't send it.
+	DriverLatitude  *float64 `json:"driverLatitude,omitempty"`
+	DriverLongitude *float64 `json:"driverLongitude,omitempty"`
 }
 
 type HandleStatusUpdateRequest struct {
@@ -209,12 +550,36 @@ type AssignedTo struct {
 	FullName string `json:"full_name"`
 }
 
-type HandleStatusUpdateResponse struct {
-	Message string `json:"message"`
+// HandleStatusUpdateResponse is the response body of HandleStatusUpdate.
+type HandleStatusUpdateResponse = httpjson.Response
+
+// statuses of StatusUpdateResult.
+const (
+	StatusUpdateStatusSuccess = "success"
+	StatusUpdateStatusError   = "error"
+)
+
+// StatusUpdateResult is the outcome of a single update of a batch status
+// update request.
+type StatusUpdateResult struct {
+	TaskRefID   string `json:"taskRefId"`
+	OrderNumber string `json:"orderNumber"`
+	// Status is one of success or error.
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
+}
+
+// normalizeStatus trims and lower-cases the task status, mileapp is not
+// consistent with the casing e.g. "Done" or " done ".
+func normalizeStatus(status string) string {
+	return strings.ToLower(strings.TrimSpace(status))
 }
 
-// Validate check all HandleStatusUpdateRequest fields, returns error if empty
+// Validate check all HandleStatusUpdateRequest fields, returns error if empty.
+// TaskStatus is normalized before being validated.
 func (h *HandleStatusUpdateRequest) Validate(logger zerolog.Logger) error {
+	h.TaskStatus = normalizeStatus(h.TaskStatus)
+
 	if h.TaskRefID == "" {
 		return ErrTaskRefIDIsRequired
 	}
@@ -225,7 +590,7 @@ func (h *HandleStatusUpdateRequest) Validate(logger zerolog.Logger) error {
 	switch h.TaskStatus {
 	case "":
 		return ErrStatusIsRequired
-	case statusOngoing, statusDone:
+	case statusOngoing, statusDone, statusCancelled, statusFailed:
 		// continue
 	default:
 		logger.Error().Msgf("got task status: %s", h.TaskStatus)
@@ -236,12 +601,14 @@ func (h *HandleStatusUpdateRequest) Validate(logger zerolog.Logger) error {
 
 func (h *HandleStatusUpdateRequest) ToPB() *tpb.UpdateOrderTaskRequest {
 	req := &tpb.UpdateOrderTaskRequest{}
-	switch h.TaskStatus {
+	switch normalizeStatus(h.TaskStatus) {
 	case statusOngoing, statusDone:
 		// mileapp shared 1 task into 2 states for pickup and delivery flow.
 		// in case for pickup the task status will be ongoing, but we should
 		// map it to success anyway as internally for us its 2 separate tasks.
 		req.State = tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS
+	case statusCancelled, statusFailed:
+		req.State = tpb.OrderTaskState_ORDER_TASK_STATE_FAILED
 	}
 	return req
 }
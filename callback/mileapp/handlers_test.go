@@ -2,22 +2,29 @@ package mileapp
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
 	"github.com/google/go-cmp/cmp"
 	"github.com/gorilla/mux"
 	"github.com/rs/zerolog"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/dropezy/internal/logging"
 	tpbmock "github.com/dropezy/proto/mock/task"
 	tpb "github.com/dropezy/proto/v1/task"
+	"github.com/dropezy/storefront-backend/http/internal/tracing"
+	"github.com/dropezy/storefront-backend/http/internal/tracing/tracingtest"
+	"github.com/dropezy/storefront-backend/http/selftest"
 )
 
 const (
@@ -63,7 +70,12 @@ func TestHandleStatusUpdate(t *testing.T) {
 	t.Run("Success", func(t *testing.T) {
 		t.Parallel()
 
-		mockClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{}, nil)
+		mockClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{
+			Tasks: []*tpb.OrderTask{{
+				TaskId:   "picking-task-id",
+				TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PICKING,
+			}},
+		}, nil)
 		mockClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.UpdateOrderTaskResponse{}, nil)
 
 		h := newTestMileappHandlers(mockClient)
@@ -113,7 +125,7 @@ func TestHandleStatusUpdate(t *testing.T) {
 			want: &HandleStatusUpdateResponse{
 				Message: "expecting http method post, got: GET",
 			},
-			wantHTTPStatusCode: http.StatusBadRequest,
+			wantHTTPStatusCode: http.StatusMethodNotAllowed,
 		},
 		{
 			name:   "MissingContentType",
@@ -163,7 +175,7 @@ func TestHandleStatusUpdate(t *testing.T) {
 			want: &HandleStatusUpdateResponse{
 				Message: ErrXAPIKeyIsRequired.Error(),
 			},
-			wantHTTPStatusCode: http.StatusBadRequest,
+			wantHTTPStatusCode: http.StatusUnauthorized,
 		},
 		{
 			name:   "InvalidXAPIKey",
@@ -176,7 +188,7 @@ func TestHandleStatusUpdate(t *testing.T) {
 			want: &HandleStatusUpdateResponse{
 				Message: ErrInvalidXAPIKey.Error(),
 			},
-			wantHTTPStatusCode: http.StatusBadRequest,
+			wantHTTPStatusCode: http.StatusUnauthorized,
 		},
 		{
 			name:   "EmptyTaskRefID",
@@ -272,6 +284,61 @@ func TestValidate(t *testing.T) {
 			},
 			wantErr: nil,
 		},
+		{
+			name: "CapitalizedTaskStatus",
+			in: &HandleStatusUpdateRequest{
+				TaskRefID:  "1234",
+				TaskStatus: "Done",
+				UserVar: UserVar{
+					OrderNumber: "12345",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "UpperCaseTaskStatusWithWhitespace",
+			in: &HandleStatusUpdateRequest{
+				TaskRefID:  "1234",
+				TaskStatus: "ONGOING ",
+				UserVar: UserVar{
+					OrderNumber: "12345",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "TaskStatusWithWhitespace",
+			in: &HandleStatusUpdateRequest{
+				TaskRefID:  "1234",
+				TaskStatus: " done ",
+				UserVar: UserVar{
+					OrderNumber: "12345",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "CancelledTaskStatus",
+			in: &HandleStatusUpdateRequest{
+				TaskRefID:  "1234",
+				TaskStatus: "Cancelled",
+				UserVar: UserVar{
+					OrderNumber: "12345",
+				},
+			},
+			wantErr: nil,
+		},
+		{
+			name: "WhitespaceOnlyTaskStatus",
+			in: &HandleStatusUpdateRequest{
+				TaskRefID:  "1234",
+				TaskStatus: "  ",
+				UserVar: UserVar{
+					OrderNumber: "12345",
+				},
+			},
+			wantErr: ErrStatusIsRequired,
+		},
 		{
 			name: "EmptyTaskRefID",
 			in: &HandleStatusUpdateRequest{
@@ -329,3 +396,1007 @@ func TestValidate(t *testing.T) {
 		})
 	}
 }
+
+func TestToPB(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name      string
+		in        *HandleStatusUpdateRequest
+		wantState tpb.OrderTaskState
+	}{
+		{
+			name:      "Done",
+			in:        &HandleStatusUpdateRequest{TaskStatus: "done"},
+			wantState: tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+		},
+		{
+			name:      "CapitalizedDone",
+			in:        &HandleStatusUpdateRequest{TaskStatus: "Done"},
+			wantState: tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+		},
+		{
+			name:      "UpperCaseOngoingWithWhitespace",
+			in:        &HandleStatusUpdateRequest{TaskStatus: "ONGOING "},
+			wantState: tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+		},
+		{
+			name:      "DoneWithWhitespace",
+			in:        &HandleStatusUpdateRequest{TaskStatus: " done "},
+			wantState: tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+		},
+		{
+			name:      "Cancelled",
+			in:        &HandleStatusUpdateRequest{TaskStatus: "cancelled"},
+			wantState: tpb.OrderTaskState_ORDER_TASK_STATE_FAILED,
+		},
+		{
+			name:      "Failed",
+			in:        &HandleStatusUpdateRequest{TaskStatus: "failed"},
+			wantState: tpb.OrderTaskState_ORDER_TASK_STATE_FAILED,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := tc.in.ToPB().State; got != tc.wantState {
+				t.Errorf("ToPB() got %v, want %v", got, tc.wantState)
+			}
+		})
+	}
+}
+
+func TestHandleStatusUpdate_StatusStates(t *testing.T) {
+	t.Parallel()
+
+	// ongoing is mapped to a distinct state than done, the state itself
+	// is arbitrary for the test.
+	states, err := ParseStatusStates("ongoing=ORDER_TASK_STATE_FAILED,done=ORDER_TASK_STATE_SUCCESS")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name      string
+		opts      []Option
+		status    string
+		wantState tpb.OrderTaskState
+	}{
+		{
+			name:      "DefaultOngoing",
+			status:    "ongoing",
+			wantState: tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+		},
+		{
+			name:      "DefaultDone",
+			status:    "done",
+			wantState: tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+		},
+		{
+			name:      "DefaultFailed",
+			status:    "failed",
+			wantState: tpb.OrderTaskState_ORDER_TASK_STATE_FAILED,
+		},
+		{
+			name:      "SplitOngoing",
+			opts:      []Option{WithStatusStates(states)},
+			status:    "ongoing",
+			wantState: tpb.OrderTaskState_ORDER_TASK_STATE_FAILED,
+		},
+		{
+			name:      "SplitDone",
+			opts:      []Option{WithStatusStates(states)},
+			status:    "done",
+			wantState: tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+			mockClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{
+				Tasks: []*tpb.OrderTask{{
+					TaskId:   "picking-task-id",
+					TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PICKING,
+				}},
+			}, nil)
+			mockClient.EXPECT().UpdateOrderTask(gomock.Any(), &tpb.UpdateOrderTaskRequest{
+				TaskId: "picking-task-id",
+				State:  tc.wantState,
+			}).Return(&tpb.UpdateOrderTaskResponse{}, nil)
+
+			h := NewMileappHandlers(MockValidXAPIKey, mockClient, tc.opts...)
+
+			body := fmt.Sprintf(`{
+				"taskRefId": "1234",
+				"taskStatus": "%s",
+				"UserVar": {
+					"orderNumber": "cf0df07b-335a-4344-8221-2fba0d507d26"
+				}
+			}`, tc.status)
+			r, err := http.NewRequest(http.MethodPost, "/mileapp/status/picking", bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("x-api-key", MockValidXAPIKey)
+			r.Header.Set("content-type", validContentType)
+
+			w := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/mileapp/status/{task-type}", h.HandleStatusUpdate)
+			router.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != http.StatusOK {
+				t.Errorf("HandleStatusUpdate(), got = %v, want = %v", got, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestParseStatusStates(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseStatusStates(" Ongoing = ORDER_TASK_STATE_FAILED ,done=ORDER_TASK_STATE_SUCCESS")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]tpb.OrderTaskState{
+		statusOngoing: tpb.OrderTaskState_ORDER_TASK_STATE_FAILED,
+		statusDone:    tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("ParseStatusStates() got %v, want %v", got, want)
+	}
+
+	for _, in := range []string{"ongoing", "ongoing=NOT_A_STATE"} {
+		if _, err := ParseStatusStates(in); err == nil {
+			t.Errorf("ParseStatusStates(%q) got nil error", in)
+		}
+	}
+}
+
+func TestParseTaskTypes(t *testing.T) {
+	t.Parallel()
+
+	got, err := ParseTaskTypes(" returning = ORDER_TASK_TYPE_DELIVERY ,picking=ORDER_TASK_TYPE_PACKING")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]tpb.OrderTaskType{
+		"returning":     tpb.OrderTaskType_ORDER_TASK_TYPE_DELIVERY,
+		taskTypePicking: tpb.OrderTaskType_ORDER_TASK_TYPE_PACKING,
+	}
+	if !cmp.Equal(got, want) {
+		t.Errorf("ParseTaskTypes() got %v, want %v", got, want)
+	}
+
+	for _, in := range []string{"returning", "returning=NOT_A_TYPE"} {
+		if _, err := ParseTaskTypes(in); err == nil {
+			t.Errorf("ParseTaskTypes(%q) got nil error", in)
+		}
+	}
+}
+
+func TestHandleStatusUpdate_TaskTypes(t *testing.T) {
+	t.Parallel()
+
+	types := map[string]tpb.OrderTaskType{"returning": tpb.OrderTaskType_ORDER_TASK_TYPE_DELIVERY}
+
+	testCases := []struct {
+		name     string
+		opts     []Option
+		task     string
+		wantType tpb.OrderTaskType
+		wantCode int
+	}{
+		{
+			name:     "Default",
+			task:     "delivery",
+			wantType: tpb.OrderTaskType_ORDER_TASK_TYPE_DELIVERY,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "NotEnabled",
+			task:     "returning",
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:     "Enabled",
+			opts:     []Option{WithTaskTypes(types)},
+			task:     "returning",
+			wantType: tpb.OrderTaskType_ORDER_TASK_TYPE_DELIVERY,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "EnabledKeepsDefault",
+			opts:     []Option{WithTaskTypes(types)},
+			task:     "picking",
+			wantType: tpb.OrderTaskType_ORDER_TASK_TYPE_PICKING,
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := tpbmock.NewMockTaskServiceClient(ctrl)
+			if tc.wantCode == http.StatusOK {
+				mockClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{
+					Tasks: []*tpb.OrderTask{{
+						TaskId:   "task-id",
+						TaskType: tc.wantType,
+					}},
+				}, nil)
+				mockClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.UpdateOrderTaskResponse{}, nil)
+			}
+
+			h := NewMileappHandlers(MockValidXAPIKey, mockClient, tc.opts...)
+
+			r, err := http.NewRequest(http.MethodPost, "/mileapp/status/"+tc.task, bytes.NewBufferString(validBody))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("x-api-key", MockValidXAPIKey)
+			r.Header.Set("content-type", validContentType)
+
+			w := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/mileapp/status/{task-type}", h.HandleStatusUpdate)
+			router.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != tc.wantCode {
+				t.Errorf("HandleStatusUpdate(), got = %v, want = %v", got, tc.wantCode)
+			}
+		})
+	}
+}
+
+func TestHandleStatusUpdate_DriverLocation(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name     string
+		location string
+		want     map[string]string
+	}{
+		{
+			name:     "WithLocation",
+			location: `"driverLatitude": -6.2088, "driverLongitude": 106.8456,`,
+			want: map[string]string{
+				"driver_name":      "John",
+				"driver_phone":     "08123",
+				"driver_latitude":  "-6.2088",
+				"driver_longitude": "106.8456",
+			},
+		},
+		{
+			name: "WithoutLocation",
+			want: map[string]string{
+				"driver_name":  "John",
+				"driver_phone": "08123",
+			},
+		},
+		{
+			name:     "PartialLocation",
+			location: `"driverLatitude": -6.2088,`,
+			want: map[string]string{
+				"driver_name":  "John",
+				"driver_phone": "08123",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+			mockClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{
+				Tasks: []*tpb.OrderTask{{
+					TaskId:   "shipping-task-id",
+					TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_SHIPPING,
+				}},
+			}, nil)
+			mockClient.EXPECT().UpdateOrderTask(gomock.Any(), &tpb.UpdateOrderTaskRequest{
+				TaskId:         "shipping-task-id",
+				State:          tpb.OrderTaskState_ORDER_TASK_STATE_SUCCESS,
+				AdditionalData: tc.want,
+			}).Return(&tpb.UpdateOrderTaskResponse{}, nil)
+
+			h := NewMileappHandlers(MockValidXAPIKey, mockClient)
+
+			body := fmt.Sprintf(`{
+				"taskRefId": "1234",
+				"taskStatus": "ongoing",
+				"assignedTo": {"full_name": "John"},
+				"UserVar": {
+					%s
+					"driverPhone": "08123",
+					"orderNumber": "cf0df07b-335a-4344-8221-2fba0d507d26"
+				}
+			}`, tc.location)
+			r, err := http.NewRequest(http.MethodPost, "/mileapp/status/shipping", bytes.NewBufferString(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("x-api-key", MockValidXAPIKey)
+			r.Header.Set("content-type", validContentType)
+
+			w := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/mileapp/status/{task-type}", h.HandleStatusUpdate)
+			router.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != http.StatusOK {
+				t.Errorf("HandleStatusUpdate(), got = %v, want = %v", got, http.StatusOK)
+			}
+		})
+	}
+}
+
+func TestHandleStatusUpdate_NilResponse(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockClient := tpbmock.NewMockTaskServiceClient(ctrl)
+	mockClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(nil, nil)
+
+	h := newTestMileappHandlers(mockClient)
+
+	r, err := http.NewRequest(http.MethodPost, "/mileapp/status/picking", bytes.NewBufferString(validBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("x-api-key", MockValidXAPIKey)
+	r.Header.Set("content-type", validContentType)
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/mileapp/status/{task-type}", h.HandleStatusUpdate)
+	router.ServeHTTP(w, r)
+
+	if got := w.Result().StatusCode; got != http.StatusInternalServerError {
+		t.Errorf("HandleStatusUpdate(), got = %v, want = %v", got, http.StatusInternalServerError)
+	}
+
+	got := &HandleStatusUpdateResponse{}
+	if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	want := &HandleStatusUpdateResponse{Message: ErrEmptyOrderTaskResponse.Error()}
+	if !cmp.Equal(got, want) {
+		t.Errorf("HandleStatusUpdate(), got %v, want %v", got, want)
+	}
+}
+
+func TestSelfTestRequest(t *testing.T) {
+	t.Parallel()
+
+	h := NewMileappHandlers(MockValidXAPIKey, &selftest.TaskClient{TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PICKING})
+	r, err := h.SelfTestRequest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleStatusUpdate(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleStatusUpdate(), got = %v, want = %v: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+}
+
+func TestValidateHeaders_APIKey(t *testing.T) {
+	t.Parallel()
+
+	h := newTestMileappHandlers(nil)
+
+	testCases := []struct {
+		name    string
+		apiKey  string
+		wantErr error
+	}{
+		{
+			name:    "Empty",
+			wantErr: ErrXAPIKeyIsRequired,
+		},
+		{
+			name:    "Invalid",
+			apiKey:  "invalid-x-api-key",
+			wantErr: ErrInvalidXAPIKey,
+		},
+		{
+			name:    "InvalidSameLength",
+			apiKey:  "valid-x-api-kez",
+			wantErr: ErrInvalidXAPIKey,
+		},
+		{
+			name:   "Valid",
+			apiKey: MockValidXAPIKey,
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			header := http.Header{}
+			header.Set("Content-Type", validContentType)
+			if tc.apiKey != "" {
+				header.Set("X-Api-Key", tc.apiKey)
+			}
+
+			if err := h.validateHeaders(logger, header); err != tc.wantErr {
+				t.Errorf("validateHeaders(), got %v, want %v", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateHeaders_RotatedAPIKeys(t *testing.T) {
+	t.Parallel()
+
+	h := NewMileappHandlers("new-x-api-key", nil, WithAuthKeys(MockValidXAPIKey))
+
+	for apiKey, wantErr := range map[string]error{
+		"new-x-api-key":   nil,
+		MockValidXAPIKey:  nil,
+		"other-x-api-key": ErrInvalidXAPIKey,
+	} {
+		header := http.Header{}
+		header.Set("Content-Type", validContentType)
+		header.Set("X-Api-Key", apiKey)
+
+		if err := h.validateHeaders(logger, header); err != wantErr {
+			t.Errorf("validateHeaders(%q), got %v, want %v", apiKey, err, wantErr)
+		}
+	}
+}
+
+func TestHandleStatusUpdate_NoMatchingTask(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		name  string
+		tasks []*tpb.OrderTask
+	}{
+		{
+			name: "NoTasks",
+		},
+		{
+			name: "NoTaskOfType",
+			tasks: []*tpb.OrderTask{{
+				TaskId:   "delivery-task-id",
+				TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_DELIVERY,
+			}},
+		},
+		{
+			// UpdateOrderTask must not be called with an empty task id.
+			name: "OnlyOtherTaskTypes",
+			tasks: []*tpb.OrderTask{
+				{TaskId: "packing-task-id", TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PACKING},
+				{TaskId: "shipping-task-id", TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_SHIPPING},
+				{TaskId: "payment-task-id", TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PAYMENT},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := tpbmock.NewMockTaskServiceClient(ctrl)
+			mockClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{Tasks: tc.tasks}, nil)
+
+			h := newTestMileappHandlers(mockClient)
+
+			r, err := http.NewRequest(http.MethodPost, "/mileapp/status/picking", bytes.NewBufferString(validBody))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("x-api-key", MockValidXAPIKey)
+			r.Header.Set("content-type", validContentType)
+
+			w := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/mileapp/status/{task-type}", h.HandleStatusUpdate)
+			router.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != http.StatusNotFound {
+				t.Errorf("HandleStatusUpdate(), got = %v, want = %v", got, http.StatusNotFound)
+			}
+
+			got := &HandleStatusUpdateResponse{}
+			if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			want := &HandleStatusUpdateResponse{Message: ErrNoMatchingTask.Error()}
+			if !cmp.Equal(got, want) {
+				t.Errorf("HandleStatusUpdate(), got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestHandleStatusUpdate_CallTimeout(t *testing.T) {
+	t.Parallel()
+
+	// block returns once the call times out.
+	block := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	tests := []struct {
+		name     string
+		clientFn func(m *tpbmock.MockTaskServiceClient)
+	}{
+		{
+			name: "GetOrderTask",
+			clientFn: func(m *tpbmock.MockTaskServiceClient) {
+				m.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, _ *tpb.GetOrderTaskRequest, _ ...interface{}) (*tpb.GetOrderTaskResponse, error) {
+						return nil, block(ctx)
+					})
+			},
+		},
+		{
+			name: "UpdateOrderTask",
+			clientFn: func(m *tpbmock.MockTaskServiceClient) {
+				m.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{
+					Tasks: []*tpb.OrderTask{{
+						TaskId:   "task-id",
+						TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PICKING,
+					}},
+				}, nil)
+				m.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).
+					DoAndReturn(func(ctx context.Context, _ *tpb.UpdateOrderTaskRequest, _ ...interface{}) (*tpb.UpdateOrderTaskResponse, error) {
+						return nil, block(ctx)
+					})
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := tpbmock.NewMockTaskServiceClient(ctrl)
+			test.clientFn(mockClient)
+
+			h := NewMileappHandlers(MockValidXAPIKey, mockClient, WithCallTimeout(10*time.Millisecond))
+
+			r, err := http.NewRequest(http.MethodPost, "/mileapp/status/picking", bytes.NewBufferString(validBody))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("x-api-key", MockValidXAPIKey)
+			r.Header.Set("content-type", validContentType)
+
+			w := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/mileapp/status/{task-type}", h.HandleStatusUpdate)
+			router.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != http.StatusGatewayTimeout {
+				t.Errorf("HandleStatusUpdate(), got = %v, want = %v", got, http.StatusGatewayTimeout)
+			}
+
+			got := &HandleStatusUpdateResponse{}
+			if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			want := &HandleStatusUpdateResponse{Message: ErrTaskServiceTimeout.Error()}
+			if !cmp.Equal(got, want) {
+				t.Errorf("HandleStatusUpdate(), got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestHandleStatusUpdate_SchemaValidation(t *testing.T) {
+	t.Parallel()
+
+	const in = `{"taskRefId": "task-ref-id", "taskStatus": "done", "UserVar": {"orderNumber": 1234}}`
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    string
+	}{
+		{name: "Enabled", enabled: true, want: "invalid request data: /UserVar/orderNumber: expected string, got number"},
+		{name: "Disabled", want: ErrInvalidRequestData.Error()},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			// no call is expected, the request is rejected before decoding.
+			mockClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+			h := NewMileappHandlers(MockValidXAPIKey, mockClient, WithSchemaValidation(test.enabled))
+
+			r, err := http.NewRequest(http.MethodPost, "/mileapp/status/picking", bytes.NewBufferString(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("x-api-key", MockValidXAPIKey)
+			r.Header.Set("content-type", validContentType)
+
+			w := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/mileapp/status/{task-type}", h.HandleStatusUpdate)
+			router.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != http.StatusBadRequest {
+				t.Errorf("HandleStatusUpdate(), got = %v, want = %v", got, http.StatusBadRequest)
+			}
+
+			got := &HandleStatusUpdateResponse{}
+			if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			want := &HandleStatusUpdateResponse{Message: test.want}
+			if !cmp.Equal(got, want) {
+				t.Errorf("HandleStatusUpdate(), got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestHandleStatusUpdate_DisallowUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	const in = `{"task_ref_id": "task-ref-id", "taskStatus": "done", "UserVar": {"orderNumber": "order-number"}}`
+
+	ctrl := gomock.NewController(t)
+	// no call is expected, the request is rejected while decoding.
+	mockClient := tpbmock.NewMockTaskServiceClient(ctrl)
+
+	h := NewMileappHandlers(MockValidXAPIKey, mockClient, WithDisallowUnknownFields(true))
+
+	r, err := http.NewRequest(http.MethodPost, "/mileapp/status/picking", bytes.NewBufferString(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("x-api-key", MockValidXAPIKey)
+	r.Header.Set("content-type", validContentType)
+
+	w := httptest.NewRecorder()
+	router := mux.NewRouter()
+	router.HandleFunc("/mileapp/status/{task-type}", h.HandleStatusUpdate)
+	router.ServeHTTP(w, r)
+
+	if got := w.Result().StatusCode; got != http.StatusBadRequest {
+		t.Errorf("HandleStatusUpdate(), got = %v, want = %v", got, http.StatusBadRequest)
+	}
+
+	got := &HandleStatusUpdateResponse{}
+	if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	want := &HandleStatusUpdateResponse{Message: `invalid request data: unknown field "task_ref_id"`}
+	if !cmp.Equal(got, want) {
+		t.Errorf("HandleStatusUpdate(), got %v, want %v", got, want)
+	}
+}
+
+func TestHandleStatusUpdate_GetOrderTaskError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		err      error
+		wantCode int
+		want     string
+	}{
+		{
+			name:     "NotFound",
+			err:      status.Error(codes.NotFound, "order not found"),
+			wantCode: http.StatusNotFound,
+			want:     ErrOrderNotFound.Error(),
+		},
+		{
+			name:     "Unavailable",
+			err:      status.Error(codes.Unavailable, "unavailable"),
+			wantCode: http.StatusServiceUnavailable,
+			want:     "failed to update order task",
+		},
+		{
+			name:     "Internal",
+			err:      status.Error(codes.Internal, "internal error"),
+			wantCode: http.StatusInternalServerError,
+			want:     "failed to update order task",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := tpbmock.NewMockTaskServiceClient(ctrl)
+			mockClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(nil, test.err)
+
+			h := newTestMileappHandlers(mockClient)
+
+			r, err := http.NewRequest(http.MethodPost, "/mileapp/status/picking", bytes.NewBufferString(validBody))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("x-api-key", MockValidXAPIKey)
+			r.Header.Set("content-type", validContentType)
+
+			w := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/mileapp/status/{task-type}", h.HandleStatusUpdate)
+			router.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != test.wantCode {
+				t.Errorf("HandleStatusUpdate(), got = %v, want = %v", got, test.wantCode)
+			}
+
+			got := &HandleStatusUpdateResponse{}
+			if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			want := &HandleStatusUpdateResponse{Message: test.want}
+			if !cmp.Equal(got, want) {
+				t.Errorf("HandleStatusUpdate(), got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestHandleStatusUpdate_Tracing(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockClient := tpbmock.NewMockTaskServiceClient(ctrl)
+	mockClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).Return(&tpb.GetOrderTaskResponse{Tasks: []*tpb.OrderTask{{
+		TaskId:   "picking-task-id",
+		TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PICKING,
+	}}}, nil)
+	mockClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).Return(nil, status.Error(codes.Unavailable, "unavailable"))
+
+	tp := &tracingtest.Provider{}
+	h := NewMileappHandlers(MockValidXAPIKey, mockClient, WithTracerProvider(tp))
+
+	r, err := http.NewRequest(http.MethodPost, "/mileapp/status/picking", bytes.NewBufferString(validBody))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("x-api-key", MockValidXAPIKey)
+	r.Header.Set("content-type", validContentType)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/mileapp/status/{task-type}", h.HandleStatusUpdate)
+	router.ServeHTTP(httptest.NewRecorder(), r)
+
+	spans := tp.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("HandleStatusUpdate() spans, got = %d, want = 1", len(spans))
+	}
+	got := map[string]string{}
+	for k, v := range spans[0].Attributes {
+		got[string(k)] = v.Emit()
+	}
+	want := map[string]string{
+		"provider":         HandlerName,
+		"order_id":         "cf0df07b-335a-4344-8221-2fba0d507d26",
+		"task_id":          "picking-task-id",
+		"http.status_code": "503",
+		"outcome":          tracing.OutcomeError,
+	}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("HandleStatusUpdate() span attributes, got = %v", cmp.Diff(want, got))
+	}
+}
+
+func TestHandleBatchStatusUpdate(t *testing.T) {
+	t.Parallel()
+
+	const batchBody = `[
+		{"taskRefId": "ref-1", "taskStatus": "done", "UserVar": {"orderNumber": "order-1"}},
+		{"taskRefId": "ref-2", "taskStatus": "Failed", "UserVar": {"orderNumber": "order-2"}}
+	]`
+
+	// getOrderTask returns the picking task of the orders, or the error of
+	// the order.
+	getOrderTask := func(errs map[string]error) func(context.Context, *tpb.GetOrderTaskRequest, ...interface{}) (*tpb.GetOrderTaskResponse, error) {
+		return func(_ context.Context, req *tpb.GetOrderTaskRequest, _ ...interface{}) (*tpb.GetOrderTaskResponse, error) {
+			if err := errs[req.OrderId]; err != nil {
+				return nil, err
+			}
+			return &tpb.GetOrderTaskResponse{Tasks: []*tpb.OrderTask{{
+				TaskId:   req.OrderId + "-picking",
+				TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PICKING,
+			}}}, nil
+		}
+	}
+
+	tests := []struct {
+		name         string
+		in           string
+		getErrs      map[string]error
+		wantUpdates  []string
+		wantCode     int
+		want         []*StatusUpdateResult
+		wantResponse *HandleStatusUpdateResponse
+	}{
+		{
+			name: "Success",
+			in:   batchBody,
+			wantUpdates: []string{
+				"order-1-picking=ORDER_TASK_STATE_SUCCESS",
+				"order-2-picking=ORDER_TASK_STATE_FAILED",
+			},
+			wantCode: http.StatusOK,
+			want: []*StatusUpdateResult{
+				{TaskRefID: "ref-1", OrderNumber: "order-1", Status: StatusUpdateStatusSuccess},
+				{TaskRefID: "ref-2", OrderNumber: "order-2", Status: StatusUpdateStatusSuccess},
+			},
+		},
+		{
+			name:        "PartialFailure",
+			in:          batchBody,
+			getErrs:     map[string]error{"order-2": status.Error(codes.NotFound, "order not found")},
+			wantUpdates: []string{"order-1-picking=ORDER_TASK_STATE_SUCCESS"},
+			wantCode:    http.StatusOK,
+			want: []*StatusUpdateResult{
+				{TaskRefID: "ref-1", OrderNumber: "order-1", Status: StatusUpdateStatusSuccess},
+				{TaskRefID: "ref-2", OrderNumber: "order-2", Status: StatusUpdateStatusError, Error: ErrOrderNotFound.Error()},
+			},
+		},
+		{
+			name: "AllFailed",
+			in:   batchBody,
+			getErrs: map[string]error{
+				"order-1": status.Error(codes.Unavailable, "unavailable"),
+				"order-2": status.Error(codes.Unavailable, "unavailable"),
+			},
+			wantCode: http.StatusServiceUnavailable,
+			want: []*StatusUpdateResult{
+				{TaskRefID: "ref-1", OrderNumber: "order-1", Status: StatusUpdateStatusError, Error: "failed to update order task"},
+				{TaskRefID: "ref-2", OrderNumber: "order-2", Status: StatusUpdateStatusError, Error: "failed to update order task"},
+			},
+		},
+		{
+			name: "InvalidItem",
+			in: `[
+				{"taskRefId": "ref-1", "taskStatus": "done", "UserVar": {"orderNumber": "order-1"}},
+				{"taskRefId": "ref-2", "taskStatus": "done", "UserVar": {}}
+			]`,
+			wantCode:     http.StatusBadRequest,
+			wantResponse: &HandleStatusUpdateResponse{Message: "item 1: " + ErrOrderNumberIsRequired.Error()},
+		},
+		{
+			name:         "EmptyBatch",
+			in:           `[]`,
+			wantCode:     http.StatusBadRequest,
+			wantResponse: &HandleStatusUpdateResponse{Message: ErrEmptyPayload.Error()},
+		},
+		{
+			name:         "SingleObject",
+			in:           validBody,
+			wantCode:     http.StatusBadRequest,
+			wantResponse: &HandleStatusUpdateResponse{Message: ErrInvalidRequestData.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := tpbmock.NewMockTaskServiceClient(ctrl)
+			mockClient.EXPECT().GetOrderTask(gomock.Any(), gomock.Any()).DoAndReturn(getOrderTask(test.getErrs)).AnyTimes()
+
+			// the updates are applied in order, as taskId=state.
+			var gotUpdates []string
+			mockClient.EXPECT().UpdateOrderTask(gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, req *tpb.UpdateOrderTaskRequest, _ ...interface{}) (*tpb.UpdateOrderTaskResponse, error) {
+					gotUpdates = append(gotUpdates, req.TaskId+"="+req.State.String())
+					return &tpb.UpdateOrderTaskResponse{}, nil
+				}).AnyTimes()
+
+			h := newTestMileappHandlers(mockClient)
+
+			r, err := http.NewRequest(http.MethodPost, "/mileapp/status/picking/batch", bytes.NewBufferString(test.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("x-api-key", MockValidXAPIKey)
+			r.Header.Set("content-type", validContentType)
+
+			w := httptest.NewRecorder()
+			router := mux.NewRouter()
+			router.HandleFunc("/mileapp/status/{task-type}/batch", h.HandleBatchStatusUpdate)
+			router.ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != test.wantCode {
+				t.Errorf("HandleBatchStatusUpdate(), got = %v, want = %v", got, test.wantCode)
+			}
+
+			if test.wantResponse != nil {
+				got := &HandleStatusUpdateResponse{}
+				if err := json.NewDecoder(w.Body).Decode(got); err != nil {
+					t.Fatal(err)
+				}
+				if !cmp.Equal(got, test.wantResponse) {
+					t.Errorf("HandleBatchStatusUpdate(), got %v, want %v", got, test.wantResponse)
+				}
+				if len(gotUpdates) != 0 {
+					t.Errorf("HandleBatchStatusUpdate() updates, got = %d, want = 0", len(gotUpdates))
+				}
+				return
+			}
+
+			var got []*StatusUpdateResult
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Errorf("HandleBatchStatusUpdate() results, got = %v", cmp.Diff(test.want, got))
+			}
+			if !cmp.Equal(gotUpdates, test.wantUpdates) {
+				t.Errorf("HandleBatchStatusUpdate() updates, got = %v", cmp.Diff(test.wantUpdates, gotUpdates))
+			}
+		})
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	t.Parallel()
+
+	h := NewMileappHandlers(MockValidXAPIKey, nil, WithAuthKeys("previous-x-api-key"))
+
+	tests := []struct {
+		name    string
+		key     string
+		wantErr error
+	}{
+		{name: "Valid", key: MockValidXAPIKey},
+		{name: "Rotated", key: "previous-x-api-key"},
+		{name: "Missing", wantErr: ErrXAPIKeyIsRequired},
+		{name: "Invalid", key: "invalid", wantErr: ErrInvalidXAPIKey},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			r := httptest.NewRequest(http.MethodPost, "/mileapp/status/picking", nil)
+			if test.key != "" {
+				r.Header.Set("x-api-key", test.key)
+			}
+			if err := h.Verifier().Verify(r, nil); !errors.Is(err, test.wantErr) {
+				t.Fatalf("Verify(), got = %v, want = %v", err, test.wantErr)
+			}
+		})
+	}
+}
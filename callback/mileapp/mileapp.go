@@ -1,5 +1,7 @@
 package mileapp
 
+import tpb "github.com/dropezy/proto/v1/task"
+
 // supported mileapp task type on our end
 const (
 	taskTypePicking  = "picking"
@@ -8,8 +10,19 @@ const (
 	taskTypeDelivery = "delivery"
 )
 
+// defaultTaskTypes maps the task-type path variable to the order task type,
+// see WithTaskTypes.
+var defaultTaskTypes = map[string]tpb.OrderTaskType{
+	taskTypePicking:  tpb.OrderTaskType_ORDER_TASK_TYPE_PICKING,
+	taskTypePacking:  tpb.OrderTaskType_ORDER_TASK_TYPE_PACKING,
+	taskTypeShipping: tpb.OrderTaskType_ORDER_TASK_TYPE_SHIPPING,
+	taskTypeDelivery: tpb.OrderTaskType_ORDER_TASK_TYPE_DELIVERY,
+}
+
 // expected task update status from mileapp
 const (
-	statusOngoing = "ongoing"
-	statusDone    = "done"
+	statusOngoing   = "ongoing"
+	statusDone      = "done"
+	statusCancelled = "cancelled"
+	statusFailed    = "failed"
 )
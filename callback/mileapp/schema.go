@@ -0,0 +1,35 @@
+package mileapp
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/dropezy/storefront-backend/http/internal/jsonschema"
+)
+
+// statusUpdateSchema checks the structure of the status update requests.
+var (
+	//go:embed schema/status_update.json
+	statusUpdateSchemaJSON []byte
+
+	statusUpdateSchema = jsonschema.MustParse(statusUpdateSchemaJSON)
+)
+
+// statusUpdateBatchSchema checks the structure of the batch status update
+// requests, an array of status updates.
+var statusUpdateBatchSchema = &jsonschema.Schema{
+	Type:  jsonschema.Types{"array"},
+	Items: statusUpdateSchema,
+}
+
+// requestDataError returns ErrInvalidRequestData for a request body that
+// failed to decode, wrapping the schema validation and unknown field errors
+// so the response points at the offending field.
+func requestDataError(err error) error {
+	var verr *jsonschema.ValidationError
+	if errors.As(err, &verr) || errors.Is(err, jsonschema.ErrUnknownField) {
+		return fmt.Errorf("%w: %v", ErrInvalidRequestData, err)
+	}
+	return ErrInvalidRequestData
+}
@@ -0,0 +1,30 @@
+package mileapp
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// selfTestBody is a canned valid status update of a picking task.
+const selfTestBody = `{
+	"taskRefId": "selftest-task-ref-id",
+	"taskStatus": "done",
+	"UserVar": {
+		"orderNumber": "selftest-order-number"
+	}
+}`
+
+// SelfTestRequest returns a canned valid picking status update carrying the
+// handler auth key, it is used to dry-run the handler.
+func (m *MileappHandlers) SelfTestRequest(ctx context.Context) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/mileapp/status/"+taskTypePicking, bytes.NewBufferString(selfTestBody))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Api-Key", m.authKey)
+	return mux.SetURLVars(r, map[string]string{"task-type": taskTypePicking}), nil
+}
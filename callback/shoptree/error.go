@@ -1,6 +1,11 @@
 package shoptree
 
-import "errors"
+import (
+	"errors"
+	"net/http"
+
+	"github.com/dropezy/storefront-backend/http/internal/httpjson"
+)
 
 var (
 	ErrReferenceIDIsRequired      = errors.New("reference id is required")
@@ -10,8 +15,11 @@ var (
 	ErrInStockIsRequired          = errors.New("in stock is required")
 	ErrQuantityChangedIsRequired  = errors.New("quantity changed is required")
 	ErrInvalidInStock             = errors.New("invalid in stock value")
+	ErrNegativeInStock            = errors.New("in stock can not be negative")
 	ErrInvalidReferenceType       = errors.New("invalid reference type")
+	ErrDuplicateStockUpdate       = errors.New("duplicate stock update for location id and product variant id")
 	ErrEnabledIsRequired          = errors.New("enabled is required")
+	ErrEmptyPayload               = errors.New("payload has no item")
 
 	ErrContenTypeIsRequired    = errors.New("content type is required")
 	ErrInvalidContentType      = errors.New("content type should be application/json")
@@ -21,6 +29,36 @@ var (
 	// ErrAuthKeyNotFound happens when no auth key is passed when initializing a new handler.
 	ErrAuthKeyNotFound = errors.New("auth key not found")
 
+	ErrMethodNotAllowed   = errors.New("expecting http method post")
+	ErrInvalidRequestData = errors.New("invalid request data")
+	ErrInventoryTimeout   = errors.New("inventory service timeout")
+
 	ErrMarshallingUnsuccessful     = errors.New("marshalling unsuccessful")
 	ErrWriteToResponseUnsuccessful = errors.New("write to response unsuccessful")
 )
+
+// statusCodes are the http status of the errors written with writeError, any
+// other error is http 500.
+var statusCodes = httpjson.StatusCodes{
+	ErrReferenceIDIsRequired:      http.StatusBadRequest,
+	ErrReferenceTypeIsRequired:    http.StatusBadRequest,
+	ErrLocationIDIsRequired:       http.StatusBadRequest,
+	ErrProductVariantIDIsRequired: http.StatusBadRequest,
+	ErrInStockIsRequired:          http.StatusBadRequest,
+	ErrQuantityChangedIsRequired:  http.StatusBadRequest,
+	ErrInvalidInStock:             http.StatusBadRequest,
+	ErrNegativeInStock:            http.StatusBadRequest,
+	ErrInvalidReferenceType:       http.StatusBadRequest,
+	ErrDuplicateStockUpdate:       http.StatusBadRequest,
+	ErrEnabledIsRequired:          http.StatusBadRequest,
+	ErrEmptyPayload:               http.StatusBadRequest,
+
+	ErrContenTypeIsRequired:    http.StatusBadRequest,
+	ErrInvalidContentType:      http.StatusBadRequest,
+	ErrXClientAPIKeyIsRequired: http.StatusUnauthorized,
+	ErrInvalidXClientAPIKey:    http.StatusUnauthorized,
+
+	ErrMethodNotAllowed:   http.StatusMethodNotAllowed,
+	ErrInvalidRequestData: http.StatusBadRequest,
+	ErrInventoryTimeout:   http.StatusGatewayTimeout,
+}
@@ -1,12 +1,20 @@
 package shoptree
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
+	"io"
 	"math"
 	"net/http"
+	"strings"
 
 	"github.com/rs/zerolog"
 
+	"github.com/dropezy/storefront-backend/http/internal/httpjson"
+	"github.com/dropezy/storefront-backend/http/internal/jsonschema"
+	"github.com/dropezy/storefront-backend/http/internal/validate"
+
 	// protobuf
 	inpb "github.com/dropezy/proto/v1/inventory"
 	prpb "github.com/dropezy/proto/v1/product"
@@ -27,6 +35,31 @@ const (
 	reference_type_order_modifier_composite = "order_modifier_composite"
 )
 
+// ReferenceTypes is the set of reference types accepted in stock updates,
+// a type missing from it is rejected with ErrInvalidReferenceType.
+var ReferenceTypes = map[string]bool{
+	reference_type_internal_order:           true,
+	reference_type_purchase_order:           true,
+	reference_type_transfer_order:           true,
+	reference_type_stock_take:               true,
+	reference_type_stock_adjustment:         true,
+	reference_type_preparation:              true,
+	reference_type_separation:               true,
+	reference_type_order:                    true,
+	reference_type_order_modifier:           true,
+	reference_type_order_composite:          true,
+	reference_type_order_modifier_composite: true,
+}
+
+// orderReferenceTypes are the reference types of the order stock movements,
+// they are acknowledged without updating the stock.
+var orderReferenceTypes = map[string]bool{
+	reference_type_order:                    true,
+	reference_type_order_modifier:           true,
+	reference_type_order_composite:          true,
+	reference_type_order_modifier_composite: true,
+}
+
 type UpdateStockRequest struct {
 	ReferenceID      string   `json:"reference_id"`
 	ReferenceType    string   `json:"reference_type"`
@@ -42,7 +75,8 @@ type UpdateProductStatusRequest struct {
 	Enabled          *bool  `json:"enabled"`
 }
 
-// Validate checks all UpdateStockRequest parameters, return error if empty.
+// Validate checks all UpdateStockRequest parameters, return error if empty
+// or when the reference type is not in ReferenceTypes.
 func (u *UpdateStockRequest) Validate() error {
 	// check if any parameter is empty
 	switch {
@@ -58,26 +92,159 @@ func (u *UpdateStockRequest) Validate() error {
 		return ErrInStockIsRequired
 	case u.QuantityChanged == nil:
 		return ErrQuantityChangedIsRequired
+	case *u.InStock < 0:
+		return ErrNegativeInStock
+	case !ReferenceTypes[u.ReferenceType]:
+		return ErrInvalidReferenceType
 	}
 
 	return nil
 }
 
-// ToPB converts UpdateStockRequest to proto format
+// StockRounding is how a fractional in_stock value is converted to the
+// integer quantity of the inventory service.
+type StockRounding int
+
+const (
+	// StockRoundingReject rejects fractional values with ErrInvalidInStock.
+	StockRoundingReject StockRounding = iota
+	// StockRoundingFloor floors fractional values, e.g. 1.5 kg is stored as 1.
+	StockRoundingFloor
+)
+
+// ParseStockRounding parses a stock rounding name, either reject or floor.
+// An empty name is StockRoundingReject.
+func ParseStockRounding(s string) (StockRounding, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "reject":
+		return StockRoundingReject, nil
+	case "floor":
+		return StockRoundingFloor, nil
+	}
+	return 0, fmt.Errorf("invalid stock rounding: %q", s)
+}
+
+// DuplicatePolicy is how the stock updates of a request sharing the same
+// location_id and product_variant_id are handled.
+type DuplicatePolicy int
+
+const (
+	// DuplicatePolicyReject rejects the request with ErrDuplicateStockUpdate.
+	DuplicatePolicyReject DuplicatePolicy = iota
+	// DuplicatePolicyLastWins only applies the last of the duplicates.
+	DuplicatePolicyLastWins
+)
+
+// ParseDuplicatePolicy parses a duplicate policy name, either reject or
+// last_wins. An empty name is DuplicatePolicyReject.
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "", "reject":
+		return DuplicatePolicyReject, nil
+	case "last_wins":
+		return DuplicatePolicyLastWins, nil
+	}
+	return 0, fmt.Errorf("invalid duplicate policy: %q", s)
+}
+
+// CoalesceDuplicates returns the indices of the stock updates to apply, in
+// the request order. Under DuplicatePolicyLastWins only the last update of
+// each location_id and product_variant_id is kept, under
+// DuplicatePolicyReject the first duplicate fails with an *ItemError.
+func CoalesceDuplicates(reqs []*UpdateStockRequest, policy DuplicatePolicy) ([]int, error) {
+	last := make(map[string]int, len(reqs))
+	for i, req := range reqs {
+		key := req.LocationID + ":" + req.ProductVariantID
+		if _, ok := last[key]; ok && policy == DuplicatePolicyReject {
+			return nil, &ItemError{Index: i, Err: ErrDuplicateStockUpdate}
+		}
+		last[key] = i
+	}
+
+	keep := make([]int, 0, len(last))
+	for i, req := range reqs {
+		if last[req.LocationID+":"+req.ProductVariantID] == i {
+			keep = append(keep, i)
+		}
+	}
+	return keep, nil
+}
+
+// ToPB converts UpdateStockRequest to proto format, fractional in_stock
+// values are rejected.
 func (u *UpdateStockRequest) ToPB() (*inpb.UpdateStockRequest, error) {
+	return u.ToPBWithRounding(StockRoundingReject)
+}
+
+// ToPBWithRounding converts UpdateStockRequest to proto format, converting a
+// fractional in_stock value according to rounding.
+func (u *UpdateStockRequest) ToPBWithRounding(rounding StockRounding) (*inpb.UpdateStockRequest, error) {
+	inStock := *u.InStock
 	// check if InStock has decimal points
-	if math.Mod(*u.InStock, 1) != 0 {
-		return nil, ErrInvalidInStock
+	if math.Mod(inStock, 1) != 0 {
+		if rounding != StockRoundingFloor {
+			return nil, ErrInvalidInStock
+		}
+		inStock = math.Floor(inStock)
 	}
 
 	return &inpb.UpdateStockRequest{
 		StoreId:          u.LocationID,
 		ProductVariantId: u.ProductVariantID,
-		Quantity:         int32(*u.InStock),
+		Quantity:         int32(inStock),
 		Source:           inpb.UpdateSource_UPDATE_SOURCE_EXTERNAL,
 	}, nil
 }
 
+// decodeItems decodes the json array of a batch request into v, a pointer to
+// a slice, with dec. A single object is decoded as an array of one item.
+func decodeItems(r io.Reader, dec jsonschema.Decoder, v interface{}) error {
+	var raw json.RawMessage
+	if err := json.NewDecoder(r).Decode(&raw); err != nil {
+		return err
+	}
+	if len(raw) > 0 && raw[0] == '{' {
+		raw = append(append([]byte{'['}, raw...), ']')
+	}
+	return dec.Decode(bytes.NewReader(raw), v)
+}
+
+// ItemError is the error of a single item of a batch request.
+type ItemError struct {
+	// Index is the index of the item in the request.
+	Index int
+	Err   error
+}
+
+func (e *ItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *ItemError) Unwrap() error {
+	return e.Err
+}
+
+// ToBatchPB validates and converts all the stock updates of a request to proto
+// format with rounding, it fails on the first invalid item so nothing is sent
+// when any of them is invalid. The returned error is an *ItemError.
+func ToBatchPB(reqs []*UpdateStockRequest, rounding StockRounding) ([]*inpb.UpdateStockRequest, error) {
+	inventories := make([]*inpb.UpdateStockRequest, 0, len(reqs))
+	for i, req := range reqs {
+		// check if the request contains all required fields and a listed
+		// reference type.
+		if err := req.Validate(); err != nil {
+			return nil, &ItemError{Index: i, Err: err}
+		}
+
+		inventory, err := req.ToPBWithRounding(rounding)
+		if err != nil {
+			return nil, &ItemError{Index: i, Err: err}
+		}
+		inventories = append(inventories, inventory)
+	}
+	return inventories, nil
+}
+
 // Validate checks all UpdateProductStatusRequest parameters, return error if empty.
 func (u *UpdateProductStatusRequest) Validate() error {
 	// check if any parameter is empty
@@ -110,51 +277,78 @@ func (u *UpdateProductStatusRequest) ToPB() *inpb.UpdateStatusRequest {
 	}
 }
 
-type Response struct {
-	Message string `json:"message"`
+// Response is the default response body of the shoptree handlers.
+type Response = httpjson.Response
+
+// statuses of StockUpdateResult.
+const (
+	StockUpdateStatusSuccess = "success"
+	StockUpdateStatusError   = "error"
+	// StockUpdateStatusDuplicate is an update already applied, it is not
+	// sent again to the inventory service.
+	StockUpdateStatusDuplicate = "duplicate"
+	// StockUpdateStatusDryRun is a valid update not sent to the inventory
+	// service, see WithDryRun.
+	StockUpdateStatusDryRun = "dry_run"
+	// StockUpdateStatusSkipped is a valid update of an order reference type,
+	// it is not sent to the inventory service.
+	StockUpdateStatusSkipped = "skipped"
+)
+
+// StockUpdateResult is the outcome of a single item of a stock update request,
+// shoptree only retries the items with an error status.
+type StockUpdateResult struct {
+	ReferenceID      string `json:"reference_id"`
+	LocationID       string `json:"location_id"`
+	ProductVariantID string `json:"product_variant_id"`
+	// Status is one of success, error, duplicate, dry_run or skipped.
+	Status string `json:"status"`
+	Error  string `json:"error,omitempty"`
 }
 
 // responseJSON create mashaled response and return response.
 func responseJSON(logger zerolog.Logger, w http.ResponseWriter, code int, message string) {
-	logger = logger.With().Str("method", "responseJSON").Logger()
-
-	w.Header().Set("Content-Type", "application/json")
+	if err := httpjson.WriteError(w, code, message); err != nil {
+		logger.Err(err).Str("method", "responseJSON").Msg(ErrWriteToResponseUnsuccessful.Error())
+	}
+}
 
-	res, err := json.Marshal(&Response{Message: message})
-	if err != nil {
-		logger.Err(ErrMarshallingUnsuccessful).Msg(ErrMarshallingUnsuccessful.Error())
+// writeError writes err with its http status from statusCodes.
+func writeError(logger zerolog.Logger, w http.ResponseWriter, err error) {
+	if err := httpjson.WriteErr(w, statusCodes, err); err != nil {
+		logger.Err(err).Str("method", "writeError").Msg(ErrWriteToResponseUnsuccessful.Error())
 	}
+}
 
-	w.WriteHeader(code)
+// responseResultsJSON writes the per item results of a stock update request.
+func responseResultsJSON(logger zerolog.Logger, w http.ResponseWriter, code int, results []*StockUpdateResult) {
+	writeJSON(logger, w, code, results)
+}
 
-	if _, err = w.Write(res); err != nil {
-		logger.Err(ErrWriteToResponseUnsuccessful).Msg(ErrWriteToResponseUnsuccessful.Error())
+func writeJSON(logger zerolog.Logger, w http.ResponseWriter, code int, v interface{}) {
+	if err := httpjson.Write(w, code, v); err != nil {
+		logger.Err(err).Str("method", "responseJSON").Msg(ErrWriteToResponseUnsuccessful.Error())
 	}
 }
 
-// validateHeaders to check if Content-Type and X-Client-Api-Key is given and not empty.
-func validateHeaders(logger zerolog.Logger, h http.Header, authKey string) error {
-	// check content type, expect application/json
-	ct := h.Get("Content-Type")
-	if ct == "" {
-		logger.Err(ErrContenTypeIsRequired).Msg(ErrContenTypeIsRequired.Error())
-		return ErrContenTypeIsRequired
-	}
-	if ct != "application/json" {
-		logger.Err(ErrInvalidContentType).Msg(ErrInvalidContentType.Error())
-		return ErrInvalidContentType
-	}
+// headerErrors keeps the error messages of the package for the shared
+// header checks.
+var headerErrors = map[error]error{
+	validate.ErrContentTypeIsRequired: ErrContenTypeIsRequired,
+	validate.ErrInvalidContentType:    ErrInvalidContentType,
+	validate.ErrAPIKeyIsRequired:      ErrXClientAPIKeyIsRequired,
+	validate.ErrInvalidAPIKey:         ErrInvalidXClientAPIKey,
+}
 
-	// check x client api key
-	apiKey := h.Get("X-Client-Api-Key")
-	if apiKey == "" {
-		logger.Err(ErrXClientAPIKeyIsRequired).Msg(ErrXClientAPIKeyIsRequired.Error())
-		return ErrXClientAPIKeyIsRequired
-	}
-	if validApiKey := authKey; apiKey != validApiKey {
-		logger.Err(ErrInvalidXClientAPIKey).Msg(ErrInvalidXClientAPIKey.Error())
-		return ErrInvalidXClientAPIKey
+// validateHeaders to check if Content-Type is given and not empty, the
+// X-Client-Api-Key is checked by the Verifier of the routes.
+func validateHeaders(logger zerolog.Logger, h http.Header) error {
+	if err := validate.Headers(h); err != nil {
+		if e, ok := headerErrors[err]; ok {
+			err = e
+		}
+		logger.Err(err).Msg(err.Error())
+		return err
 	}
-
 	return nil
 }
@@ -0,0 +1,80 @@
+package shoptree
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// processedCache is a bounded LRU of the stock updates already applied, the
+// entries expire after ttl so a legit later update of the same reference is
+// applied again.
+type processedCache struct {
+	mu    sync.Mutex
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[string]*list.Element
+
+	now func() time.Time
+}
+
+type processedEntry struct {
+	key       string
+	expiresAt time.Time
+}
+
+func newProcessedCache(size int, ttl time.Duration) *processedCache {
+	return &processedCache{
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element, size),
+		now:   time.Now,
+	}
+}
+
+// processedKey is the idempotency key of a stock update.
+func processedKey(req *UpdateStockRequest) string {
+	return req.ReferenceID + ":" + req.ProductVariantID
+}
+
+// Contains reports whether key was added and did not expire yet.
+func (c *processedCache) Contains(key string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	if c.now().After(el.Value.(*processedEntry).expiresAt) {
+		c.remove(el)
+		return false
+	}
+	c.ll.MoveToFront(el)
+	return true
+}
+
+// Add adds key, evicting the least recently used key when the cache is full.
+func (c *processedCache) Add(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if el, ok := c.items[key]; ok {
+		el.Value.(*processedEntry).expiresAt = expiresAt
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[key] = c.ll.PushFront(&processedEntry{key: key, expiresAt: expiresAt})
+	if c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+	}
+}
+
+func (c *processedCache) remove(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*processedEntry).key)
+}
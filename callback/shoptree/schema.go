@@ -0,0 +1,32 @@
+package shoptree
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+
+	"github.com/dropezy/storefront-backend/http/internal/jsonschema"
+)
+
+// stockUpdateSchema and productStatusUpdateSchema check the structure of
+// the batch requests, a single object is validated once wrapped in an array.
+var (
+	//go:embed schema/stock_update.json
+	stockUpdateSchemaJSON []byte
+	//go:embed schema/product_status_update.json
+	productStatusUpdateSchemaJSON []byte
+
+	stockUpdateSchema         = jsonschema.MustParse(stockUpdateSchemaJSON)
+	productStatusUpdateSchema = jsonschema.MustParse(productStatusUpdateSchemaJSON)
+)
+
+// requestDataError returns ErrInvalidRequestData for a request body that
+// failed to decode, wrapping the schema validation and unknown field errors
+// so the response points at the offending field.
+func requestDataError(err error) error {
+	var verr *jsonschema.ValidationError
+	if errors.As(err, &verr) || errors.Is(err, jsonschema.ErrUnknownField) {
+		return fmt.Errorf("%w: %v", ErrInvalidRequestData, err)
+	}
+	return ErrInvalidRequestData
+}
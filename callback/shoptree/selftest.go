@@ -0,0 +1,29 @@
+package shoptree
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// selfTestBody is a canned valid stock update.
+const selfTestBody = `[{
+	"reference_id": "selftest-reference-id",
+	"reference_type": "stock_adjustment",
+	"location_id": "selftest-location-id",
+	"product_variant_id": "selftest-product-variant-id",
+	"in_stock": 1,
+	"quantity_changed": 0
+}]`
+
+// SelfTestRequest returns a canned valid stock update carrying the handler
+// auth key, it is used to dry-run the handler.
+func (h *Handler) SelfTestRequest(ctx context.Context) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(ctx, http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(selfTestBody))
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Client-Api-Key", h.authKey)
+	return r, nil
+}
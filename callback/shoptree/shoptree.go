@@ -1,65 +1,257 @@
 package shoptree
 
 import (
-	"encoding/json"
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dropezy/storefront-backend/http/internal/httpjson"
+	"github.com/dropezy/storefront-backend/http/internal/jsonschema"
+	"github.com/dropezy/storefront-backend/http/internal/tracing"
+	"github.com/dropezy/storefront-backend/http/internal/validate"
+	"github.com/dropezy/storefront-backend/http/middleware"
 
 	// protobuf
 	"github.com/dropezy/internal/logging"
 	inpb "github.com/dropezy/proto/v1/inventory"
 )
 
-const handlerName = "shoptree"
+// HandlerName is the name of the handler used in logs, metrics and alerts.
+const HandlerName = "shoptree"
+
+// defaultStockUpdateConcurrency is the default number of stock updates sent
+// concurrently to the inventory service.
+const defaultStockUpdateConcurrency = 8
+
+// defaultStatusUpdateConcurrency is the default number of product status
+// updates sent concurrently to the inventory service.
+const defaultStatusUpdateConcurrency = 8
+
+// defaultCallTimeout is the default time allowed for each call to the
+// inventory service.
+const defaultCallTimeout = 5 * time.Second
 
 // Handler is a http handler to receive callbacks from shoptree
 // and forward it to our internal gRPC services.
 type Handler struct {
 	authKey string
-	client  inpb.InventoryServiceClient
+	// authKeys are the keys accepted on top of authKey while rotating it.
+	authKeys []string
+	client   inpb.InventoryServiceClient
+
+	// callTimeout bounds each stock and product status update sent to the
+	// inventory service.
+	callTimeout time.Duration
+	// stockUpdateConcurrency bounds the stock updates in flight per request.
+	stockUpdateConcurrency int
+	// statusUpdateConcurrency bounds the product status updates in flight
+	// per request.
+	statusUpdateConcurrency int
+	// stockRounding converts the fractional stock of items sold by weight.
+	stockRounding StockRounding
+	// duplicatePolicy handles the stock updates of a request targeting the
+	// same variant in the same location.
+	duplicatePolicy DuplicatePolicy
+	// dryRun validates the updates without sending them to the inventory
+	// service.
+	dryRun bool
+	// schemaValidation checks the request bodies against the embedded json
+	// schemas before decoding them.
+	schemaValidation bool
+	// disallowUnknownFields rejects the request body fields missing from the
+	// request structs.
+	disallowUnknownFields bool
+	// processed keeps the stock updates already applied, shoptree sometimes
+	// redelivers the same callback. It is nil when disabled.
+	processed *processedCache
+	// tracer starts the span of each callback.
+	tracer trace.Tracer
+}
+
+// Option configures optional behaviour of the Handler.
+type Option func(*Handler)
+
+// WithAuthKeys accepts keys on top of the auth key, so a new key can be
+// rolled out to shoptree before the old one is removed.
+func WithAuthKeys(keys ...string) Option {
+	return func(h *Handler) {
+		h.authKeys = keys
+	}
+}
+
+// WithCallTimeout sets the time allowed for each stock and product status
+// update sent to the inventory service. The default of 5s is kept when timeout
+// is not positive.
+func WithCallTimeout(timeout time.Duration) Option {
+	return func(h *Handler) {
+		if timeout > 0 {
+			h.callTimeout = timeout
+		}
+	}
+}
+
+// WithStatusUpdateConcurrency sets how many product status updates of a
+// single request are sent concurrently to the inventory service, values below
+// 1 are ignored.
+func WithStatusUpdateConcurrency(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.statusUpdateConcurrency = n
+		}
+	}
+}
+
+// WithStockUpdateConcurrency sets how many stock updates of a single request
+// are sent concurrently to the inventory service, values below 1 are ignored.
+func WithStockUpdateConcurrency(n int) Option {
+	return func(h *Handler) {
+		if n > 0 {
+			h.stockUpdateConcurrency = n
+		}
+	}
+}
+
+// WithStockRounding sets how fractional in_stock values are converted, they
+// are rejected with http 400 by default.
+func WithStockRounding(rounding StockRounding) Option {
+	return func(h *Handler) {
+		h.stockRounding = rounding
+	}
+}
+
+// WithDuplicatePolicy sets how the duplicated stock updates of a request are
+// handled, the request is rejected with http 400 by default.
+func WithDuplicatePolicy(policy DuplicatePolicy) Option {
+	return func(h *Handler) {
+		h.duplicatePolicy = policy
+	}
+}
+
+// WithDryRun fully validates the stock and product status updates, logging
+// the inventory requests at debug level instead of sending them, so the
+// payloads of a new shoptree location can be checked without mutating the
+// inventory.
+func WithDryRun(enabled bool) Option {
+	return func(h *Handler) {
+		h.dryRun = enabled
+	}
+}
+
+// WithSchemaValidation checks the stock and product status update bodies
+// against their json schema before decoding them, so e.g. an in_stock sent
+// as a string is rejected with the path of the field.
+func WithSchemaValidation(enabled bool) Option {
+	return func(h *Handler) {
+		h.schemaValidation = enabled
+	}
+}
+
+// WithDisallowUnknownFields rejects the stock and product status updates
+// holding a field unknown to the handler, e.g. a renamed in_stock, with the
+// name of the field instead of ignoring it.
+func WithDisallowUnknownFields(enabled bool) Option {
+	return func(h *Handler) {
+		h.disallowUnknownFields = enabled
+	}
+}
+
+// WithIdempotency skips the stock updates whose reference_id and
+// product_variant_id were already applied in the last ttl, at most size of
+// them are kept. It is disabled when size or ttl is not positive.
+func WithIdempotency(size int, ttl time.Duration) Option {
+	return func(h *Handler) {
+		if size <= 0 || ttl <= 0 {
+			h.processed = nil
+			return
+		}
+		h.processed = newProcessedCache(size, ttl)
+	}
+}
+
+// WithTracerProvider starts the span of each callback with a tracer of tp,
+// the spans are no-ops by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(h *Handler) {
+		h.tracer = tracing.Tracer(tp)
+	}
 }
 
 // NewHandler returns a new inventory handler.
-func NewHandler(authKey string, client inpb.InventoryServiceClient) (*Handler, error) {
+func NewHandler(authKey string, client inpb.InventoryServiceClient, opts ...Option) (*Handler, error) {
 	switch "" {
 	case authKey:
 		return nil, ErrAuthKeyNotFound
 	}
-	return &Handler{
+	h := &Handler{
 		authKey: authKey,
 		client:  client,
-	}, nil
+
+		callTimeout: defaultCallTimeout,
+		tracer:      tracing.Tracer(nil),
+
+		stockUpdateConcurrency:  defaultStockUpdateConcurrency,
+		statusUpdateConcurrency: defaultStatusUpdateConcurrency,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h, nil
+}
+
+// validAuthKeys returns all the accepted auth keys.
+func (h *Handler) validAuthKeys() []string {
+	return append([]string{h.authKey}, h.authKeys...)
+}
+
+// Verifier returns the middleware.Verifier of the shoptree routes, checking
+// the X-Client-Api-Key header holds one of the auth keys.
+func (h *Handler) Verifier() middleware.Verifier {
+	return middleware.VerifierFunc(func(r *http.Request, _ []byte) error {
+		err := validate.CheckAPIKey(r.Header, "X-Client-Api-Key", h.validAuthKeys()...)
+		if e, ok := headerErrors[err]; ok {
+			err = e
+		}
+		return err
+	})
 }
 
 // HandleStockUpdate handles callback from Shoptree to update
 // product stock in a specific location.
 func (h *Handler) HandleStockUpdate(w http.ResponseWriter, r *http.Request) {
-	logger := logging.FromContext(r.Context()).With().Str("handler", handlerName).Logger()
+	w, r, span := tracing.Start(h.tracer, w, r, HandlerName, "shoptree.HandleStockUpdate")
+	defer span.End()
+
+	logger := logging.FromContext(r.Context()).With().Str("handler", HandlerName).Logger()
 
 	if r.Method != http.MethodPost {
-		err := fmt.Errorf("expecting http method post, got: %s", r.Method)
-		logger.Err(err).Send()
+		err := fmt.Errorf("%w, got: %s", ErrMethodNotAllowed, r.Method)
+		logger.Err(err).Msg("invalid request method")
 
-		responseJSON(logger, w,
-			http.StatusMethodNotAllowed,
-			err.Error(),
-		)
+		writeError(logger, w, err)
 		return
 	}
 
-	if err := validateHeaders(logger, r.Header, h.authKey); err != nil {
-		responseJSON(logger, w, http.StatusBadRequest,
-			err.Error(),
-		)
+	if err := validateHeaders(logger, r.Header); err != nil {
+		writeError(logger, w, err)
 		return
 	}
 
+	dec := jsonschema.Decoder{DisallowUnknownFields: h.disallowUnknownFields}
+	if h.schemaValidation {
+		dec.Schema = stockUpdateSchema
+	}
 	var data []*UpdateStockRequest
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+	if err := decodeItems(r.Body, dec, &data); err != nil {
 		logger.Err(err).Msg("failed to decode request data")
 
 		if logger.GetLevel() == zerolog.DebugLevel {
@@ -70,115 +262,128 @@ func (h *Handler) HandleStockUpdate(w http.ResponseWriter, r *http.Request) {
 			}
 		}
 
-		responseJSON(logger, w, http.StatusBadRequest,
-			"invalid request data",
-		)
+		writeError(logger, w, requestDataError(err))
 		return
 	}
 
-	for _, req := range data {
-		// add product variant id and location id to logger
-		logger := logger.With().Fields(map[string]interface{}{
-			"shoptree_variant_id":  req.ProductVariantID,
-			"shoptree_location_id": req.LocationID,
-		}).Logger()
+	span.SetAttributes(tracing.ItemsKey.Int(len(data)))
 
-		// check if the request contains all required fields
-		if err := req.Validate(); err != nil {
-			logger.Err(err).Send()
+	// an empty batch is most likely a bug on the shoptree side.
+	if len(data) == 0 {
+		logger.Err(ErrEmptyPayload).Msg("empty payload")
+		writeError(logger, w, ErrEmptyPayload)
+		return
+	}
 
-			responseJSON(logger, w, http.StatusBadRequest,
-				err.Error(),
-			)
-			return
-		}
+	// validate all items before dispatching any of them.
+	inventories, err := ToBatchPB(data, h.stockRounding)
+	if err != nil {
+		responseItemError(logger, w, data, err)
+		return
+	}
 
-		// only update stock to inventory service if the reference type is not type "order",
-		// and return error if the reference type is not listed.
-		switch req.ReferenceType {
-		case reference_type_order,
-			reference_type_internal_order,
-			reference_type_purchase_order,
-			reference_type_transfer_order,
-			reference_type_stock_take,
-			reference_type_stock_adjustment,
-			reference_type_preparation,
-			reference_type_separation,
-			reference_type_order_modifier,
-			reference_type_order_composite,
-			reference_type_order_modifier_composite:
-			inventory, err := req.ToPB()
-			if err != nil {
-				if errors.Is(ErrInvalidInStock, err) {
-					logger.Err(err).Send()
-
-					responseJSON(logger, w, http.StatusBadRequest,
-						err.Error(),
-					)
-					return
-				}
-				logger.Debug().Msg("failed to convert update stock request to pb")
+	// applying duplicates concurrently would leave an arbitrary one of them.
+	keep, err := CoalesceDuplicates(data, h.duplicatePolicy)
+	if err != nil {
+		responseItemError(logger, w, data, err)
+		return
+	}
+	if len(keep) != len(data) {
+		data, inventories = coalesce(logger, data, inventories, keep)
+	}
 
-				responseJSON(logger, w, http.StatusInternalServerError,
-					"failed to update stock",
-				)
-				return
-			}
-			// request update stock to inventory service.
-			if _, err := h.client.UpdateStock(r.Context(), inventory); err != nil {
-				logger.Err(err).Msg("failed to update stock to inventory service")
+	results, code := h.updateStocks(r.Context(), logger, data, inventories)
 
-				responseJSON(logger, w, http.StatusInternalServerError,
-					"failed to update stock",
-				)
-				return
-			}
+	// the request succeeds as long as one of the items succeeded, the results
+	// tell shoptree which items to retry.
+	failed := 0
+	for _, res := range results {
+		if res.Status == StockUpdateStatusError {
+			failed++
+		}
+	}
+	if failed > 0 && failed == len(results) {
+		logger.Error().Int("failed", failed).Int("code", code).Msg("failed to update all stocks")
+		responseResultsJSON(logger, w, code, results)
+		return
+	}
 
-			// logs the returned SKU and Location ID
-			logger.Info().Msg("successfully update stock to inventory service")
-		default:
-			logger.
-				Err(ErrInvalidReferenceType).
-				Str("reference_type", req.ReferenceType).
-				Msg(ErrInvalidReferenceType.Error())
+	logger.Info().Int("failed", failed).Msg("successfully processing update stock request")
+	responseResultsJSON(logger, w, http.StatusOK, results)
+}
 
-			responseJSON(logger, w, http.StatusBadRequest,
-				ErrInvalidReferenceType.Error(),
-			)
-			return
-		}
+// responseItemError writes the error of an invalid item of a batch request
+// with its http status from statusCodes.
+func responseItemError(logger zerolog.Logger, w http.ResponseWriter, data []*UpdateStockRequest, err error) {
+	msg := err.Error()
+	var itemErr *ItemError
+	if errors.As(err, &itemErr) {
+		msg = itemErr.Err.Error()
+		req := data[itemErr.Index]
+		logger = logger.With().Fields(map[string]interface{}{
+			"shoptree_variant_id":  req.ProductVariantID,
+			"shoptree_location_id": req.LocationID,
+			"reference_type":       req.ReferenceType,
+		}).Logger()
+	}
+	logger.Err(err).Msg("invalid request item")
+
+	responseJSON(logger, w, statusCodes.Code(err), msg)
+}
 
+// coalesce returns the stock updates at the keep indices, logging the
+// dropped duplicates.
+func coalesce(logger zerolog.Logger, data []*UpdateStockRequest, inventories []*inpb.UpdateStockRequest, keep []int) ([]*UpdateStockRequest, []*inpb.UpdateStockRequest) {
+	kept := make(map[int]bool, len(keep))
+	for _, i := range keep {
+		kept[i] = true
+	}
+	for i, req := range data {
+		if kept[i] {
+			continue
+		}
+		logger.Warn().Fields(map[string]interface{}{
+			"index":                i,
+			"reference_id":         req.ReferenceID,
+			"shoptree_variant_id":  req.ProductVariantID,
+			"shoptree_location_id": req.LocationID,
+		}).Msg("duplicate stock update, keeping the last one")
 	}
 
-	logger.Info().Msg("successfully processing update stock request")
-	responseJSON(logger, w, http.StatusOK,
-		"success",
-	)
+	coalescedData := make([]*UpdateStockRequest, 0, len(keep))
+	coalescedInventories := make([]*inpb.UpdateStockRequest, 0, len(keep))
+	for _, i := range keep {
+		coalescedData = append(coalescedData, data[i])
+		coalescedInventories = append(coalescedInventories, inventories[i])
+	}
+	return coalescedData, coalescedInventories
 }
 
 func (h *Handler) HandleProductStatusUpdate(w http.ResponseWriter, r *http.Request) {
-	logger := logging.FromContext(r.Context()).With().Str("handler", handlerName).Logger()
+	w, r, span := tracing.Start(h.tracer, w, r, HandlerName, "shoptree.HandleProductStatusUpdate")
+	defer span.End()
+
+	logger := logging.FromContext(r.Context()).With().Str("handler", HandlerName).Logger()
 
 	if r.Method != http.MethodPost {
-		err := fmt.Errorf("expecting http method post, got: %s", r.Method)
-		logger.Err(err).Send()
+		err := fmt.Errorf("%w, got: %s", ErrMethodNotAllowed, r.Method)
+		logger.Err(err).Msg("invalid request method")
 
-		responseJSON(logger, w,
-			http.StatusMethodNotAllowed,
-			err.Error(),
-		)
+		writeError(logger, w, err)
 		return
 	}
 
-	if err := validateHeaders(logger, r.Header, h.authKey); err != nil {
-		responseJSON(logger, w, http.StatusBadRequest,
-			err.Error(),
-		)
+	if err := validateHeaders(logger, r.Header); err != nil {
+		writeError(logger, w, err)
 		return
 	}
 
+	dec := jsonschema.Decoder{DisallowUnknownFields: h.disallowUnknownFields}
+	if h.schemaValidation {
+		dec.Schema = productStatusUpdateSchema
+	}
 	var data []*UpdateProductStatusRequest
-	if err := json.NewDecoder(r.Body).Decode(&data); err != nil {
+	if err := decodeItems(r.Body, dec, &data); err != nil {
 		logger.Err(err).Msg("failed to decode request data")
 
 		if logger.GetLevel() == zerolog.DebugLevel {
@@ -189,40 +394,44 @@ func (h *Handler) HandleProductStatusUpdate(w http.ResponseWriter, r *http.Reque
 			}
 		}
 
-		responseJSON(logger, w, http.StatusBadRequest,
-			"invalid request data",
-		)
+		writeError(logger, w, requestDataError(err))
 		return
 	}
 
-	for _, req := range data {
-		// add product variant id and location id to logger
-		logger := logger.With().Fields(map[string]interface{}{
-			"shoptree_variant_id":  req.ProductVariantID,
-			"shoptree_location_id": req.LocationID,
-		}).Logger()
+	span.SetAttributes(tracing.ItemsKey.Int(len(data)))
+
+	// an empty batch is most likely a bug on the shoptree side.
+	if len(data) == 0 {
+		logger.Err(ErrEmptyPayload).Msg("empty payload")
+		writeError(logger, w, ErrEmptyPayload)
+		return
+	}
 
+	// validate all items before dispatching any of them.
+	for _, req := range data {
 		// check if the request contains all required fields
 		if err := req.Validate(); err != nil {
-			logger.Err(err).Send()
+			logger.Err(err).Fields(map[string]interface{}{
+				"shoptree_variant_id":  req.ProductVariantID,
+				"shoptree_location_id": req.LocationID,
+			}).Send()
 
-			responseJSON(logger, w, http.StatusBadRequest,
-				err.Error(),
-			)
+			writeError(logger, w, err)
 			return
 		}
+	}
 
-		inventory := req.ToPB()
-		// request update product variant status to inventory service.
-		if _, err := h.client.UpdateStatus(r.Context(), inventory); err != nil {
-			logger.Err(err).Msg("failed to update status to inventory service")
-
-			responseJSON(logger, w, http.StatusInternalServerError,
-				"failed to update product variant status",
-			)
+	if failed, code := h.updateStatuses(r.Context(), logger, data); failed > 0 {
+		logger.Error().Int("failed", failed).Int("code", code).Int("total", len(data)).
+			Msg("failed to update product statuses")
+		if code == http.StatusGatewayTimeout {
+			writeError(logger, w, ErrInventoryTimeout)
 			return
 		}
-
+		responseJSON(logger, w, code,
+			"failed to update product variant status",
+		)
+		return
 	}
 
 	logger.Info().Msg("successfully processing update product status request")
@@ -230,3 +439,147 @@ func (h *Handler) HandleProductStatusUpdate(w http.ResponseWriter, r *http.Reque
 		"success",
 	)
 }
+
+// updateStatuses sends the product status updates to the inventory service
+// with at most statusUpdateConcurrency of them in flight, it returns the
+// number of failed updates and their http status, see httpjson.FailureCode.
+func (h *Handler) updateStatuses(ctx context.Context, logger zerolog.Logger, data []*UpdateProductStatusRequest) (int, int) {
+	var (
+		failed int32
+		wg     sync.WaitGroup
+	)
+	codes := make([]int, len(data))
+	sem := make(chan struct{}, h.statusUpdateConcurrency)
+	for i, req := range data {
+		i, req := i, req
+
+		// add product variant id and location id to logger
+		logger := logger.With().Fields(map[string]interface{}{
+			"shoptree_variant_id":  req.ProductVariantID,
+			"shoptree_location_id": req.LocationID,
+		}).Logger()
+
+		inventory := req.ToPB()
+		if h.dryRun {
+			logger.Debug().Interface("inventory_request", inventory).Msg("dry run, skipping product status update")
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ctx, cancel := context.WithTimeout(ctx, h.callTimeout)
+			defer cancel()
+
+			// request update product variant status to inventory service.
+			if _, err := h.client.UpdateStatus(ctx, inventory); err != nil {
+				logger.Err(err).Msg("failed to update status to inventory service")
+				atomic.AddInt32(&failed, 1)
+				codes[i] = httpjson.GRPCToHTTP(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return int(failed), httpjson.FailureCode(codes)
+}
+
+// updateStocks sends the stock updates to the inventory service with at most
+// stockUpdateConcurrency of them in flight, the results are in the order of
+// data. It also returns the http status of the failed updates, see
+// httpjson.FailureCode.
+func (h *Handler) updateStocks(ctx context.Context, logger zerolog.Logger, data []*UpdateStockRequest, inventories []*inpb.UpdateStockRequest) ([]*StockUpdateResult, int) {
+	results := make([]*StockUpdateResult, len(inventories))
+	codes := make([]int, len(inventories))
+	sem := make(chan struct{}, h.stockUpdateConcurrency)
+
+	var wg sync.WaitGroup
+	for i, inventory := range inventories {
+		i, inventory := i, inventory
+		req := data[i]
+		results[i] = &StockUpdateResult{
+			ReferenceID:      req.ReferenceID,
+			LocationID:       req.LocationID,
+			ProductVariantID: req.ProductVariantID,
+		}
+
+		// stock is only updated when the reference type is not an order.
+		if orderReferenceTypes[req.ReferenceType] {
+			logger.Info().
+				Str("reference_id", req.ReferenceID).
+				Str("reference_type", req.ReferenceType).
+				Str("shoptree_variant_id", req.ProductVariantID).
+				Msg("order stock update, skipping")
+			results[i].Status = StockUpdateStatusSkipped
+			continue
+		}
+
+		// skip the redelivered updates, applying them again would double
+		// count the stock movement.
+		if h.processed != nil && h.processed.Contains(processedKey(req)) {
+			logger.Info().
+				Str("reference_id", req.ReferenceID).
+				Str("shoptree_variant_id", req.ProductVariantID).
+				Msg("stock update already applied, ignoring")
+			results[i].Status = StockUpdateStatusDuplicate
+			continue
+		}
+
+		if h.dryRun {
+			logger.Debug().
+				Str("shoptree_variant_id", req.ProductVariantID).
+				Str("shoptree_location_id", req.LocationID).
+				Interface("inventory_request", inventory).
+				Msg("dry run, skipping stock update")
+			results[i].Status = StockUpdateStatusDryRun
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			// add product variant id and location id to logger
+			logger := logger.With().Fields(map[string]interface{}{
+				"shoptree_variant_id":  req.ProductVariantID,
+				"shoptree_location_id": req.LocationID,
+			}).Logger()
+
+			ctx, cancel := context.WithTimeout(ctx, h.callTimeout)
+			defer cancel()
+
+			// request update stock to inventory service.
+			if _, err := h.client.UpdateStock(ctx, inventory); err != nil {
+				logger.Err(err).Msg("failed to update stock to inventory service")
+				results[i].Status = StockUpdateStatusError
+				results[i].Error = "failed to update stock"
+				if isDeadlineExceeded(err) {
+					results[i].Error = ErrInventoryTimeout.Error()
+				}
+				codes[i] = httpjson.GRPCToHTTP(err)
+				return
+			}
+			results[i].Status = StockUpdateStatusSuccess
+			if h.processed != nil {
+				h.processed.Add(processedKey(req))
+			}
+
+			// logs the returned SKU and Location ID
+			logger.Info().Msg("successfully update stock to inventory service")
+		}()
+	}
+	wg.Wait()
+
+	return results, httpjson.FailureCode(codes)
+}
+
+// isDeadlineExceeded reports whether err is an inventory service call that
+// ran out of time, either locally or as a gRPC deadline exceeded status.
+func isDeadlineExceeded(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded) || status.Code(err) == codes.DeadlineExceeded
+}
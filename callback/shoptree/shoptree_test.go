@@ -2,13 +2,27 @@ package shoptree
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/golang/mock/gomock"
+	"github.com/google/go-cmp/cmp"
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/dropezy/storefront-backend/http/middleware"
+	"github.com/dropezy/storefront-backend/http/selftest"
 
 	// protobuf
 
@@ -26,6 +40,11 @@ func newTestHandler(client *inpbmock.MockInventoryServiceClient) *Handler {
 	return h
 }
 
+// verified wraps next with the Verifier of h, like the shoptree routes.
+func verified(h *Handler, next http.HandlerFunc) http.Handler {
+	return middleware.Verify(h.Verifier())(next)
+}
+
 func TestNewHandler(t *testing.T) {
 	t.Parallel()
 
@@ -94,7 +113,7 @@ func TestHandleStockUpdate(t *testing.T) {
 		r.Header.Set("X-Client-Api-Key", validAuthKey)
 		r.Header.Set("Content-Type", validContentType)
 
-		handler := http.HandlerFunc(h.HandleStockUpdate)
+		handler := verified(h, h.HandleStockUpdate)
 		handler.ServeHTTP(w, r)
 
 		resp := w.Result()
@@ -224,6 +243,21 @@ func TestHandleStockUpdate(t *testing.T) {
 			wantErr:     ErrInvalidInStock.Error(),
 			wantErrCode: http.StatusBadRequest,
 		},
+		{
+			name:    "NegativeInStock",
+			method:  http.MethodPost,
+			headers: validHeaders,
+			in: []byte(`[{
+				"reference_id": "valid-reference-id",
+				"reference_type": "stock_adjustment",
+				"location_id": "valid-location-id",
+				"product_variant_id": "valid-product-variant-id",
+				"in_stock": -5,
+				"quantity_changed": -1
+			}]`),
+			wantErr:     ErrNegativeInStock.Error(),
+			wantErrCode: http.StatusBadRequest,
+		},
 		{
 			name:    "InvalidReferenceType",
 			method:  http.MethodPost,
@@ -246,6 +280,14 @@ func TestHandleStockUpdate(t *testing.T) {
 			wantErr:     "invalid request data",
 			wantErrCode: http.StatusBadRequest,
 		},
+		{
+			name:        "EmptyArray",
+			method:      http.MethodPost,
+			headers:     validHeaders,
+			in:          []byte(`[]`),
+			wantErr:     ErrEmptyPayload.Error(),
+			wantErrCode: http.StatusBadRequest,
+		},
 		{
 			name:   "EmptyContentTypeHeader",
 			method: http.MethodPost,
@@ -275,7 +317,7 @@ func TestHandleStockUpdate(t *testing.T) {
 			},
 			in:          []byte(validRequest),
 			wantErr:     ErrXClientAPIKeyIsRequired.Error(),
-			wantErrCode: http.StatusBadRequest,
+			wantErrCode: http.StatusUnauthorized,
 		},
 		{
 			name:   "InvalidAuthKeyHeader",
@@ -286,7 +328,7 @@ func TestHandleStockUpdate(t *testing.T) {
 			},
 			in:          []byte(validRequest),
 			wantErr:     ErrInvalidXClientAPIKey.Error(),
-			wantErrCode: http.StatusBadRequest,
+			wantErrCode: http.StatusUnauthorized,
 		},
 	}
 
@@ -307,7 +349,7 @@ func TestHandleStockUpdate(t *testing.T) {
 				r.Header.Set(k, v)
 			}
 
-			handler := http.HandlerFunc(h.HandleStockUpdate)
+			handler := verified(h, h.HandleStockUpdate)
 			handler.ServeHTTP(w, r)
 
 			resp := w.Result()
@@ -334,6 +376,141 @@ func TestHandleStockUpdate(t *testing.T) {
 	}
 }
 
+func TestHandleStockUpdate_Batch(t *testing.T) {
+	t.Parallel()
+
+	const in = `[{
+		"reference_id": "ref-1",
+		"reference_type": "stock_adjustment",
+		"location_id": "location-id",
+		"product_variant_id": "variant-1",
+		"in_stock": 1,
+		"quantity_changed": -1
+	}, {
+		"reference_id": "ref-2",
+		"reference_type": "purchase_order",
+		"location_id": "location-id",
+		"product_variant_id": "variant-2",
+		"in_stock": 2,
+		"quantity_changed": 1
+	}]`
+
+	tests := []struct {
+		name     string
+		failing  map[string]bool
+		wantCode int
+		want     []*StockUpdateResult
+	}{
+		{
+			name:     "PartialFailure",
+			failing:  map[string]bool{"variant-2": true},
+			wantCode: http.StatusOK,
+			want: []*StockUpdateResult{
+				{ReferenceID: "ref-1", LocationID: "location-id", ProductVariantID: "variant-1", Status: StockUpdateStatusSuccess},
+				{ReferenceID: "ref-2", LocationID: "location-id", ProductVariantID: "variant-2", Status: StockUpdateStatusError, Error: "failed to update stock"},
+			},
+		},
+		{
+			name:     "AllFailed",
+			failing:  map[string]bool{"variant-1": true, "variant-2": true},
+			wantCode: http.StatusInternalServerError,
+			want: []*StockUpdateResult{
+				{ReferenceID: "ref-1", LocationID: "location-id", ProductVariantID: "variant-1", Status: StockUpdateStatusError, Error: "failed to update stock"},
+				{ReferenceID: "ref-2", LocationID: "location-id", ProductVariantID: "variant-2", Status: StockUpdateStatusError, Error: "failed to update stock"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+			mockClient.EXPECT().
+				UpdateStock(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ interface{}, req *inpb.UpdateStockRequest, _ ...interface{}) (*inpb.UpdateStockResponse, error) {
+					if test.failing[req.ProductVariantId] {
+						return nil, errors.New("inventory unavailable")
+					}
+					return &inpb.UpdateStockResponse{}, nil
+				}).
+				Times(2)
+
+			h, err := NewHandler(validAuthKey, mockClient, WithStockUpdateConcurrency(2))
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("X-Client-Api-Key", validAuthKey)
+			r.Header.Set("Content-Type", "application/json")
+
+			verified(h, h.HandleStockUpdate).ServeHTTP(w, r)
+
+			resp := w.Result()
+			if gotStatusCode := resp.StatusCode; gotStatusCode != test.wantCode {
+				t.Fatalf("HandleStockUpdate(), got = %v, want = %v", gotStatusCode, test.wantCode)
+			}
+
+			var got []*StockUpdateResult
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Fatalf("HandleStockUpdate(), got = %v", cmp.Diff(test.want, got))
+			}
+		})
+	}
+}
+
+func TestToBatchPB(t *testing.T) {
+	t.Parallel()
+
+	inStock, quantityChanged := 1.0, -1.0
+	valid := &UpdateStockRequest{
+		ReferenceID:      "ref-1",
+		ReferenceType:    "stock_adjustment",
+		LocationID:       "location-id",
+		ProductVariantID: "variant-1",
+		InStock:          &inStock,
+		QuantityChanged:  &quantityChanged,
+	}
+
+	got, err := ToBatchPB([]*UpdateStockRequest{valid, valid}, StockRoundingReject)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("ToBatchPB(), got = %v items, want = 2", len(got))
+	}
+
+	invalid := *valid
+	invalid.ReferenceType = "invalid-reference-type"
+	_, err = ToBatchPB([]*UpdateStockRequest{valid, &invalid}, StockRoundingReject)
+
+	var itemErr *ItemError
+	if !errors.As(err, &itemErr) {
+		t.Fatalf("ToBatchPB(), got = %v, want an *ItemError", err)
+	}
+	if itemErr.Index != 1 || !errors.Is(err, ErrInvalidReferenceType) {
+		t.Fatalf("ToBatchPB(), got = %v, want = item 1: %v", err, ErrInvalidReferenceType)
+	}
+
+	for referenceType := range ReferenceTypes {
+		req := *valid
+		req.ReferenceType = referenceType
+		if _, err := ToBatchPB([]*UpdateStockRequest{&req}, StockRoundingReject); err != nil {
+			t.Fatalf("ToBatchPB(%s), got = %v, want = nil", referenceType, err)
+		}
+	}
+}
+
 func TestHandleProductStatusUpdate(t *testing.T) {
 	t.Parallel()
 
@@ -376,7 +553,7 @@ func TestHandleProductStatusUpdate(t *testing.T) {
 		r.Header.Set("X-Client-Api-Key", validAuthKey)
 		r.Header.Set("Content-Type", validContentType)
 
-		handler := http.HandlerFunc(h.HandleProductStatusUpdate)
+		handler := verified(h, h.HandleProductStatusUpdate)
 		handler.ServeHTTP(w, r)
 
 		resp := w.Result()
@@ -445,6 +622,14 @@ func TestHandleProductStatusUpdate(t *testing.T) {
 			wantErr:     "invalid request data",
 			wantErrCode: http.StatusBadRequest,
 		},
+		{
+			name:        "EmptyArray",
+			method:      http.MethodPost,
+			headers:     validHeaders,
+			in:          []byte(`[]`),
+			wantErr:     ErrEmptyPayload.Error(),
+			wantErrCode: http.StatusBadRequest,
+		},
 		{
 			name:   "EmptyContentTypeHeader",
 			method: http.MethodPost,
@@ -474,7 +659,7 @@ func TestHandleProductStatusUpdate(t *testing.T) {
 			},
 			in:          []byte(validRequest),
 			wantErr:     ErrXClientAPIKeyIsRequired.Error(),
-			wantErrCode: http.StatusBadRequest,
+			wantErrCode: http.StatusUnauthorized,
 		},
 		{
 			name:   "InvalidAuthKeyHeader",
@@ -485,7 +670,7 @@ func TestHandleProductStatusUpdate(t *testing.T) {
 			},
 			in:          []byte(validRequest),
 			wantErr:     ErrInvalidXClientAPIKey.Error(),
-			wantErrCode: http.StatusBadRequest,
+			wantErrCode: http.StatusUnauthorized,
 		},
 	}
 
@@ -506,7 +691,7 @@ func TestHandleProductStatusUpdate(t *testing.T) {
 				r.Header.Set(k, v)
 			}
 
-			handler := http.HandlerFunc(h.HandleProductStatusUpdate)
+			handler := verified(h, h.HandleProductStatusUpdate)
 			handler.ServeHTTP(w, r)
 
 			resp := w.Result()
@@ -532,3 +717,1073 @@ func TestHandleProductStatusUpdate(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleProductStatusUpdate_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	const concurrency = 2
+	items := make([]string, 0, 6)
+	for i := 0; i < cap(items); i++ {
+		items = append(items, fmt.Sprintf(`{
+			"location_id": "location-id",
+			"product_variant_id": "variant-%d",
+			"enabled": true
+		}`, i))
+	}
+	in := "[" + strings.Join(items, ",") + "]"
+
+	tests := []struct {
+		name     string
+		failed   string
+		wantCode int
+	}{
+		{name: "Success", wantCode: http.StatusOK},
+		{name: "OneFailed", failed: "variant-3", wantCode: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var inFlight, maxInFlight int32
+			ctrl := gomock.NewController(t)
+			mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+			// every item is dispatched, even after a failure.
+			mockClient.EXPECT().
+				UpdateStatus(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, req *inpb.UpdateStatusRequest, _ ...interface{}) (*inpb.UpdateStatusResponse, error) {
+					n := atomic.AddInt32(&inFlight, 1)
+					defer atomic.AddInt32(&inFlight, -1)
+					for {
+						max := atomic.LoadInt32(&maxInFlight)
+						if n <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, n) {
+							break
+						}
+					}
+					time.Sleep(5 * time.Millisecond)
+
+					if req.ProductVariantId == test.failed {
+						return nil, errors.New("unavailable")
+					}
+					return &inpb.UpdateStatusResponse{}, nil
+				}).
+				Times(len(items))
+
+			h, err := NewHandler(validAuthKey, mockClient, WithStatusUpdateConcurrency(concurrency))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/product-status-update", bytes.NewBufferString(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("X-Client-Api-Key", validAuthKey)
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			verified(h, h.HandleProductStatusUpdate).ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != test.wantCode {
+				t.Fatalf("HandleProductStatusUpdate(), got = %v, want = %v", got, test.wantCode)
+			}
+			if got := atomic.LoadInt32(&maxInFlight); got > concurrency {
+				t.Fatalf("HandleProductStatusUpdate(), got %v updates in flight, want at most %v", got, concurrency)
+			}
+		})
+	}
+}
+
+func TestToPBWithRounding(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		inStock  float64
+		rounding StockRounding
+		want     int32
+		wantErr  error
+	}{
+		{
+			name:     "RejectInteger",
+			inStock:  2,
+			rounding: StockRoundingReject,
+			want:     2,
+		},
+		{
+			name:     "RejectFractional",
+			inStock:  1.5,
+			rounding: StockRoundingReject,
+			wantErr:  ErrInvalidInStock,
+		},
+		{
+			name:     "FloorFractional",
+			inStock:  1.5,
+			rounding: StockRoundingFloor,
+			want:     1,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			quantityChanged := -1.0
+			req := &UpdateStockRequest{
+				LocationID:       "location-id",
+				ProductVariantID: "variant-1",
+				InStock:          &test.inStock,
+				QuantityChanged:  &quantityChanged,
+			}
+
+			got, err := req.ToPBWithRounding(test.rounding)
+			if !errors.Is(err, test.wantErr) {
+				t.Fatalf("ToPBWithRounding(), got = %v, want = %v", err, test.wantErr)
+			}
+			if err == nil && got.Quantity != test.want {
+				t.Fatalf("ToPBWithRounding(), got = %v, want = %v", got.Quantity, test.want)
+			}
+		})
+	}
+}
+
+func TestParseStockRounding(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]StockRounding{
+		"":       StockRoundingReject,
+		"reject": StockRoundingReject,
+		"Floor":  StockRoundingFloor,
+	} {
+		got, err := ParseStockRounding(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("ParseStockRounding(%q), got = %v, want = %v", in, got, want)
+		}
+	}
+
+	if _, err := ParseStockRounding("ceil"); err == nil {
+		t.Fatal("ParseStockRounding(), got nil error for an unknown rounding")
+	}
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	t.Parallel()
+
+	for in, want := range map[string]DuplicatePolicy{
+		"":          DuplicatePolicyReject,
+		"reject":    DuplicatePolicyReject,
+		"Last_Wins": DuplicatePolicyLastWins,
+	} {
+		got, err := ParseDuplicatePolicy(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != want {
+			t.Fatalf("ParseDuplicatePolicy(%q), got = %v, want = %v", in, got, want)
+		}
+	}
+
+	if _, err := ParseDuplicatePolicy("first_wins"); err == nil {
+		t.Fatal("ParseDuplicatePolicy(), got nil error for an unknown policy")
+	}
+}
+
+func TestHandleStockUpdate_Duplicates(t *testing.T) {
+	t.Parallel()
+
+	// variant-1 is updated twice with conflicting stocks.
+	const in = `[{
+		"reference_id": "ref-1",
+		"reference_type": "stock_adjustment",
+		"location_id": "location-id",
+		"product_variant_id": "variant-1",
+		"in_stock": 5,
+		"quantity_changed": 1
+	}, {
+		"reference_id": "ref-2",
+		"reference_type": "stock_adjustment",
+		"location_id": "location-id",
+		"product_variant_id": "variant-2",
+		"in_stock": 2,
+		"quantity_changed": 1
+	}, {
+		"reference_id": "ref-3",
+		"reference_type": "stock_adjustment",
+		"location_id": "location-id",
+		"product_variant_id": "variant-1",
+		"in_stock": 3,
+		"quantity_changed": -2
+	}]`
+
+	tests := []struct {
+		name        string
+		policy      DuplicatePolicy
+		wantCode    int
+		wantUpdates map[string]int32
+		want        []*StockUpdateResult
+	}{
+		{
+			name:     "Reject",
+			policy:   DuplicatePolicyReject,
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name:        "LastWins",
+			policy:      DuplicatePolicyLastWins,
+			wantCode:    http.StatusOK,
+			wantUpdates: map[string]int32{"variant-1": 3, "variant-2": 2},
+			want: []*StockUpdateResult{
+				{ReferenceID: "ref-2", LocationID: "location-id", ProductVariantID: "variant-2", Status: StockUpdateStatusSuccess},
+				{ReferenceID: "ref-3", LocationID: "location-id", ProductVariantID: "variant-1", Status: StockUpdateStatusSuccess},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var (
+				mu  sync.Mutex
+				got = map[string]int32{}
+			)
+
+			ctrl := gomock.NewController(t)
+			mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+			mockClient.EXPECT().
+				UpdateStock(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ interface{}, req *inpb.UpdateStockRequest, _ ...interface{}) (*inpb.UpdateStockResponse, error) {
+					mu.Lock()
+					defer mu.Unlock()
+					got[req.ProductVariantId] = req.Quantity
+					return &inpb.UpdateStockResponse{}, nil
+				}).
+				Times(len(test.wantUpdates))
+
+			h, err := NewHandler(validAuthKey, mockClient, WithDuplicatePolicy(test.policy))
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("X-Client-Api-Key", validAuthKey)
+			r.Header.Set("Content-Type", "application/json")
+
+			verified(h, h.HandleStockUpdate).ServeHTTP(w, r)
+
+			resp := w.Result()
+			if gotStatusCode := resp.StatusCode; gotStatusCode != test.wantCode {
+				t.Fatalf("HandleStockUpdate(), got = %v, want = %v", gotStatusCode, test.wantCode)
+			}
+			if test.wantCode != http.StatusOK {
+				return
+			}
+
+			if !cmp.Equal(got, test.wantUpdates) {
+				t.Fatalf("HandleStockUpdate() updates, got = %v", cmp.Diff(test.wantUpdates, got))
+			}
+			var results []*StockUpdateResult
+			if err := json.NewDecoder(resp.Body).Decode(&results); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(results, test.want) {
+				t.Fatalf("HandleStockUpdate(), got = %v", cmp.Diff(test.want, results))
+			}
+		})
+	}
+}
+
+func TestHandleStockUpdate_OrderReferenceTypes(t *testing.T) {
+	t.Parallel()
+
+	for referenceType := range orderReferenceTypes {
+		referenceType := referenceType
+		t.Run(referenceType, func(t *testing.T) {
+			t.Parallel()
+
+			in := fmt.Sprintf(`[{
+				"reference_id": "ref-1",
+				"reference_type": %q,
+				"location_id": "location-id",
+				"product_variant_id": "variant-1",
+				"in_stock": 1,
+				"quantity_changed": -1
+			}]`, referenceType)
+
+			// UpdateStock must not be called.
+			ctrl := gomock.NewController(t)
+			mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+
+			h, err := NewHandler(validAuthKey, mockClient)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("X-Client-Api-Key", validAuthKey)
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			verified(h, h.HandleStockUpdate).ServeHTTP(w, r)
+
+			if gotStatusCode := w.Result().StatusCode; gotStatusCode != http.StatusOK {
+				t.Fatalf("HandleStockUpdate(), got = %v, want = %v", gotStatusCode, http.StatusOK)
+			}
+
+			var got []*StockUpdateResult
+			if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			want := []*StockUpdateResult{{
+				ReferenceID:      "ref-1",
+				LocationID:       "location-id",
+				ProductVariantID: "variant-1",
+				Status:           StockUpdateStatusSkipped,
+			}}
+			if !cmp.Equal(got, want) {
+				t.Fatalf("HandleStockUpdate(), got = %v, want = %v", got, want)
+			}
+		})
+	}
+}
+
+func TestHandleStockUpdate_Idempotency(t *testing.T) {
+	t.Parallel()
+
+	const in = `[{
+		"reference_id": "ref-1",
+		"reference_type": "stock_adjustment",
+		"location_id": "location-id",
+		"product_variant_id": "variant-1",
+		"in_stock": 1,
+		"quantity_changed": -1
+	}]`
+
+	ctrl := gomock.NewController(t)
+	mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+	mockClient.EXPECT().
+		UpdateStock(gomock.Any(), gomock.Any()).
+		Return(&inpb.UpdateStockResponse{}, nil).
+		Times(1)
+
+	h, err := NewHandler(validAuthKey, mockClient, WithIdempotency(10, time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, wantStatus := range []string{StockUpdateStatusSuccess, StockUpdateStatusDuplicate} {
+		r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(in))
+		if err != nil {
+			t.Fatal(err)
+		}
+		r.Header.Set("X-Client-Api-Key", validAuthKey)
+		r.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		verified(h, h.HandleStockUpdate).ServeHTTP(w, r)
+
+		resp := w.Result()
+		if gotStatusCode := resp.StatusCode; gotStatusCode != http.StatusOK {
+			t.Fatalf("HandleStockUpdate(), got = %v, want = %v", gotStatusCode, http.StatusOK)
+		}
+		var got []*StockUpdateResult
+		if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+			t.Fatal(err)
+		}
+		if len(got) != 1 || got[0].Status != wantStatus {
+			t.Fatalf("HandleStockUpdate(), got = %v, want status = %v", got, wantStatus)
+		}
+	}
+}
+
+func TestProcessedCache(t *testing.T) {
+	t.Parallel()
+
+	now := time.Now()
+	c := newProcessedCache(2, time.Minute)
+	c.now = func() time.Time { return now }
+
+	c.Add("ref-1:variant-1")
+	c.Add("ref-2:variant-1")
+	// touch ref-1 so ref-2 is the least recently used
+	if !c.Contains("ref-1:variant-1") {
+		t.Fatal("Contains(ref-1), got = false, want = true")
+	}
+	c.Add("ref-3:variant-1")
+
+	if c.Contains("ref-2:variant-1") {
+		t.Fatal("Contains(ref-2), got = true, want = false after eviction")
+	}
+	if !c.Contains("ref-3:variant-1") {
+		t.Fatal("Contains(ref-3), got = false, want = true")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if c.Contains("ref-1:variant-1") {
+		t.Fatal("Contains(ref-1), got = true, want = false after ttl")
+	}
+}
+
+func TestSelfTestRequest(t *testing.T) {
+	t.Parallel()
+
+	h := newTestHandler(nil)
+	h.client = &selftest.InventoryClient{}
+	r, err := h.SelfTestRequest(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	h.HandleStockUpdate(w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("HandleStockUpdate(), got = %v, want = %v: %s", w.Code, http.StatusOK, w.Body.String())
+	}
+}
+
+func TestHandleStockUpdate_MaxBodyBytes(t *testing.T) {
+	t.Parallel()
+
+	const item = `{
+		"reference_id": "ref-1",
+		"reference_type": "stock_adjustment",
+		"location_id": "location-id",
+		"product_variant_id": "variant-1",
+		"in_stock": 1,
+		"quantity_changed": -1
+	}`
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:     "TooLarge",
+			body:     "[" + item + "," + item + "]",
+			wantCode: http.StatusRequestEntityTooLarge,
+			wantErr:  middleware.ErrBodyTooLarge.Error(),
+		},
+		{
+			name:     "Truncated",
+			body:     "[" + item[:len(item)/2],
+			wantCode: http.StatusBadRequest,
+			wantErr:  "invalid request data",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			h, err := NewHandler(validAuthKey, inpbmock.NewMockInventoryServiceClient(ctrl))
+			if err != nil {
+				t.Fatal(err)
+			}
+			handler := middleware.BufferBody(int64(len(item) + 2))(verified(h, h.HandleStockUpdate))
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(test.body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("X-Client-Api-Key", validAuthKey)
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			if resp.StatusCode != test.wantCode {
+				t.Fatalf("HandleStockUpdate(), got = %v, want = %v", resp.StatusCode, test.wantCode)
+			}
+			res := struct {
+				Message string `json:"message"`
+			}{}
+			if err := json.NewDecoder(resp.Body).Decode(&res); err != nil {
+				t.Fatal(err)
+			}
+			if res.Message != test.wantErr {
+				t.Fatalf("HandleStockUpdate() message, got = %v, want = %v", res.Message, test.wantErr)
+			}
+		})
+	}
+}
+
+func TestHandleProductStatusUpdate_RotatedAuthKeys(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		authKey  string
+		wantCode int
+	}{
+		{name: "NewKey", authKey: "new-x-client-api-key", wantCode: http.StatusOK},
+		{name: "PreviousKey", authKey: validAuthKey, wantCode: http.StatusOK},
+		{name: "UnknownKey", authKey: "other-x-client-api-key", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+			if test.wantCode == http.StatusOK {
+				mockClient.EXPECT().UpdateStatus(gomock.Any(), gomock.Any()).Return(&inpb.UpdateStatusResponse{}, nil)
+			}
+
+			h, err := NewHandler("new-x-client-api-key", mockClient, WithAuthKeys(validAuthKey))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/product-status-update", bytes.NewBufferString(`[{
+				"location_id": "valid-location-id",
+				"product_variant_id": "valid-product-variant-id",
+				"enabled": true
+			}]`))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("X-Client-Api-Key", test.authKey)
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			verified(h, h.HandleProductStatusUpdate).ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != test.wantCode {
+				t.Fatalf("HandleProductStatusUpdate(), got = %v, want = %v", got, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestHandleStockUpdate_DryRun(t *testing.T) {
+	t.Parallel()
+
+	const in = `[{
+		"reference_id": "ref-1",
+		"reference_type": "stock_adjustment",
+		"location_id": "location-id",
+		"product_variant_id": "variant-1",
+		"in_stock": 4,
+		"quantity_changed": 1
+	}]`
+
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs).Level(zerolog.DebugLevel)
+
+	// the inventory client is never called.
+	ctrl := gomock.NewController(t)
+	h, err := NewHandler(validAuthKey, inpbmock.NewMockInventoryServiceClient(ctrl), WithDryRun(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r = r.WithContext(logger.WithContext(r.Context()))
+	r.Header.Set("X-Client-Api-Key", validAuthKey)
+	r.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	verified(h, h.HandleStockUpdate).ServeHTTP(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("HandleStockUpdate(), got = %v, want = %v", resp.StatusCode, http.StatusOK)
+	}
+	var got []*StockUpdateResult
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []*StockUpdateResult{
+		{ReferenceID: "ref-1", LocationID: "location-id", ProductVariantID: "variant-1", Status: StockUpdateStatusDryRun},
+	}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("HandleStockUpdate(), got = %v", cmp.Diff(want, got))
+	}
+	if !strings.Contains(logs.String(), `"inventory_request"`) {
+		t.Fatalf("HandleStockUpdate() logs, got = %s, want the inventory request", logs.String())
+	}
+}
+
+func TestHandleStockUpdate_CallTimeout(t *testing.T) {
+	t.Parallel()
+
+	const in = `[{
+		"reference_id": "ref-1",
+		"reference_type": "stock_adjustment",
+		"location_id": "location-id",
+		"product_variant_id": "variant-1",
+		"in_stock": 4,
+		"quantity_changed": 1
+	}]`
+
+	ctrl := gomock.NewController(t)
+	mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+	// the inventory service blocks until the call times out.
+	mockClient.EXPECT().
+		UpdateStock(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(ctx context.Context, _ *inpb.UpdateStockRequest, _ ...interface{}) (*inpb.UpdateStockResponse, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		})
+
+	h, err := NewHandler(validAuthKey, mockClient, WithCallTimeout(10*time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("X-Client-Api-Key", validAuthKey)
+	r.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	verified(h, h.HandleStockUpdate).ServeHTTP(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusGatewayTimeout {
+		t.Fatalf("HandleStockUpdate(), got = %v, want = %v", resp.StatusCode, http.StatusGatewayTimeout)
+	}
+	var got []*StockUpdateResult
+	if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+		t.Fatal(err)
+	}
+	want := []*StockUpdateResult{{
+		ReferenceID:      "ref-1",
+		LocationID:       "location-id",
+		ProductVariantID: "variant-1",
+		Status:           StockUpdateStatusError,
+		Error:            ErrInventoryTimeout.Error(),
+	}}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("HandleStockUpdate(), got = %v", cmp.Diff(want, got))
+	}
+}
+
+func TestHandleProductStatusUpdate_CallTimeout(t *testing.T) {
+	t.Parallel()
+
+	const in = `[
+		{"location_id": "location-id", "product_variant_id": "variant-1", "enabled": true},
+		{"location_id": "location-id", "product_variant_id": "variant-2", "enabled": true}
+	]`
+
+	tests := []struct {
+		name string
+		// errored fails variant-2 without a timeout.
+		errored  bool
+		wantCode int
+		wantMsg  string
+	}{
+		{
+			name:     "AllTimedOut",
+			wantCode: http.StatusGatewayTimeout,
+			wantMsg:  ErrInventoryTimeout.Error(),
+		},
+		{
+			name:     "OneErrored",
+			errored:  true,
+			wantCode: http.StatusInternalServerError,
+			wantMsg:  "failed to update product variant status",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+			mockClient.EXPECT().
+				UpdateStatus(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(ctx context.Context, req *inpb.UpdateStatusRequest, _ ...interface{}) (*inpb.UpdateStatusResponse, error) {
+					if test.errored && req.ProductVariantId == "variant-2" {
+						return nil, errors.New("unavailable")
+					}
+					<-ctx.Done()
+					return nil, status.Error(codes.DeadlineExceeded, ctx.Err().Error())
+				}).
+				Times(2)
+
+			h, err := NewHandler(validAuthKey, mockClient, WithCallTimeout(10*time.Millisecond))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/product-status-update", bytes.NewBufferString(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("X-Client-Api-Key", validAuthKey)
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			verified(h, h.HandleProductStatusUpdate).ServeHTTP(w, r)
+
+			resp := w.Result()
+			if resp.StatusCode != test.wantCode {
+				t.Fatalf("HandleProductStatusUpdate(), got = %v, want = %v", resp.StatusCode, test.wantCode)
+			}
+			got := &Response{}
+			if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Message != test.wantMsg {
+				t.Fatalf("HandleProductStatusUpdate(), got = %q, want = %q", got.Message, test.wantMsg)
+			}
+		})
+	}
+}
+
+func TestHandleStockUpdate_PayloadShapes(t *testing.T) {
+	t.Parallel()
+
+	const item = `{
+		"reference_id": "ref-1",
+		"reference_type": "stock_adjustment",
+		"location_id": "location-id",
+		"product_variant_id": "variant-1",
+		"in_stock": 4,
+		"quantity_changed": 1
+	}`
+
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{name: "Array", in: "[" + item + "]"},
+		{name: "SingleObject", in: item},
+		{name: "SingleObjectWithSpaces", in: "\n  " + item + "\n"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+			mockClient.EXPECT().
+				UpdateStock(gomock.Any(), &inpb.UpdateStockRequest{
+					StoreId:          "location-id",
+					ProductVariantId: "variant-1",
+					Quantity:         4,
+					Source:           inpb.UpdateSource_UPDATE_SOURCE_EXTERNAL,
+				}).
+				Return(&inpb.UpdateStockResponse{}, nil)
+
+			h := newTestHandler(mockClient)
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(test.in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("X-Client-Api-Key", validAuthKey)
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			verified(h, h.HandleStockUpdate).ServeHTTP(w, r)
+
+			resp := w.Result()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("HandleStockUpdate(), got = %v, want = %v", resp.StatusCode, http.StatusOK)
+			}
+			var got []*StockUpdateResult
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatal(err)
+			}
+			want := []*StockUpdateResult{
+				{ReferenceID: "ref-1", LocationID: "location-id", ProductVariantID: "variant-1", Status: StockUpdateStatusSuccess},
+			}
+			if !cmp.Equal(got, want) {
+				t.Fatalf("HandleStockUpdate(), got = %v", cmp.Diff(want, got))
+			}
+		})
+	}
+}
+
+func TestHandleProductStatusUpdate_SingleObject(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+	mockClient.EXPECT().
+		UpdateStatus(gomock.Any(), gomock.Any()).
+		Return(&inpb.UpdateStatusResponse{}, nil)
+
+	h := newTestHandler(mockClient)
+
+	const in = `{"location_id": "location-id", "product_variant_id": "variant-1", "enabled": true}`
+	r, err := http.NewRequest(http.MethodPost, "/shoptree/product-status-update", bytes.NewBufferString(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("X-Client-Api-Key", validAuthKey)
+	r.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	verified(h, h.HandleProductStatusUpdate).ServeHTTP(w, r)
+
+	if got := w.Result().StatusCode; got != http.StatusOK {
+		t.Fatalf("HandleProductStatusUpdate(), got = %v, want = %v", got, http.StatusOK)
+	}
+}
+
+func TestHandleStockUpdate_SchemaValidation(t *testing.T) {
+	t.Parallel()
+
+	const in = `{"reference_id": "ref-1", "reference_type": "stock_adjustment", "location_id": "location-id", "product_variant_id": "variant-1", "in_stock": "4"}`
+
+	tests := []struct {
+		name    string
+		enabled bool
+		want    string
+	}{
+		{name: "Enabled", enabled: true, want: "invalid request data: /0/in_stock: expected number or null, got string"},
+		{name: "Disabled", want: ErrInvalidRequestData.Error()},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			// no call is expected, the request is rejected before decoding.
+			mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+
+			h, err := NewHandler(validAuthKey, mockClient, WithSchemaValidation(test.enabled))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("X-Client-Api-Key", validAuthKey)
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			verified(h, h.HandleStockUpdate).ServeHTTP(w, r)
+
+			resp := w.Result()
+			if resp.StatusCode != http.StatusBadRequest {
+				t.Fatalf("HandleStockUpdate(), got = %v, want = %v", resp.StatusCode, http.StatusBadRequest)
+			}
+			got := &Response{}
+			if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Message != test.want {
+				t.Fatalf("HandleStockUpdate(), got = %q, want = %q", got.Message, test.want)
+			}
+		})
+	}
+}
+
+func TestHandleProductStatusUpdate_SchemaValidation(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+
+	h, err := NewHandler(validAuthKey, mockClient, WithSchemaValidation(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const in = `[{"location_id": "location-id", "product_variant_id": "variant-1", "enabled": "true"}]`
+	r, err := http.NewRequest(http.MethodPost, "/shoptree/product-status-update", bytes.NewBufferString(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("X-Client-Api-Key", validAuthKey)
+	r.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	verified(h, h.HandleProductStatusUpdate).ServeHTTP(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("HandleProductStatusUpdate(), got = %v, want = %v", resp.StatusCode, http.StatusBadRequest)
+	}
+	got := &Response{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	if want := "invalid request data: /0/enabled: expected boolean or null, got string"; got.Message != want {
+		t.Fatalf("HandleProductStatusUpdate(), got = %q, want = %q", got.Message, want)
+	}
+}
+
+func TestHandleStockUpdate_DisallowUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	// no call is expected, the request is rejected while decoding.
+	mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+
+	h, err := NewHandler(validAuthKey, mockClient, WithDisallowUnknownFields(true))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const in = `[{"reference_id": "ref-1", "reference_type": "stock_adjustment", "location_id": "location-id", "product_variant_id": "variant-1", "inStock": 4}]`
+	r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("X-Client-Api-Key", validAuthKey)
+	r.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	verified(h, h.HandleStockUpdate).ServeHTTP(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("HandleStockUpdate(), got = %v, want = %v", resp.StatusCode, http.StatusBadRequest)
+	}
+	got := &Response{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	if want := `invalid request data: unknown field "inStock"`; got.Message != want {
+		t.Fatalf("HandleStockUpdate(), got = %q, want = %q", got.Message, want)
+	}
+}
+
+func TestHandleStockUpdate_BackendStatus(t *testing.T) {
+	t.Parallel()
+
+	const in = `[
+		{"reference_id": "ref-1", "reference_type": "stock_adjustment", "location_id": "location-id", "product_variant_id": "variant-1", "in_stock": 4, "quantity_changed": 1},
+		{"reference_id": "ref-2", "reference_type": "stock_adjustment", "location_id": "location-id", "product_variant_id": "variant-2", "in_stock": 2, "quantity_changed": 1}
+	]`
+
+	tests := []struct {
+		name string
+		// errs are the inventory service errors per variant.
+		errs     map[string]error
+		wantCode int
+	}{
+		{
+			name: "Unavailable",
+			errs: map[string]error{
+				"variant-1": status.Error(codes.Unavailable, "unavailable"),
+				"variant-2": status.Error(codes.Unavailable, "unavailable"),
+			},
+			wantCode: http.StatusServiceUnavailable,
+		},
+		{
+			name: "InvalidArgument",
+			errs: map[string]error{
+				"variant-1": status.Error(codes.InvalidArgument, "invalid"),
+				"variant-2": status.Error(codes.InvalidArgument, "invalid"),
+			},
+			wantCode: http.StatusBadRequest,
+		},
+		{
+			name: "Mixed",
+			errs: map[string]error{
+				"variant-1": status.Error(codes.Unavailable, "unavailable"),
+				"variant-2": status.Error(codes.InvalidArgument, "invalid"),
+			},
+			wantCode: http.StatusInternalServerError,
+		},
+		{
+			name: "PartialFailure",
+			errs: map[string]error{
+				"variant-1": status.Error(codes.Unavailable, "unavailable"),
+			},
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctrl := gomock.NewController(t)
+			mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+			mockClient.EXPECT().
+				UpdateStock(gomock.Any(), gomock.Any()).
+				DoAndReturn(func(_ context.Context, req *inpb.UpdateStockRequest, _ ...interface{}) (*inpb.UpdateStockResponse, error) {
+					if err := test.errs[req.ProductVariantId]; err != nil {
+						return nil, err
+					}
+					return &inpb.UpdateStockResponse{}, nil
+				}).
+				Times(2)
+
+			h := newTestHandler(mockClient)
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", bytes.NewBufferString(in))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("X-Client-Api-Key", validAuthKey)
+			r.Header.Set("Content-Type", "application/json")
+
+			w := httptest.NewRecorder()
+			verified(h, h.HandleStockUpdate).ServeHTTP(w, r)
+
+			if got := w.Result().StatusCode; got != test.wantCode {
+				t.Fatalf("HandleStockUpdate(), got = %v, want = %v", got, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestHandleProductStatusUpdate_Unavailable(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	mockClient := inpbmock.NewMockInventoryServiceClient(ctrl)
+	mockClient.EXPECT().
+		UpdateStatus(gomock.Any(), gomock.Any()).
+		Return(nil, status.Error(codes.Unavailable, "unavailable"))
+
+	h := newTestHandler(mockClient)
+
+	const in = `[{"location_id": "location-id", "product_variant_id": "variant-1", "enabled": true}]`
+	r, err := http.NewRequest(http.MethodPost, "/shoptree/product-status-update", bytes.NewBufferString(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("X-Client-Api-Key", validAuthKey)
+	r.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	verified(h, h.HandleProductStatusUpdate).ServeHTTP(w, r)
+
+	if got := w.Result().StatusCode; got != http.StatusServiceUnavailable {
+		t.Fatalf("HandleProductStatusUpdate(), got = %v, want = %v", got, http.StatusServiceUnavailable)
+	}
+}
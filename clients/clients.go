@@ -0,0 +1,161 @@
+// Package clients builds the gRPC clients of the storefront backend with the
+// standard dial options and interceptors, so the handlers can be wired the
+// same way in main and in the tests.
+package clients
+
+import (
+	"context"
+	"crypto/tls"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+	"google.golang.org/grpc/metadata"
+	grpctrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/google.golang.org/grpc"
+
+	"github.com/dropezy/storefront-backend/http/breaker"
+	"github.com/dropezy/storefront-backend/http/grpcconn"
+	"github.com/dropezy/storefront-backend/http/middleware"
+
+	// protobuf
+	inpb "github.com/dropezy/proto/v1/inventory"
+	opb "github.com/dropezy/proto/v1/order"
+	tpb "github.com/dropezy/proto/v1/task"
+)
+
+// TLSConfig configures the TLS of the backend connection.
+type TLSConfig struct {
+	// Enabled dials with TLS, the connection is insecure otherwise.
+	Enabled bool
+	// CAFile is the CA certificate verifying the backend, the system roots
+	// are used when empty.
+	CAFile string
+	// ServerName overrides the server name verified in the backend
+	// certificate.
+	ServerName string
+}
+
+// Config configures the backend connection.
+type Config struct {
+	// Addr is a comma separated list of host:port addresses, see
+	// grpcconn.Target.
+	Addr string
+	// AuthKey is sent as the x-api-key of every call.
+	AuthKey string
+	// ServiceName is the service of the client traces.
+	ServiceName string
+	TLS         TLSConfig
+
+	// RetryAttempts and RetryBackoff control the retry of the idempotent
+	// reads, see grpcconn.RetryInterceptor.
+	RetryAttempts int
+	RetryBackoff  time.Duration
+
+	// KeepaliveTime pings the backend after that long without activity, the
+	// connection is closed when the ping is not acknowledged within
+	// KeepaliveTimeout. Disabled when KeepaliveTime is 0.
+	KeepaliveTime    time.Duration
+	KeepaliveTimeout time.Duration
+
+	// Breaker fails the calls fast while the backend is unavailable, it is
+	// not used when nil.
+	Breaker *breaker.Breaker
+
+	// DialOptions are appended to the standard options, e.g. the dialer of
+	// an in-memory listener in tests.
+	DialOptions []grpc.DialOption
+}
+
+// Clients are the gRPC clients of the storefront backend, sharing the same
+// connection.
+type Clients struct {
+	Conn      *grpc.ClientConn
+	Order     opb.OrderServiceClient
+	Task      tpb.TaskServiceClient
+	Inventory inpb.InventoryServiceClient
+}
+
+// New dials the backend with cfg and returns its clients, the connection is
+// established in the background so it doesn't fail when the backend is down.
+func New(ctx context.Context, cfg Config) (*Clients, error) {
+	creds, err := TransportCredentials(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+
+	target, opts, err := grpcconn.Target(cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	opts = append(opts, grpc.WithTransportCredentials(creds), grpc.WithChainUnaryInterceptor(interceptors(cfg)...))
+
+	// keep the connection warm between sporadic callbacks, the backend
+	// drops the idle connections otherwise.
+	if cfg.KeepaliveTime > 0 {
+		opts = append(opts, grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:                cfg.KeepaliveTime,
+			Timeout:             cfg.KeepaliveTimeout,
+			PermitWithoutStream: true,
+		}))
+	}
+
+	conn, err := grpc.DialContext(ctx, target, append(opts, cfg.DialOptions...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &Clients{
+		Conn:      conn,
+		Order:     opb.NewOrderServiceClient(conn),
+		Task:      tpb.NewTaskServiceClient(conn),
+		Inventory: inpb.NewInventoryServiceClient(conn),
+	}, nil
+}
+
+// Close closes the connection of the clients.
+func (c *Clients) Close() error {
+	return c.Conn.Close()
+}
+
+// TransportCredentials returns the credentials of the backend connection,
+// they are insecure when TLS is not enabled.
+func TransportCredentials(cfg TLSConfig) (credentials.TransportCredentials, error) {
+	if !cfg.Enabled {
+		return insecure.NewCredentials(), nil
+	}
+	if cfg.CAFile == "" {
+		// verified with the system roots.
+		return credentials.NewTLS(&tls.Config{ServerName: cfg.ServerName}), nil
+	}
+	return credentials.NewClientTLSFromFile(cfg.CAFile, cfg.ServerName)
+}
+
+// interceptors returns the unary interceptors of the clients, in call order.
+func interceptors(cfg Config) []grpc.UnaryClientInterceptor {
+	i := []grpc.UnaryClientInterceptor{
+		grpctrace.UnaryClientInterceptor(grpctrace.WithServiceName(cfg.ServiceName)),
+		grpcconn.RetryInterceptor(cfg.RetryAttempts, cfg.RetryBackoff, grpcconn.ReadMethods...),
+	}
+	if cfg.Breaker != nil {
+		i = append(i, cfg.Breaker.UnaryClientInterceptor())
+	}
+	return append(i, authInterceptor(cfg.AuthKey), requestIDInterceptor)
+}
+
+// authInterceptor sends key as the x-api-key of the calls.
+func authInterceptor(key string) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		authCtx := metadata.AppendToOutgoingContext(ctx, "x-api-Key", key)
+		return invoker(authCtx, method, req, reply, cc, opts...)
+	}
+}
+
+// requestIDInterceptor forwards the request ID of the incoming webhook to the
+// backend, so a single webhook can be followed across services.
+func requestIDInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if id, ok := middleware.RequestIDFromContext(ctx); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", id)
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
@@ -0,0 +1,114 @@
+package clients
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+
+	"github.com/dropezy/storefront-backend/http/grpcconn"
+	"github.com/dropezy/storefront-backend/http/middleware"
+)
+
+func TestNew(t *testing.T) {
+	t.Parallel()
+
+	t.Run("Success", func(t *testing.T) {
+		t.Parallel()
+
+		// the backend doesn't need to be up, the connection is lazy.
+		c, err := New(context.Background(), Config{Addr: "localhost:50051", AuthKey: "auth-key"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { c.Close() })
+
+		if c.Order == nil || c.Task == nil || c.Inventory == nil {
+			t.Fatalf("New(), got = %+v, want all the clients", c)
+		}
+	})
+
+	t.Run("EmptyAddr", func(t *testing.T) {
+		t.Parallel()
+
+		if _, err := New(context.Background(), Config{}); !errors.Is(err, grpcconn.ErrAddrIsRequired) {
+			t.Fatalf("New(), got = %v, want %v", err, grpcconn.ErrAddrIsRequired)
+		}
+	})
+
+	t.Run("MissingCAFile", func(t *testing.T) {
+		t.Parallel()
+
+		cfg := Config{
+			Addr: "localhost:50051",
+			TLS:  TLSConfig{Enabled: true, CAFile: "testdata/missing.pem"},
+		}
+		if _, err := New(context.Background(), cfg); err == nil {
+			t.Fatal("New(), got nil error for a missing ca file")
+		}
+	})
+}
+
+func TestTransportCredentials(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  TLSConfig
+		want string
+	}{
+		{name: "Disabled", want: "insecure"},
+		{name: "SystemRoots", cfg: TLSConfig{Enabled: true, ServerName: "backend"}, want: "tls"},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			creds, err := TransportCredentials(test.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := creds.Info().SecurityProtocol; got != test.want {
+				t.Fatalf("TransportCredentials(), got = %v, want = %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestInterceptors_Metadata(t *testing.T) {
+	t.Parallel()
+
+	var got metadata.MD
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		got, _ = metadata.FromOutgoingContext(ctx)
+		return nil
+	}
+	handler := middleware.RequestID(zerolog.Nop())(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// the auth interceptor calls the request id one, as in the chain of
+		// the connection.
+		next := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			return requestIDInterceptor(ctx, method, req, reply, cc, invoker, opts...)
+		}
+		if err := authInterceptor("auth-key")(r.Context(), "/task.TaskService/GetOrderTask", nil, nil, nil, next); err != nil {
+			t.Fatal(err)
+		}
+	}))
+
+	r := httptest.NewRequest(http.MethodPost, "/mileapp/status/picking", nil)
+	r.Header.Set(middleware.RequestIDHeader, "request-id")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	if v := got.Get("x-api-key"); len(v) != 1 || v[0] != "auth-key" {
+		t.Errorf("x-api-key, got = %v, want = [auth-key]", v)
+	}
+	if v := got.Get("x-request-id"); len(v) != 1 || v[0] != "request-id" {
+		t.Errorf("x-request-id, got = %v, want = [request-id]", v)
+	}
+}
@@ -0,0 +1,99 @@
+// Package dedup provides the key value store used to remember which callbacks
+// have already been processed, so duplicated deliveries can be short-circuited.
+package dedup
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Entry is the value stored for a dedup key.
+type Entry struct {
+	// Outcome is the result recorded when the key was first processed.
+	Outcome string
+	// ExpiresAt is the time at which the key is evicted from the store.
+	ExpiresAt time.Time
+}
+
+// TTL returns the remaining time to live of the entry relative to now.
+func (e Entry) TTL(now time.Time) time.Duration {
+	if ttl := e.ExpiresAt.Sub(now); ttl > 0 {
+		return ttl
+	}
+	return 0
+}
+
+// KVStore stores processed keys with a ttl. Implementations must be safe for
+// concurrent use, so it can be backed by an in-memory map or by redis.
+type KVStore interface {
+	// Get returns the entry stored for key, the boolean is false when the key
+	// is not present or already expired.
+	Get(ctx context.Context, key string) (Entry, bool, error)
+	// Set stores outcome for key, the key is evicted after ttl.
+	Set(ctx context.Context, key, outcome string, ttl time.Duration) error
+	// Delete evicts key from the store, deleting a missing key is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// MemoryStore is an in-memory KVStore. Expired keys are evicted lazily when
+// they are read, or when a new key is stored.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]Entry
+
+	// now is used to get the current time, replaced in tests.
+	now func() time.Time
+}
+
+// NewMemoryStore returns a new empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries: make(map[string]Entry),
+		now:     time.Now,
+	}
+}
+
+// Get implements KVStore.
+func (s *MemoryStore) Get(_ context.Context, key string) (Entry, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return Entry{}, false, nil
+	}
+	if !s.now().Before(e.ExpiresAt) {
+		delete(s.entries, key)
+		return Entry{}, false, nil
+	}
+	return e, true, nil
+}
+
+// Set implements KVStore.
+func (s *MemoryStore) Set(_ context.Context, key, outcome string, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for k, e := range s.entries {
+		if !now.Before(e.ExpiresAt) {
+			delete(s.entries, k)
+		}
+	}
+
+	s.entries[key] = Entry{
+		Outcome:   outcome,
+		ExpiresAt: now.Add(ttl),
+	}
+	return nil
+}
+
+// Delete implements KVStore.
+func (s *MemoryStore) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.entries, key)
+	return nil
+}
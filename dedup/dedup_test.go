@@ -0,0 +1,52 @@
+package dedup
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	s := NewMemoryStore()
+	s.now = func() time.Time { return now }
+
+	if _, ok, err := s.Get(ctx, "missing"); ok || err != nil {
+		t.Fatalf("Get(), got = %v %v, want = false nil", ok, err)
+	}
+
+	if err := s.Set(ctx, "key", "success", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+
+	e, ok, err := s.Get(ctx, "key")
+	if !ok || err != nil {
+		t.Fatalf("Get(), got = %v %v, want = true nil", ok, err)
+	}
+	if e.Outcome != "success" {
+		t.Fatalf("Get(), got = %v, want = %v", e.Outcome, "success")
+	}
+	if got := e.TTL(now); got != time.Minute {
+		t.Fatalf("TTL(), got = %v, want = %v", got, time.Minute)
+	}
+
+	// key is evicted once the ttl is reached.
+	now = now.Add(time.Minute)
+	if _, ok, _ := s.Get(ctx, "key"); ok {
+		t.Fatal("Get(), got = true, want = false")
+	}
+
+	if err := s.Set(ctx, "key", "success", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(ctx, "key"); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := s.Get(ctx, "key"); ok {
+		t.Fatal("Get() after Delete(), got = true, want = false")
+	}
+}
@@ -0,0 +1,166 @@
+// Package errorlog keeps the last errors logged by each provider handler in
+// memory, so a failing provider can be triaged from the admin endpoints
+// without searching the logs first. The request ID of an entry leads to the
+// full log lines.
+package errorlog
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/middleware"
+)
+
+// DefaultSize is the number of errors kept per provider when NewRecorder is
+// given a size below 1.
+const DefaultSize = 20
+
+// Entry is an error logged by a provider handler.
+type Entry struct {
+	Time      time.Time `json:"time"`
+	Message   string    `json:"message"`
+	Error     string    `json:"error,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
+}
+
+// ring keeps the last len(entries) entries of a single provider.
+type ring struct {
+	entries []Entry
+	// next is the index of the next entry, n the number of entries kept.
+	next, n int
+}
+
+// add records e, replacing the oldest entry when the ring is full.
+func (r *ring) add(e Entry) {
+	r.entries[r.next] = e
+	r.next = (r.next + 1) % len(r.entries)
+	if r.n < len(r.entries) {
+		r.n++
+	}
+}
+
+// last returns the entries kept, newest first.
+func (r *ring) last() []Entry {
+	entries := make([]Entry, 0, r.n)
+	for i := 1; i <= r.n; i++ {
+		entries = append(entries, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+	return entries
+}
+
+// Recorder keeps the last errors per provider, it is safe for concurrent
+// use. The memory used is bounded by size times the number of providers,
+// which are the fixed handler names.
+type Recorder struct {
+	size int
+
+	mu        sync.Mutex
+	providers map[string]*ring
+
+	now func() time.Time
+}
+
+// NewRecorder returns a new Recorder keeping the last size errors of each
+// provider.
+func NewRecorder(size int) *Recorder {
+	if size < 1 {
+		size = DefaultSize
+	}
+	return &Recorder{
+		size:      size,
+		providers: map[string]*ring{},
+		now:       time.Now,
+	}
+}
+
+// register adds provider to the known providers.
+func (rec *Recorder) register(provider string) *ring {
+	r, ok := rec.providers[provider]
+	if !ok {
+		r = &ring{entries: make([]Entry, rec.size)}
+		rec.providers[provider] = r
+	}
+	return r
+}
+
+// Record records an error of provider, Time is set when it is zero.
+func (rec *Recorder) Record(provider string, e Entry) {
+	if e.Time.IsZero() {
+		e.Time = rec.now()
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	rec.register(provider).add(e)
+}
+
+// Last returns the errors kept for provider, newest first. It returns false
+// when provider has no Middleware and no error recorded.
+func (rec *Recorder) Last(provider string) ([]Entry, bool) {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+
+	r, ok := rec.providers[provider]
+	if !ok {
+		return nil, false
+	}
+	return r.last(), true
+}
+
+// hook records the errors of a request context logger.
+type hook struct {
+	rec       *Recorder
+	provider  string
+	requestID string
+}
+
+// Run implements zerolog.Hook.
+func (h hook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	if level < zerolog.ErrorLevel || level > zerolog.PanicLevel {
+		return
+	}
+	h.rec.Record(h.provider, Entry{Message: msg, Error: eventError(e), RequestID: h.requestID})
+}
+
+// eventError returns the error field of e, or "" when it has none. zerolog
+// doesn't expose the fields of an event to its hooks and the writer of the
+// context logger is owned by the logging package, so they are read from the
+// JSON encoded so far, which is only missing its closing brace.
+// TestEventError fails when a zerolog upgrade drops the buf field.
+func eventError(e *zerolog.Event) string {
+	buf := reflect.ValueOf(e).Elem().FieldByName("buf")
+	if buf.Kind() != reflect.Slice || buf.Type().Elem().Kind() != reflect.Uint8 {
+		return ""
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(append(append([]byte(nil), buf.Bytes()...), '}'), &fields); err != nil {
+		return ""
+	}
+	msg, _ := fields[zerolog.ErrorFieldName].(string)
+	return msg
+}
+
+// Middleware returns a middleware recording under provider the messages and
+// the errors of the error logs of the context logger. It goes after RequestID so the
+// entries carry the request ID.
+func (rec *Recorder) Middleware(provider string) func(http.Handler) http.Handler {
+	// the provider is known from now on, even before its first error.
+	rec.mu.Lock()
+	rec.register(provider)
+	rec.mu.Unlock()
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id, _ := middleware.RequestIDFromContext(r.Context())
+			l := logging.FromContext(r.Context()).Hook(hook{rec: rec, provider: provider, requestID: id})
+			next.ServeHTTP(w, r.WithContext(l.WithContext(r.Context())))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package errorlog
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/rs/zerolog"
+
+	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/middleware"
+)
+
+func TestRecorder(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	rec := NewRecorder(2)
+	rec.now = func() time.Time { return now }
+
+	if _, ok := rec.Last("midtrans"); ok {
+		t.Fatal("Last(), got = true, want = false")
+	}
+
+	rec.Record("midtrans", Entry{Message: "first"})
+	now = now.Add(time.Second)
+	rec.Record("midtrans", Entry{Message: "second", RequestID: "req-2"})
+	now = now.Add(time.Second)
+	// the oldest entry is replaced once the ring is full.
+	rec.Record("midtrans", Entry{Message: "third"})
+	rec.Record("shoptree", Entry{Message: "other"})
+
+	got, ok := rec.Last("midtrans")
+	if !ok {
+		t.Fatal("Last(), got = false, want = true")
+	}
+	want := []Entry{
+		{Time: now, Message: "third"},
+		{Time: now.Add(-time.Second), Message: "second", RequestID: "req-2"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("Last(), got = %v", cmp.Diff(want, got))
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 6, 1, 12, 0, 0, 0, time.UTC)
+	rec := NewRecorder(0)
+	rec.now = func() time.Time { return now }
+
+	handler := rec.Middleware("mileapp")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.FromContext(r.Context())
+		logger.Info().Msg("received request")
+		logger.Warn().Msg("retrying")
+		logger.Err(errors.New("rpc error: code = Unavailable")).Str("task_id", "task-1").Msg("failed to get order task")
+		logger.Error().Msg("failed to write response")
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	handler = middleware.RequestID(zerolog.New(io.Discard))(handler)
+
+	// the provider is known before its first error.
+	if got, ok := rec.Last("mileapp"); !ok || len(got) != 0 {
+		t.Fatalf("Last(), got = %v, %v, want = [], true", got, ok)
+	}
+
+	r, err := http.NewRequest(http.MethodPost, "/mileapp/status-update", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set(middleware.RequestIDHeader, "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), r)
+
+	got, _ := rec.Last("mileapp")
+	want := []Entry{
+		{Time: now, Message: "failed to write response", RequestID: "req-1"},
+		{Time: now, Message: "failed to get order task", Error: "rpc error: code = Unavailable", RequestID: "req-1"},
+	}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("Last(), got = %v", cmp.Diff(want, got))
+	}
+}
+
+// TestEventError pins the zerolog internals read by eventError, the errors
+// recorded silently lose their error field when the event buffer moves.
+func TestEventError(t *testing.T) {
+	t.Parallel()
+
+	field, ok := reflect.TypeOf(zerolog.Event{}).FieldByName("buf")
+	if !ok || field.Type != reflect.TypeOf([]byte(nil)) {
+		t.Fatalf("zerolog.Event.buf, got = %v, %v, want = []byte, true", field.Type, ok)
+	}
+
+	var got []string
+	logger := zerolog.New(io.Discard).Hook(zerolog.HookFunc(func(e *zerolog.Event, level zerolog.Level, msg string) {
+		got = append(got, eventError(e))
+	}))
+	logger.Err(errors.New("rpc error: code = Unavailable")).Str("task_id", "task-1").Msg("failed to get order task")
+	logger.Error().Msg("failed to write response")
+
+	want := []string{"rpc error: code = Unavailable", ""}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("eventError(), got = %v", cmp.Diff(want, got))
+	}
+}
@@ -0,0 +1,81 @@
+// Package grpcconn dials the storefront backend, load balancing the calls
+// over all of its instances.
+package grpcconn
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"google.golang.org/grpc"
+	_ "google.golang.org/grpc/health" // enables client side health checking
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// ServiceConfig spreads the calls round-robin over the healthy backends, a
+// backend not reporting SERVING on the standard gRPC health service is
+// skipped until it recovers.
+const ServiceConfig = `{
+	"loadBalancingConfig": [{"round_robin": {}}],
+	"healthCheckConfig": {"serviceName": ""}
+}`
+
+// staticScheme is the resolver scheme used for a list of addresses.
+const staticScheme = "static"
+
+var ErrAddrIsRequired = errors.New("grpc addr is required")
+
+// ParseAddrs parses a comma separated list of host:port addresses.
+func ParseAddrs(s string) ([]string, error) {
+	var addrs []string
+	for _, addr := range strings.Split(s, ",") {
+		addr = strings.TrimSpace(addr)
+		if addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(addr); err != nil {
+			return nil, fmt.Errorf("invalid grpc addr %q: %w", addr, err)
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, ErrAddrIsRequired
+	}
+	return addrs, nil
+}
+
+// Target returns the dial target of addr along with the dial options needed
+// to resolve it. A single address is resolved through DNS, so a name
+// resolving to several instances is balanced over all of them; several
+// addresses are balanced as they are.
+func Target(addr string) (string, []grpc.DialOption, error) {
+	addrs, err := ParseAddrs(addr)
+	if err != nil {
+		return "", nil, err
+	}
+
+	opts := []grpc.DialOption{grpc.WithDefaultServiceConfig(ServiceConfig)}
+	if len(addrs) == 1 {
+		return "dns:///" + addrs[0], opts, nil
+	}
+
+	state := resolver.State{}
+	for _, a := range addrs {
+		state.Addresses = append(state.Addresses, resolver.Address{Addr: a})
+	}
+	r := manual.NewBuilderWithScheme(staticScheme)
+	r.InitialState(state)
+
+	return r.Scheme() + ":///backend", append(opts, grpc.WithResolvers(r)), nil
+}
+
+// Dial dials addr, a comma separated list of host:port addresses, see Target.
+func Dial(addr string, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	target, targetOpts, err := Target(addr)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Dial(target, append(targetOpts, opts...)...)
+}
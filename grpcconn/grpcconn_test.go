@@ -0,0 +1,135 @@
+package grpcconn
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestParseAddrs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		addr    string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "Single",
+			addr: "localhost:50051",
+			want: []string{"localhost:50051"},
+		},
+		{
+			name: "Multiple",
+			addr: "backend-0:50051, backend-1:50051,",
+			want: []string{"backend-0:50051", "backend-1:50051"},
+		},
+		{
+			name:    "Empty",
+			addr:    " , ",
+			wantErr: true,
+		},
+		{
+			name:    "MissingPort",
+			addr:    "backend-0:50051,backend-1",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := ParseAddrs(test.addr)
+			if (err != nil) != test.wantErr {
+				t.Fatalf("ParseAddrs(), err = %v, wantErr = %v", err, test.wantErr)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Fatalf("ParseAddrs(), got = %v, want = %v", got, test.want)
+			}
+		})
+	}
+
+	if _, err := ParseAddrs(""); !errors.Is(err, ErrAddrIsRequired) {
+		t.Fatalf("ParseAddrs(), got = %v, want = %v", err, ErrAddrIsRequired)
+	}
+}
+
+func TestTarget(t *testing.T) {
+	t.Parallel()
+
+	target, opts, err := Target("backend:50051")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != "dns:///backend:50051" {
+		t.Fatalf("Target(), got = %v, want = %v", target, "dns:///backend:50051")
+	}
+	if len(opts) != 1 {
+		t.Fatalf("Target() options, got = %v, want = 1", len(opts))
+	}
+
+	target, opts, err = Target("backend-0:50051,backend-1:50051")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if target != staticScheme+":///backend" {
+		t.Fatalf("Target(), got = %v, want = %v", target, staticScheme+":///backend")
+	}
+	if len(opts) != 2 {
+		t.Fatalf("Target() options, got = %v, want = 2", len(opts))
+	}
+}
+
+// newHealthServer starts a gRPC server exposing the health service and
+// returns its address.
+func newHealthServer(t *testing.T) (string, *health.Server) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hs := health.NewServer()
+	srv := grpc.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go func() { _ = srv.Serve(lis) }()
+	t.Cleanup(srv.Stop)
+	return lis.Addr().String(), hs
+}
+
+func TestDial_SkipsUnhealthyBackend(t *testing.T) {
+	t.Parallel()
+
+	addr0, _ := newHealthServer(t)
+	addr1, hs1 := newHealthServer(t)
+	hs1.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	conn, err := Dial(addr0+","+addr1, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	client := healthpb.NewHealthClient(conn)
+	for i := 0; i < 10; i++ {
+		res, err := client.Check(ctx, &healthpb.HealthCheckRequest{}, grpc.WaitForReady(true))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Status != healthpb.HealthCheckResponse_SERVING {
+			t.Fatalf("Check(), got = %v, want = %v", res.Status, healthpb.HealthCheckResponse_SERVING)
+		}
+	}
+}
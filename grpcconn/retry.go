@@ -0,0 +1,56 @@
+package grpcconn
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ReadMethods are the names of the idempotent backend methods, safe to send
+// again when a call fails before the backend answered.
+var ReadMethods = []string{"Get", "GetOrderTask"}
+
+// RetryInterceptor retries the calls of methods failing with Unavailable or
+// DeadlineExceeded, up to maxAttempts calls in total with a backoff doubling
+// after each attempt. methods are matched by name without the service, e.g.
+// "GetOrderTask" for "/task.TaskService/GetOrderTask", so the writes are
+// never retried unless listed.
+func RetryInterceptor(maxAttempts int, backoff time.Duration, methods ...string) grpc.UnaryClientInterceptor {
+	retried := make(map[string]bool, len(methods))
+	for _, m := range methods {
+		retried[m] = true
+	}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if maxAttempts <= 1 || !retried[path.Base(method)] {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		delay := backoff
+		for attempt := 1; ; attempt++ {
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if attempt >= maxAttempts || !retryable(err) {
+				return err
+			}
+
+			select {
+			case <-ctx.Done():
+				return err
+			case <-time.After(delay):
+			}
+			delay *= 2
+		}
+	}
+}
+
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	}
+	return false
+}
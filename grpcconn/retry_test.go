@@ -0,0 +1,78 @@
+package grpcconn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRetryInterceptor(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		method    string
+		errs      []error
+		wantCalls int
+		wantCode  codes.Code
+	}{
+		{
+			name:      "ReadRecovered",
+			method:    "/task.TaskService/GetOrderTask",
+			errs:      []error{status.Error(codes.Unavailable, "restarting"), nil},
+			wantCalls: 2,
+			wantCode:  codes.OK,
+		},
+		{
+			name:   "ReadExhausted",
+			method: "/order.OrderService/Get",
+			errs: []error{
+				status.Error(codes.DeadlineExceeded, "slow"),
+				status.Error(codes.Unavailable, "restarting"),
+				status.Error(codes.Unavailable, "restarting"),
+			},
+			wantCalls: 3,
+			wantCode:  codes.Unavailable,
+		},
+		{
+			name:      "ReadNotRetryable",
+			method:    "/task.TaskService/GetOrderTask",
+			errs:      []error{status.Error(codes.NotFound, "not found")},
+			wantCalls: 1,
+			wantCode:  codes.NotFound,
+		},
+		{
+			name:      "WriteNotRetried",
+			method:    "/task.TaskService/UpdateOrderTask",
+			errs:      []error{status.Error(codes.Unavailable, "restarting")},
+			wantCalls: 1,
+			wantCode:  codes.Unavailable,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			calls := 0
+			invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+				err := test.errs[calls]
+				calls++
+				return err
+			}
+
+			err := RetryInterceptor(3, time.Millisecond, ReadMethods...)(context.Background(), test.method, nil, nil, nil, invoker)
+			if got := status.Code(err); got != test.wantCode {
+				t.Fatalf("RetryInterceptor(), got = %v, want = %v", got, test.wantCode)
+			}
+			if calls != test.wantCalls {
+				t.Fatalf("RetryInterceptor() calls, got = %v, want = %v", calls, test.wantCalls)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+// Package health contains the probe handlers used by kubernetes to check
+// the http server, they never require auth headers.
+package health
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/dropezy/internal/logging"
+)
+
+const (
+	LivenessPath  = "/healthz"
+	ReadinessPath = "/readyz"
+	VersionPath   = "/version"
+	StatusPath    = "/status"
+
+	statusOK            = "ok"
+	statusNotReady      = "not ready"
+	statusReady         = "ready"
+	statusNotConfigured = "not configured"
+	statusDegraded      = "degraded"
+)
+
+// Response is the response body of the probe handlers.
+type Response struct {
+	Status  string `json:"status"`
+	Service string `json:"service,omitempty"`
+	Version string `json:"version,omitempty"`
+	// State is the state of the upstream gRPC connection.
+	State string `json:"state,omitempty"`
+}
+
+// Build describes the running build, it is the response body of the
+// version handler.
+type Build struct {
+	Service     string `json:"service"`
+	Version     string `json:"version"`
+	Environment string `json:"environment"`
+	Commit      string `json:"commit"`
+}
+
+// ProviderStatus is the state of a callback provider handler.
+type ProviderStatus struct {
+	Status string `json:"status"`
+	// Error is why the handler failed to initialize.
+	Error string `json:"error,omitempty"`
+}
+
+// StatusResponse is the response body of the status handler.
+type StatusResponse struct {
+	Status    string                    `json:"status"`
+	Providers map[string]ProviderStatus `json:"providers"`
+}
+
+// ConnStater reports the state of a gRPC connection, it is implemented
+// by *grpc.ClientConn.
+type ConnStater interface {
+	GetState() connectivity.State
+}
+
+// Liveness returns a handler that always responds with http 200, it does
+// not call any downstream service so it stays cheap to probe.
+func Liveness(service, version string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseJSON(w, r, http.StatusOK, &Response{
+			Status:  statusOK,
+			Service: service,
+			Version: version,
+		})
+	}
+}
+
+// Readiness returns a handler that responds with http 503 when the upstream
+// gRPC connection is not usable, i.e. in TransientFailure or Shutdown state.
+func Readiness(conn ConnStater) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		state := conn.GetState()
+
+		switch state {
+		case connectivity.TransientFailure, connectivity.Shutdown:
+			responseJSON(w, r, http.StatusServiceUnavailable, &Response{
+				Status: statusNotReady,
+				State:  state.String(),
+			})
+		default:
+			responseJSON(w, r, http.StatusOK, &Response{
+				Status: statusOK,
+				State:  state.String(),
+			})
+		}
+	}
+}
+
+// Version returns a handler that responds with b, so the deploy tooling can
+// assert the running build.
+func Version(b Build) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseJSON(w, r, http.StatusOK, &b)
+	}
+}
+
+// Status returns a handler that reports, per provider, whether its handler
+// is configured and ready, the status is degraded when any of them is not.
+// It always responds with http 200 as the server keeps serving the other
+// providers, so it must not be used as a probe. providers maps the provider names to the error
+// returned while initializing their handler, nil when it succeeded.
+func Status(providers map[string]error) http.HandlerFunc {
+	resp := StatusResponse{
+		Status:    statusOK,
+		Providers: make(map[string]ProviderStatus, len(providers)),
+	}
+	for name, err := range providers {
+		if err != nil {
+			resp.Status = statusDegraded
+			resp.Providers[name] = ProviderStatus{Status: statusNotConfigured, Error: err.Error()}
+			continue
+		}
+		resp.Providers[name] = ProviderStatus{Status: statusReady}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		responseJSON(w, r, http.StatusOK, &resp)
+	}
+}
+
+// responseJSON marshals v and writes it as the response body.
+func responseJSON(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	logger := logging.FromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		logger.Err(err).Msg("failed to write probe response")
+	}
+}
@@ -0,0 +1,162 @@
+package health
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/connectivity"
+)
+
+type fakeConn connectivity.State
+
+func (c fakeConn) GetState() connectivity.State { return connectivity.State(c) }
+
+func TestLiveness(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodGet, LivenessPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Liveness("http-server", "v1.0.0").ServeHTTP(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Liveness(), got = %v, want = %v", resp.StatusCode, http.StatusOK)
+	}
+
+	got := &Response{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	want := &Response{Status: "ok", Service: "http-server", Version: "v1.0.0"}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("Liveness(), got = %+v, want = %+v", got, want)
+	}
+}
+
+func TestVersion(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodGet, VersionPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := &Build{Service: "http-server", Version: "v1.0.0", Environment: "staging", Commit: "abc1234"}
+	Version(*want).ServeHTTP(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Version(), got = %v, want = %v", resp.StatusCode, http.StatusOK)
+	}
+
+	got := &Build{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("Version(), got = %+v, want = %+v", got, want)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		state    connectivity.State
+		wantCode int
+		want     *Response
+	}{
+		{
+			state:    connectivity.Ready,
+			wantCode: http.StatusOK,
+			want:     &Response{Status: "ok", State: "READY"},
+		},
+		{
+			state:    connectivity.Idle,
+			wantCode: http.StatusOK,
+			want:     &Response{Status: "ok", State: "IDLE"},
+		},
+		{
+			state:    connectivity.TransientFailure,
+			wantCode: http.StatusServiceUnavailable,
+			want:     &Response{Status: "not ready", State: "TRANSIENT_FAILURE"},
+		},
+		{
+			state:    connectivity.Shutdown,
+			wantCode: http.StatusServiceUnavailable,
+			want:     &Response{Status: "not ready", State: "SHUTDOWN"},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.state.String(), func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			r, err := http.NewRequest(http.MethodGet, ReadinessPath, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			Readiness(fakeConn(test.state)).ServeHTTP(w, r)
+
+			resp := w.Result()
+			if resp.StatusCode != test.wantCode {
+				t.Fatalf("Readiness(), got = %v, want = %v", resp.StatusCode, test.wantCode)
+			}
+
+			got := &Response{}
+			if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Fatalf("Readiness(), got = %+v, want = %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestStatus(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodGet, StatusPath, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	Status(map[string]error{
+		"mileapp":  nil,
+		"midtrans": errors.New("serverKey not found"),
+	}).ServeHTTP(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("Status(), got = %v, want = %v", resp.StatusCode, http.StatusOK)
+	}
+
+	got := &StatusResponse{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	want := &StatusResponse{
+		Status: "degraded",
+		Providers: map[string]ProviderStatus{
+			"mileapp":  {Status: "ready"},
+			"midtrans": {Status: "not configured", Error: "serverKey not found"},
+		},
+	}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("Status(), got = %+v, want = %+v", got, want)
+	}
+}
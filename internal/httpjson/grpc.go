@@ -0,0 +1,50 @@
+package httpjson
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCToHTTP returns the http status of a failed backend call, so e.g. an
+// unavailable backend is answered with http 503 and the providers retry. It
+// is http 500 for the errors without a more specific status.
+func GRPCToHTTP(err error) int {
+	if err == nil {
+		return http.StatusOK
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+
+	// the status may be wrapped, status.Code only checks err itself.
+	var se interface{ GRPCStatus() *status.Status }
+	if !errors.As(err, &se) {
+		return http.StatusInternalServerError
+	}
+
+	switch se.GRPCStatus().Code() {
+	case codes.OK:
+		return http.StatusOK
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	}
+	// Unauthenticated and PermissionDenied are about our own credentials to
+	// the backend, not the provider's, so they stay http 500 with the rest.
+	return http.StatusInternalServerError
+}
@@ -0,0 +1,46 @@
+package httpjson
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCToHTTP(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "Nil", want: http.StatusOK},
+		{name: "InvalidArgument", err: status.Error(codes.InvalidArgument, "invalid"), want: http.StatusBadRequest},
+		{name: "NotFound", err: status.Error(codes.NotFound, "not found"), want: http.StatusNotFound},
+		{name: "AlreadyExists", err: status.Error(codes.AlreadyExists, "exists"), want: http.StatusConflict},
+		{name: "ResourceExhausted", err: status.Error(codes.ResourceExhausted, "exhausted"), want: http.StatusTooManyRequests},
+		{name: "Unavailable", err: status.Error(codes.Unavailable, "unavailable"), want: http.StatusServiceUnavailable},
+		{name: "DeadlineExceeded", err: status.Error(codes.DeadlineExceeded, "deadline"), want: http.StatusGatewayTimeout},
+		{name: "ContextDeadline", err: context.DeadlineExceeded, want: http.StatusGatewayTimeout},
+		{name: "Internal", err: status.Error(codes.Internal, "internal"), want: http.StatusInternalServerError},
+		{name: "Unauthenticated", err: status.Error(codes.Unauthenticated, "unauthenticated"), want: http.StatusInternalServerError},
+		{name: "Wrapped", err: fmt.Errorf("wrapped: %w", status.Error(codes.Unavailable, "unavailable")), want: http.StatusServiceUnavailable},
+		{name: "NotGRPC", err: errors.New("unknown error"), want: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := GRPCToHTTP(test.err); got != test.want {
+				t.Fatalf("GRPCToHTTP(), got = %v, want = %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+// Package httpjson contains the json response helpers shared by the callback
+// handlers, so every provider gets the same {"message": ...} shape.
+package httpjson
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Response is the default response body of the callback handlers.
+type Response struct {
+	Message string `json:"message"`
+}
+
+// Write marshals v and writes it as the response body with code, http 500
+// is written instead when v can't be marshalled. It returns the marshalling
+// or write error.
+func Write(w http.ResponseWriter, code int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+
+	res, err := json.Marshal(v)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return err
+	}
+
+	w.WriteHeader(code)
+
+	_, err = w.Write(res)
+	return err
+}
+
+// WriteError writes msg as the body of a code response.
+func WriteError(w http.ResponseWriter, code int, msg string) error {
+	return Write(w, code, &Response{Message: msg})
+}
+
+// WriteOK writes msg as the body of an http 200 response.
+func WriteOK(w http.ResponseWriter, msg string) error {
+	return Write(w, http.StatusOK, &Response{Message: msg})
+}
+
+// StatusCodes maps the sentinel errors of a package to the http status of
+// their response.
+type StatusCodes map[error]int
+
+// Code returns the status of the error in c matching err with errors.Is,
+// http 500 when none matches.
+func (c StatusCodes) Code(err error) int {
+	for target, code := range c {
+		if errors.Is(err, target) {
+			return code
+		}
+	}
+	return http.StatusInternalServerError
+}
+
+// FailureCode returns the http status shared by the failed calls of a batch,
+// e.g. http 503 when the backend is unavailable, and http 500 when they failed
+// differently. The zero codes are the calls that didn't fail.
+func FailureCode(codes []int) int {
+	code := 0
+	for _, c := range codes {
+		switch {
+		case c == 0:
+		case code == 0:
+			code = c
+		case code != c:
+			return http.StatusInternalServerError
+		}
+	}
+	if code == 0 {
+		return http.StatusInternalServerError
+	}
+	return code
+}
+
+// WriteErr writes err as the body of a response with the status mapped from
+// codes.
+func WriteErr(w http.ResponseWriter, codes StatusCodes, err error) error {
+	return WriteError(w, codes.Code(err), err.Error())
+}
@@ -0,0 +1,142 @@
+package httpjson
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var errTest = errors.New("test error")
+
+func TestWrite(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		write    func(w http.ResponseWriter) error
+		wantCode int
+		want     *Response
+	}{
+		{
+			name:     "OK",
+			write:    func(w http.ResponseWriter) error { return WriteOK(w, "success") },
+			wantCode: http.StatusOK,
+			want:     &Response{Message: "success"},
+		},
+		{
+			name:     "Error",
+			write:    func(w http.ResponseWriter) error { return WriteError(w, http.StatusBadRequest, "invalid request data") },
+			wantCode: http.StatusBadRequest,
+			want:     &Response{Message: "invalid request data"},
+		},
+		{
+			name: "Err",
+			write: func(w http.ResponseWriter) error {
+				return WriteErr(w, StatusCodes{errTest: http.StatusUnauthorized}, errTest)
+			},
+			wantCode: http.StatusUnauthorized,
+			want:     &Response{Message: errTest.Error()},
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			w := httptest.NewRecorder()
+			if err := test.write(w); err != nil {
+				t.Fatal(err)
+			}
+
+			resp := w.Result()
+			if resp.StatusCode != test.wantCode {
+				t.Fatalf("got = %v, want = %v", resp.StatusCode, test.wantCode)
+			}
+			if got := resp.Header.Get("Content-Type"); got != "application/json" {
+				t.Fatalf("Content-Type, got = %v, want = application/json", got)
+			}
+
+			got := &Response{}
+			if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if !cmp.Equal(got, test.want) {
+				t.Fatalf("got = %+v, want = %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestWrite_MarshalError(t *testing.T) {
+	t.Parallel()
+
+	w := httptest.NewRecorder()
+	if err := Write(w, http.StatusOK, func() {}); err == nil {
+		t.Fatal("Write(), got nil error for an unmarshallable value")
+	}
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("Write(), got = %v, want = %v", w.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestStatusCodes(t *testing.T) {
+	t.Parallel()
+
+	errOther := errors.New("other error")
+	codes := StatusCodes{
+		errTest:  http.StatusBadRequest,
+		errOther: http.StatusNotFound,
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want int
+	}{
+		{name: "Sentinel", err: errTest, want: http.StatusBadRequest},
+		{name: "Wrapped", err: fmt.Errorf("wrapped: %w", errOther), want: http.StatusNotFound},
+		{name: "Unknown", err: errors.New("unknown error"), want: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := codes.Code(test.err); got != test.want {
+				t.Fatalf("Code(), got = %v, want = %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestFailureCode(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		codes []int
+		want  int
+	}{
+		{name: "Shared", codes: []int{0, 503, 503}, want: http.StatusServiceUnavailable},
+		{name: "Different", codes: []int{503, 404}, want: http.StatusInternalServerError},
+		{name: "NoFailure", codes: []int{0, 0}, want: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := FailureCode(test.codes); got != test.want {
+				t.Errorf("FailureCode(%v), got = %v, want = %v", test.codes, got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+// Package jsonschema validates the structure of the callback payloads
+// before they are decoded, so a field with the wrong type is reported with
+// its path instead of a generic decode error.
+//
+// Only the subset of JSON schema used by the embedded provider schemas is
+// supported: type, properties, required and items.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidPayload is wrapped by the errors of the payloads not matching
+	// their schema.
+	ErrInvalidPayload = errors.New("payload does not match schema")
+	// ErrUnknownField is wrapped by the errors of the payload fields missing
+	// from the decoded struct, see Decoder.DisallowUnknownFields.
+	ErrUnknownField = errors.New("unknown field")
+)
+
+// unknownFieldPrefix prefixes the encoding/json error of an unknown field.
+const unknownFieldPrefix = "json: unknown field "
+
+// Schema is a parsed JSON schema.
+type Schema struct {
+	// Type is the accepted json types, any type is accepted when empty.
+	Type       Types              `json:"type"`
+	Properties map[string]*Schema `json:"properties"`
+	Required   []string           `json:"required"`
+	Items      *Schema            `json:"items"`
+}
+
+// Types is the type keyword of a schema, either a single type or a list.
+type Types []string
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (t *Types) UnmarshalJSON(b []byte) error {
+	var name string
+	if err := json.Unmarshal(b, &name); err == nil {
+		*t = Types{name}
+		return nil
+	}
+	var names []string
+	if err := json.Unmarshal(b, &names); err != nil {
+		return err
+	}
+	*t = names
+	return nil
+}
+
+// ValidationError is the error of a payload value not matching its schema.
+type ValidationError struct {
+	// Path is the json pointer of the value, e.g. /0/in_stock.
+	Path string
+	Msg  string
+}
+
+func (e *ValidationError) Error() string {
+	return e.Path + ": " + e.Msg
+}
+
+// Unwrap returns ErrInvalidPayload.
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidPayload
+}
+
+// Parse parses a JSON schema.
+func Parse(data []byte) (*Schema, error) {
+	s := &Schema{}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	return s, nil
+}
+
+// MustParse is like Parse but panics on error, it is meant for the schemas
+// embedded in the binary.
+func MustParse(data []byte) *Schema {
+	s, err := Parse(data)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// Validate checks the json document data matches s, it returns a
+// *ValidationError for the first value that doesn't.
+func (s *Schema) Validate(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return err
+	}
+	return s.validate("", v)
+}
+
+// Decoder decodes the request bodies of the callback handlers.
+type Decoder struct {
+	// Schema validates the document before it is decoded, the validation is
+	// skipped when nil.
+	Schema *Schema
+	// DisallowUnknownFields rejects the object fields missing from the
+	// decoded struct instead of ignoring them.
+	DisallowUnknownFields bool
+}
+
+// Decode reads the json document of r, validates it against d.Schema and
+// decodes it into v. An unknown field is returned as an error wrapping
+// ErrUnknownField with the field name.
+func (d Decoder) Decode(r io.Reader, v interface{}) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if d.Schema != nil {
+		if err := d.Schema.Validate(data); err != nil {
+			return err
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if d.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if err := dec.Decode(v); err != nil {
+		// encoding/json has no error type for the unknown fields.
+		if msg := err.Error(); strings.HasPrefix(msg, unknownFieldPrefix) {
+			return fmt.Errorf("%w %s", ErrUnknownField, strings.TrimPrefix(msg, unknownFieldPrefix))
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *Schema) validate(path string, v interface{}) error {
+	got := typeOf(v)
+	if !s.Type.accepts(got, v) {
+		return &ValidationError{
+			Path: pointer(path),
+			Msg:  fmt.Sprintf("expected %s, got %s", strings.Join(s.Type, " or "), got),
+		}
+	}
+
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for _, name := range s.Required {
+			if _, ok := v[name]; !ok {
+				return &ValidationError{Path: pointer(path + "/" + name), Msg: "is required"}
+			}
+		}
+		for name, prop := range s.Properties {
+			value, ok := v[name]
+			if !ok {
+				continue
+			}
+			if err := prop.validate(path+"/"+name, value); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		if s.Items == nil {
+			return nil
+		}
+		for i, item := range v {
+			if err := s.Items.validate(path+"/"+strconv.Itoa(i), item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// accepts reports whether a value of the json type got is accepted, an
+// integer is also a number.
+func (t Types) accepts(got string, v interface{}) bool {
+	if len(t) == 0 {
+		return true
+	}
+	for _, want := range t {
+		switch {
+		case want == got:
+			return true
+		case want == "integer" && got == "number":
+			if _, err := v.(json.Number).Int64(); err == nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// typeOf returns the json type of a value decoded with UseNumber.
+func typeOf(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case json.Number:
+		return "number"
+	case string:
+		return "string"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// pointer returns path as a json pointer, the document root is "/".
+func pointer(path string) string {
+	if path == "" {
+		return "/"
+	}
+	return path
+}
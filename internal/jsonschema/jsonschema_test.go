@@ -0,0 +1,155 @@
+package jsonschema
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+const testSchema = `{
+	"type": "array",
+	"items": {
+		"type": "object",
+		"required": ["location_id"],
+		"properties": {
+			"location_id": {"type": "string"},
+			"in_stock": {"type": ["number", "null"]},
+			"count": {"type": "integer"},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		}
+	}
+}`
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+
+	schema := MustParse([]byte(testSchema))
+
+	tests := []struct {
+		name     string
+		data     string
+		wantPath string
+		wantMsg  string
+	}{
+		{
+			name: "Valid",
+			data: `[{"location_id": "loc-1", "in_stock": 1.5, "count": 2, "tags": ["a"], "unknown": {}}]`,
+		},
+		{
+			name: "NullAllowed",
+			data: `[{"location_id": "loc-1", "in_stock": null}]`,
+		},
+		{
+			name:     "NotArray",
+			data:     `{"location_id": "loc-1"}`,
+			wantPath: "/",
+			wantMsg:  "expected array, got object",
+		},
+		{
+			name:     "WrongType",
+			data:     `[{"location_id": "loc-1"}, {"location_id": "loc-2", "in_stock": "10"}]`,
+			wantPath: "/1/in_stock",
+			wantMsg:  "expected number or null, got string",
+		},
+		{
+			name:     "NotInteger",
+			data:     `[{"location_id": "loc-1", "count": 1.5}]`,
+			wantPath: "/0/count",
+			wantMsg:  "expected integer, got number",
+		},
+		{
+			name:     "Missing",
+			data:     `[{"in_stock": 1}]`,
+			wantPath: "/0/location_id",
+			wantMsg:  "is required",
+		},
+		{
+			name:     "NestedItem",
+			data:     `[{"location_id": "loc-1", "tags": ["a", 1]}]`,
+			wantPath: "/0/tags/1",
+			wantMsg:  "expected string, got number",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := schema.Validate([]byte(test.data))
+			if test.wantPath == "" {
+				if err != nil {
+					t.Fatalf("Validate(), got = %v, want = nil", err)
+				}
+				return
+			}
+
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("Validate(), got = %v, want = *ValidationError", err)
+			}
+			if verr.Path != test.wantPath || verr.Msg != test.wantMsg {
+				t.Fatalf("Validate(), got = %s: %s, want = %s: %s", verr.Path, verr.Msg, test.wantPath, test.wantMsg)
+			}
+			if !errors.Is(err, ErrInvalidPayload) {
+				t.Fatalf("Validate(), got = %v, want = %v", err, ErrInvalidPayload)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	t.Parallel()
+
+	schema := MustParse([]byte(testSchema))
+
+	var got []struct {
+		LocationID string `json:"location_id"`
+	}
+	if err := (Decoder{Schema: schema}).Decode(strings.NewReader(`[{"location_id": "loc-1"}]`), &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].LocationID != "loc-1" {
+		t.Fatalf("Decode(), got = %+v", got)
+	}
+
+	// the validation is skipped without a schema.
+	if err := (Decoder{}).Decode(strings.NewReader(`{"location_id": 1}`), &map[string]interface{}{}); err != nil {
+		t.Fatalf("Decode(), got = %v, want = nil", err)
+	}
+
+	var verr *ValidationError
+	err := (Decoder{Schema: schema}).Decode(strings.NewReader(`[{"location_id": 1}]`), &got)
+	if !errors.As(err, &verr) || verr.Path != "/0/location_id" {
+		t.Fatalf("Decode(), got = %v, want = /0/location_id error", err)
+	}
+}
+
+func TestDecode_DisallowUnknownFields(t *testing.T) {
+	t.Parallel()
+
+	const in = `{"location_id": "loc-1", "locationId": "loc-1"}`
+
+	var got struct {
+		LocationID string `json:"location_id"`
+	}
+	if err := (Decoder{}).Decode(strings.NewReader(in), &got); err != nil {
+		t.Fatalf("Decode(), got = %v, want = nil", err)
+	}
+
+	err := (Decoder{DisallowUnknownFields: true}).Decode(strings.NewReader(in), &got)
+	if !errors.Is(err, ErrUnknownField) {
+		t.Fatalf("Decode(), got = %v, want = %v", err, ErrUnknownField)
+	}
+	if want := `unknown field "locationId"`; err.Error() != want {
+		t.Fatalf("Decode(), got = %q, want = %q", err.Error(), want)
+	}
+}
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	if _, err := Parse([]byte(`{"type": 1}`)); err == nil {
+		t.Fatal("Parse(), got = nil, want = error")
+	}
+}
@@ -0,0 +1,104 @@
+// Package tracing starts the OpenTelemetry spans of the callback handlers.
+// The spans go to the tracer provider configured on each handler, they are
+// no-ops without one.
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName names the tracer of the handlers.
+const instrumentationName = "github.com/dropezy/storefront-backend/http"
+
+// Attribute keys of the handler spans.
+const (
+	ProviderKey   = attribute.Key("provider")
+	OrderIDKey    = attribute.Key("order_id")
+	TaskIDKey     = attribute.Key("task_id")
+	ItemsKey      = attribute.Key("items")
+	StatusCodeKey = attribute.Key("http.status_code")
+	OutcomeKey    = attribute.Key("outcome")
+)
+
+// Outcomes of a handler span, from the response status.
+const (
+	OutcomeSuccess  = "success"
+	OutcomeRejected = "rejected"
+	OutcomeError    = "error"
+)
+
+// Tracer returns the tracer of the handlers from tp, a no-op tracer when tp
+// is nil.
+func Tracer(tp trace.TracerProvider) trace.Tracer {
+	if tp == nil {
+		tp = trace.NewNoopTracerProvider()
+	}
+	return tp.Tracer(instrumentationName)
+}
+
+// Span is the span of a handler request, it records the response status on
+// End.
+type Span struct {
+	trace.Span
+
+	w *statusWriter
+}
+
+// Start starts the span name of a provider handler. The trace parent is the
+// span of the request context, or else the W3C traceparent header sent by the
+// provider. The returned writer and request must be used by the handler so
+// the span gets its outcome and the downstream calls are its children.
+func Start(tracer trace.Tracer, w http.ResponseWriter, r *http.Request, provider, name string) (http.ResponseWriter, *http.Request, *Span) {
+	ctx := r.Context()
+	if !trace.SpanContextFromContext(ctx).IsValid() {
+		ctx = propagation.TraceContext{}.Extract(ctx, propagation.HeaderCarrier(r.Header))
+	}
+
+	ctx, span := tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(ProviderKey.String(provider)),
+	)
+	sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+	return sw, r.WithContext(ctx), &Span{Span: span, w: sw}
+}
+
+// End records the outcome of the response and ends the span. The span status
+// is an error for the http 5xx responses only, the rejected payloads are the
+// provider's fault.
+func (s *Span) End(options ...trace.SpanEndOption) {
+	code := s.w.status
+	outcome := OutcomeSuccess
+	switch {
+	case code >= http.StatusInternalServerError:
+		outcome = OutcomeError
+		s.SetStatus(codes.Error, http.StatusText(code))
+	case code >= http.StatusBadRequest:
+		outcome = OutcomeRejected
+	}
+	s.SetAttributes(StatusCodeKey.Int(code), OutcomeKey.String(outcome))
+	s.Span.End(options...)
+}
+
+// statusWriter records the status code written by the handler.
+type statusWriter struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	w.status = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Flush implements http.Flusher when the wrapped writer does.
+func (w *statusWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
@@ -0,0 +1,87 @@
+package tracing
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/dropezy/storefront-backend/http/internal/tracing/tracingtest"
+)
+
+func TestStart(t *testing.T) {
+	t.Parallel()
+
+	const traceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+	tests := []struct {
+		name        string
+		traceParent string
+		code        int
+		wantOutcome string
+		wantStatus  codes.Code
+	}{
+		{name: "Success", code: http.StatusOK, wantOutcome: OutcomeSuccess},
+		{name: "TraceParent", traceParent: traceParent, code: http.StatusOK, wantOutcome: OutcomeSuccess},
+		{name: "Rejected", code: http.StatusBadRequest, wantOutcome: OutcomeRejected},
+		{name: "Error", code: http.StatusServiceUnavailable, wantOutcome: OutcomeError, wantStatus: codes.Error},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			tp := &tracingtest.Provider{}
+			r, err := http.NewRequest(http.MethodPost, "/midtrans/transaction-update", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.traceParent != "" {
+				r.Header.Set("traceparent", test.traceParent)
+			}
+
+			w, r, span := Start(Tracer(tp), httptest.NewRecorder(), r, "midtrans", "midtrans.HandleTransactionUpdate")
+			if got := trace.SpanFromContext(r.Context()).SpanContext(); !got.Equal(span.SpanContext()) {
+				t.Fatalf("Start() request span, got = %v, want = %v", got, span.SpanContext())
+			}
+			w.WriteHeader(test.code)
+			span.End()
+
+			ended := tp.Ended()
+			if len(ended) != 1 {
+				t.Fatalf("Start() ended spans, got = %d, want = 1", len(ended))
+			}
+			got := ended[0]
+			if test.traceParent != "" && got.Parent.TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Fatalf("Start() parent, got = %v, want = traceparent trace ID", got.Parent.TraceID())
+			}
+			if got.Attributes[ProviderKey].AsString() != "midtrans" {
+				t.Fatalf("Start() provider, got = %v, want = midtrans", got.Attributes[ProviderKey].AsString())
+			}
+			if got.Attributes[StatusCodeKey].AsInt64() != int64(test.code) {
+				t.Fatalf("End() status code, got = %v, want = %v", got.Attributes[StatusCodeKey].AsInt64(), test.code)
+			}
+			if got.Attributes[OutcomeKey].AsString() != test.wantOutcome {
+				t.Fatalf("End() outcome, got = %v, want = %v", got.Attributes[OutcomeKey].AsString(), test.wantOutcome)
+			}
+			if got.Status != test.wantStatus {
+				t.Fatalf("End() status, got = %v, want = %v", got.Status, test.wantStatus)
+			}
+		})
+	}
+}
+
+func TestTracer_Nil(t *testing.T) {
+	t.Parallel()
+
+	// without a tracer provider the spans are no-ops.
+	r := httptest.NewRequest(http.MethodPost, "/shoptree/stock-update", nil)
+	_, _, span := Start(Tracer(nil), httptest.NewRecorder(), r, "shoptree", "shoptree.HandleStockUpdate")
+	if span.IsRecording() {
+		t.Fatal("Start() recording, got = true, want = false")
+	}
+	span.End()
+}
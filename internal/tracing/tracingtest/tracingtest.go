@@ -0,0 +1,90 @@
+// Package tracingtest provides a tracer provider recording the handler spans
+// in the tests, without an OpenTelemetry SDK.
+package tracingtest
+
+import (
+	"context"
+	"crypto/rand"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Provider is a trace.TracerProvider recording the ended spans.
+type Provider struct {
+	mu    sync.Mutex
+	ended []*Span
+}
+
+// Tracer implements trace.TracerProvider.
+func (p *Provider) Tracer(string, ...trace.TracerOption) trace.Tracer {
+	return &tracer{p: p}
+}
+
+// Ended returns the ended spans, in end order.
+func (p *Provider) Ended() []*Span {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return append([]*Span(nil), p.ended...)
+}
+
+type tracer struct {
+	p *Provider
+}
+
+func (t *tracer) Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	cfg := trace.NewSpanStartConfig(opts...)
+	parent := trace.SpanContextFromContext(ctx)
+
+	scc := trace.SpanContextConfig{TraceID: parent.TraceID(), TraceFlags: trace.FlagsSampled}
+	if !scc.TraceID.IsValid() {
+		rand.Read(scc.TraceID[:])
+	}
+	rand.Read(scc.SpanID[:])
+
+	s := &Span{
+		p:          t.p,
+		Name:       name,
+		Parent:     parent,
+		Attributes: map[attribute.Key]attribute.Value{},
+		sc:         trace.NewSpanContext(scc),
+	}
+	s.SetAttributes(cfg.Attributes()...)
+	return trace.ContextWithSpan(ctx, s), s
+}
+
+// Span is a recorded span.
+type Span struct {
+	p *Provider
+
+	Name       string
+	Parent     trace.SpanContext
+	Attributes map[attribute.Key]attribute.Value
+	Status     codes.Code
+
+	sc trace.SpanContext
+}
+
+func (s *Span) End(...trace.SpanEndOption) {
+	s.p.mu.Lock()
+	defer s.p.mu.Unlock()
+
+	s.p.ended = append(s.p.ended, s)
+}
+
+func (s *Span) SetAttributes(kv ...attribute.KeyValue) {
+	for _, a := range kv {
+		s.Attributes[a.Key] = a.Value
+	}
+}
+
+func (s *Span) SetStatus(code codes.Code, _ string)     { s.Status = code }
+func (s *Span) SpanContext() trace.SpanContext          { return s.sc }
+func (s *Span) IsRecording() bool                       { return true }
+func (s *Span) RecordError(error, ...trace.EventOption) {}
+func (s *Span) AddEvent(string, ...trace.EventOption)   {}
+func (s *Span) SetName(name string)                     { s.Name = name }
+func (s *Span) TracerProvider() trace.TracerProvider    { return s.p }
@@ -0,0 +1,148 @@
+// Package validate contains the request validation shared by the callback
+// handlers.
+package validate
+
+import (
+	"crypto/subtle"
+	"errors"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Media types accepted by the callback routes.
+const (
+	MediaTypeJSON   = "application/json"
+	MediaTypeCSV    = "text/csv"
+	MediaTypeNDJSON = "application/x-ndjson"
+)
+
+var (
+	ErrContentTypeIsRequired = errors.New("content type is required")
+	ErrInvalidContentType    = errors.New("invalid content type")
+	ErrAPIKeyIsRequired      = errors.New("api key is required")
+	ErrInvalidAPIKey         = errors.New("invalid api key")
+)
+
+type options struct {
+	mediaTypes   []string
+	apiKeyHeader string
+	apiKeys      []string
+}
+
+// Option configures the checks of Headers.
+type Option func(*options)
+
+// APIKey checks the header named header holds one of keys, several keys are
+// accepted while rotating them. The empty keys are ignored.
+func APIKey(header string, keys ...string) Option {
+	return func(o *options) {
+		o.apiKeyHeader = header
+		o.apiKeys = o.apiKeys[:0]
+		for _, key := range keys {
+			if key != "" {
+				o.apiKeys = append(o.apiKeys, key)
+			}
+		}
+	}
+}
+
+// MediaTypes sets the media types of the content type checked by Headers,
+// so the upload routes can accept their own while the JSON routes stay
+// strict. It is MediaTypeJSON by default.
+func MediaTypes(mediaTypes ...string) Option {
+	return func(o *options) {
+		o.mediaTypes = mediaTypes
+	}
+}
+
+// Headers checks the request headers, the content type must be one of the
+// MediaTypes, application/json by default, and the api key header, when
+// configured, must match. Media type parameters such as charset are ignored.
+func Headers(h http.Header, opts ...Option) error {
+	o := &options{mediaTypes: []string{MediaTypeJSON}}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if err := ContentType(h, o.mediaTypes...); err != nil {
+		return err
+	}
+
+	if o.apiKeyHeader == "" {
+		return nil
+	}
+	return CheckAPIKey(h, o.apiKeyHeader, o.apiKeys...)
+}
+
+// ContentType checks the content type is one of mediaTypes, without the api
+// key check of Headers. Media type parameters such as charset are ignored.
+func ContentType(h http.Header, mediaTypes ...string) error {
+	ct := h.Get("Content-Type")
+	if ct == "" {
+		return ErrContentTypeIsRequired
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	if err != nil || !contains(mediaTypes, mediaType) {
+		return ErrInvalidContentType
+	}
+	return nil
+}
+
+// Accepts reports whether one of the media ranges of the Accept header values
+// matches mediaType with a non zero quality, e.g. "application/*" matches
+// MediaTypeJSON. The malformed ranges are ignored.
+func Accepts(values []string, mediaType string) bool {
+	wildcard := strings.SplitN(mediaType, "/", 2)[0] + "/*"
+	for _, v := range values {
+		for _, mediaRange := range strings.Split(v, ",") {
+			accepted, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+			if err != nil {
+				continue
+			}
+			if q, ok := params["q"]; ok {
+				if f, err := strconv.ParseFloat(q, 64); err != nil || f <= 0 {
+					continue
+				}
+			}
+
+			switch accepted {
+			case mediaType, wildcard, "*/*":
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// CheckAPIKey checks the header named header holds one of keys, without the
+// content type check of Headers. The empty keys are ignored.
+func CheckAPIKey(h http.Header, header string, keys ...string) error {
+	apiKey := h.Get(header)
+	if apiKey == "" {
+		return ErrAPIKeyIsRequired
+	}
+	// compare in constant time so the key can't be guessed from timings,
+	// every key is compared for the same reason.
+	match := 0
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		match |= subtle.ConstantTimeCompare([]byte(apiKey), []byte(key))
+	}
+	if match != 1 {
+		return ErrInvalidAPIKey
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
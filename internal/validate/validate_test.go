@@ -0,0 +1,168 @@
+package validate
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestHeaders(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header http.Header
+		opts   []Option
+		want   error
+	}{
+		{
+			name:   "Valid",
+			header: http.Header{"Content-Type": {"application/json"}},
+		},
+		{
+			name:   "MissingContentType",
+			header: http.Header{},
+			want:   ErrContentTypeIsRequired,
+		},
+		{
+			name:   "InvalidContentType",
+			header: http.Header{"Content-Type": {"text/plain"}},
+			want:   ErrInvalidContentType,
+		},
+		{
+			name:   "JSONWithCharset",
+			header: http.Header{"Content-Type": {"application/json; charset=utf-8"}},
+		},
+		{
+			name:   "MalformedContentType",
+			header: http.Header{"Content-Type": {"application/json; ="}},
+			want:   ErrInvalidContentType,
+		},
+		{
+			name:   "JSONRejectsCSV",
+			header: http.Header{"Content-Type": {"text/csv"}},
+			want:   ErrInvalidContentType,
+		},
+		{
+			name:   "CSVAcceptsCSV",
+			header: http.Header{"Content-Type": {"text/csv"}},
+			opts:   []Option{MediaTypes(MediaTypeCSV)},
+		},
+		{
+			name:   "CSVRejectsJSON",
+			header: http.Header{"Content-Type": {"application/json"}},
+			opts:   []Option{MediaTypes(MediaTypeCSV)},
+			want:   ErrInvalidContentType,
+		},
+		{
+			name:   "NDJSONAcceptsNDJSON",
+			header: http.Header{"Content-Type": {"application/x-ndjson"}},
+			opts:   []Option{MediaTypes(MediaTypeNDJSON)},
+		},
+		{
+			name:   "ValidAPIKey",
+			header: http.Header{"Content-Type": {"application/json"}, "X-Api-Key": {"secret"}},
+			opts:   []Option{APIKey("x-api-key", "secret")},
+		},
+		{
+			name:   "RotatedAPIKey",
+			header: http.Header{"Content-Type": {"application/json"}, "X-Api-Key": {"old-secret"}},
+			opts:   []Option{APIKey("X-Api-Key", "secret", "old-secret")},
+		},
+		{
+			name:   "EmptyAPIKeys",
+			header: http.Header{"Content-Type": {"application/json"}, "X-Api-Key": {"secret"}},
+			opts:   []Option{APIKey("X-Api-Key", "")},
+			want:   ErrInvalidAPIKey,
+		},
+		{
+			name:   "MissingAPIKey",
+			header: http.Header{"Content-Type": {"application/json"}},
+			opts:   []Option{APIKey("X-Api-Key", "secret")},
+			want:   ErrAPIKeyIsRequired,
+		},
+		{
+			name:   "InvalidAPIKey",
+			header: http.Header{"Content-Type": {"application/json"}, "X-Api-Key": {"secreT"}},
+			opts:   []Option{APIKey("X-Api-Key", "secret")},
+			want:   ErrInvalidAPIKey,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Headers(test.header, test.opts...); !errors.Is(got, test.want) {
+				t.Fatalf("Headers(), got = %v, want = %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestCheckAPIKey(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header http.Header
+		want   error
+	}{
+		{
+			// the content type is not checked.
+			name:   "Valid",
+			header: http.Header{"X-Api-Key": {"old-secret"}},
+		},
+		{
+			name:   "Missing",
+			header: http.Header{"Content-Type": {"application/json"}},
+			want:   ErrAPIKeyIsRequired,
+		},
+		{
+			name:   "Invalid",
+			header: http.Header{"X-Api-Key": {"other"}},
+			want:   ErrInvalidAPIKey,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := CheckAPIKey(test.header, "X-Api-Key", "secret", "", "old-secret"); !errors.Is(got, test.want) {
+				t.Fatalf("CheckAPIKey(), got = %v, want = %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestAccepts(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		values    []string
+		mediaType string
+		want      bool
+	}{
+		{name: "Exact", values: []string{"text/csv"}, mediaType: MediaTypeCSV, want: true},
+		{name: "TypeWildcard", values: []string{"text/*"}, mediaType: MediaTypeCSV, want: true},
+		{name: "OtherTypeWildcard", values: []string{"application/*"}, mediaType: MediaTypeCSV},
+		{name: "Wildcard", values: []string{"*/*"}, mediaType: MediaTypeNDJSON, want: true},
+		{name: "ZeroQuality", values: []string{"application/json;q=0"}, mediaType: MediaTypeJSON},
+		{name: "Malformed", values: []string{"application/json; ="}, mediaType: MediaTypeJSON},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			if got := Accepts(test.values, test.mediaType); got != test.want {
+				t.Fatalf("Accepts(), got = %v, want = %v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,59 @@
+// Package jobs keeps the results of bulk jobs so they can be downloaded
+// after the job finished.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrJobNotFound happens when no results are stored for a job id.
+var ErrJobNotFound = errors.New("job not found")
+
+// ResultStore gives access to the results of bulk jobs.
+type ResultStore interface {
+	// Results calls fn for every result of job id in order, it stops at the
+	// first error returned by fn. ErrJobNotFound is returned when the job is
+	// unknown.
+	Results(ctx context.Context, id string, fn func(result interface{}) error) error
+}
+
+// MemoryStore is an in-memory ResultStore.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	results map[string][]interface{}
+}
+
+// NewMemoryStore returns a new empty in-memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{results: make(map[string][]interface{})}
+}
+
+// Add appends results to job id.
+func (s *MemoryStore) Add(id string, results ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.results[id] = append(s.results[id], results...)
+}
+
+// Results implements ResultStore.
+func (s *MemoryStore) Results(ctx context.Context, id string, fn func(result interface{}) error) error {
+	s.mu.RLock()
+	results, ok := s.results[id]
+	s.mu.RUnlock()
+	if !ok {
+		return ErrJobNotFound
+	}
+
+	for _, res := range results {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(res); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -6,24 +6,42 @@ import (
 	"log"
 	"net"
 	"net/http"
+	"net/http/pprof"
 	"os"
 	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
 	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/kenshaw/envcfg"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc/metadata"
-	grpctrace "gopkg.in/DataDog/dd-trace-go.v1/contrib/google.golang.org/grpc"
 	"gopkg.in/DataDog/dd-trace-go.v1/ddtrace/tracer"
 	"gopkg.in/DataDog/dd-trace-go.v1/profiler"
 
 	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/admin"
+	"github.com/dropezy/storefront-backend/http/alert"
+	"github.com/dropezy/storefront-backend/http/breaker"
 	"github.com/dropezy/storefront-backend/http/callback/midtrans"
 	"github.com/dropezy/storefront-backend/http/callback/mileapp"
 	"github.com/dropezy/storefront-backend/http/callback/shoptree"
+	"github.com/dropezy/storefront-backend/http/clients"
+	"github.com/dropezy/storefront-backend/http/dedup"
+	"github.com/dropezy/storefront-backend/http/errorlog"
+	"github.com/dropezy/storefront-backend/http/health"
+	"github.com/dropezy/storefront-backend/http/internal/validate"
+	"github.com/dropezy/storefront-backend/http/jobs"
+	"github.com/dropezy/storefront-backend/http/middleware"
+	"github.com/dropezy/storefront-backend/http/payload"
+	"github.com/dropezy/storefront-backend/http/selftest"
+	"github.com/dropezy/storefront-backend/http/throughput"
 
 	// protobuf
 
@@ -34,15 +52,21 @@ import (
 
 const service = "http-server"
 
+// defaultShutdownTimeout is used when server.shutdownTimeout is not set.
+const defaultShutdownTimeout = 10 * time.Second
+
 var (
 	version     = "development"
+	commit      = "unknown"
 	environment = "development"
 
 	config *envcfg.Envcfg
 	logger zerolog.Logger
 )
 
-func init() {
+// setup loads the config and the logger, it is called first by main rather
+// than in init so the tests load their own config.
+func setup() {
 	log.SetFlags(log.LstdFlags | log.Lshortfile)
 
 	var err error
@@ -72,12 +96,21 @@ func init() {
 		Level(logLevel).With().
 		Str("service-name", service).
 		Str("version", version).
+		Str("commit", commit).
 		Logger()
 }
 
 func main() {
+	setup()
 	logger := logger.With().Str("func", "main").Logger()
 
+	if errs := validateConfig(config); len(errs) > 0 {
+		for _, err := range errs {
+			logger.Error().Err(err).Send()
+		}
+		logger.Fatal().Int("errors", len(errs)).Msg("invalid config")
+	}
+
 	if environment == "production" {
 		// TODO(vishen): move datadog tracing and profile stuff to an importable package
 		// Start datadog APM
@@ -107,44 +140,66 @@ func main() {
 	}
 
 	// GRPC client
-	opts := []grpc.DialOption{
-		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithChainUnaryInterceptor(
-			grpctrace.UnaryClientInterceptor(grpctrace.WithServiceName(service)),
-			storefrontAuthInterceptor,
-		),
+	backendBreaker := breaker.New(
+		config.GetInt("grpc.breakerThreshold"),
+		config.GetDuration("grpc.breakerCooldown"),
+	)
+	if insecureGRPC() {
+		logger.Warn().Str("environment", environment).Msg("grpc tls is disabled, the backend connection is insecure")
 	}
-
-	// The server address in the format of host:port
-	conn, err := grpc.Dial(config.GetString("grpc.addr"), opts...)
+	backend, err := clients.New(context.Background(), clients.Config{
+		// The server addresses in the format of host:port, comma separated.
+		Addr:        config.GetString("grpc.addr"),
+		AuthKey:     config.GetString("storefront-api.authKey"),
+		ServiceName: service,
+		TLS: clients.TLSConfig{
+			Enabled:    config.GetBool("grpc.tls.enabled"),
+			CAFile:     config.GetString("grpc.tls.caFile"),
+			ServerName: config.GetString("grpc.tls.serverName"),
+		},
+		RetryAttempts:    config.GetInt("grpc.retryAttempts"),
+		RetryBackoff:     config.GetDuration("grpc.retryBackoff"),
+		KeepaliveTime:    config.GetDuration("grpc.keepalive.time"),
+		KeepaliveTimeout: config.GetDuration("grpc.keepalive.timeout"),
+		Breaker:          backendBreaker,
+	})
 	if err != nil {
 		logger.Fatal().Msgf("fail to dial: %v", err)
 	}
-	defer conn.Close()
+	defer backend.Close()
 
 	var (
-		orderClient     = opb.NewOrderServiceClient(conn)
-		taskClient      = tpb.NewTaskServiceClient(conn)
-		inventoryClient = inpb.NewInventoryServiceClient(conn)
+		conn            = backend.Conn
+		orderClient     = backend.Order
+		taskClient      = backend.Task
+		inventoryClient = backend.Inventory
 	)
 
-	addr := net.JoinHostPort("", config.GetString("server.port"))
+	// inFlight tracks the requests being handled, so shutdown waits for the
+	// handlers to finish their downstream calls.
+	var inFlight sync.WaitGroup
+
+	// listens on all the interfaces when server.host is empty.
+	addr := net.JoinHostPort(config.GetString("server.host"), config.GetString("server.port"))
 	srv := &http.Server{
 		Addr:         addr,
-		Handler:      registerHandler(orderClient, taskClient, inventoryClient),
+		Handler:      registerHandler(&inFlight, backendBreaker, conn, orderClient, taskClient, inventoryClient),
 		ReadTimeout:  config.GetDuration("server.readTimeout"),
 		IdleTimeout:  config.GetDuration("server.idleTimeout"),
 		WriteTimeout: config.GetDuration("server.writeTimeout"),
 	}
 
 	idleConnsClosed := make(chan struct{})
-	// watch for os.Interrupt signal and gracefully shutdown
-	// the server.
+	// watch for os.Interrupt and SIGTERM (sent by kubernetes on pod
+	// termination) signals and gracefully shutdown the server.
 	go func() {
-		const shutdownTimeout = 10 * time.Second
+		shutdownTimeout := config.GetDuration("server.shutdownTimeout")
+		if shutdownTimeout <= 0 {
+			shutdownTimeout = defaultShutdownTimeout
+		}
 
 		sigint := make(chan os.Signal, 1)
-		signal.Notify(sigint, os.Interrupt)
+		signal.Notify(sigint, os.Interrupt, syscall.SIGTERM)
 		<-sigint
 
 		ctx, cancel := context.WithTimeout(
@@ -156,6 +211,9 @@ func main() {
 		if err := srv.Shutdown(ctx); err != nil {
 			logger.Fatal().Err(err).Msg("HTTP server shutdown")
 		}
+		if err := middleware.Wait(ctx, &inFlight); err != nil {
+			logger.Err(err).Msg("in-flight requests not drained before shutdown timeout")
+		}
 		logger.Info().Msg("HTTP server shutdown")
 		close(idleConnsClosed)
 	}()
@@ -169,13 +227,111 @@ func main() {
 	logger.Info().Msg("server exited gracefully")
 }
 
+// requiredConfigKeys are the config keys that must not be empty. The keys of
+// the callback providers are checked in registerHandler, a provider missing
+// them is reported on /status and the others are still served.
+var requiredConfigKeys = []string{
+	"server.port",
+	"grpc.addr",
+	"storefront-api.authKey",
+	"admin.authKey",
+}
+
+// positiveDurationKeys are the config keys that must be positive durations.
+var positiveDurationKeys = []string{
+	"server.readTimeout",
+	"server.idleTimeout",
+	"server.writeTimeout",
+}
+
+// validateConfig returns an error for each missing or invalid config key,
+// so a bad deploy fails at startup instead of on the first callbacks.
+func validateConfig(config *envcfg.Envcfg) []error {
+	var errs []error
+	for _, key := range requiredConfigKeys {
+		if config.GetString(key) == "" {
+			errs = append(errs, fmt.Errorf("config key %s is required", key))
+		}
+	}
+	for _, key := range positiveDurationKeys {
+		if config.GetDuration(key) <= 0 {
+			errs = append(errs, fmt.Errorf("config key %s must be a positive duration, got %q", key, config.GetString(key)))
+		}
+	}
+	return errs
+}
+
+// requireConfig returns an error for the first of keys that is empty.
+func requireConfig(keys ...string) error {
+	for _, key := range keys {
+		if config.GetString(key) == "" {
+			return fmt.Errorf("config key %s is required", key)
+		}
+	}
+	return nil
+}
+
 func registerHandler(
+	inFlight *sync.WaitGroup,
+	backendBreaker *breaker.Breaker,
+	conn *grpc.ClientConn,
 	orderClient opb.OrderServiceClient,
 	taskClient tpb.TaskServiceClient,
 	inventoryClient inpb.InventoryServiceClient,
 ) http.Handler {
 	router := mux.NewRouter()
 
+	// maxBodyBytes caps the callback request bodies, bigger ones get http 413.
+	maxBodyBytes := config.GetInt64("server.maxBodyBytes", 10, 64)
+	if maxBodyBytes <= 0 {
+		maxBodyBytes = middleware.DefaultMaxBodyBytes
+	}
+
+	// payloadSink keeps the raw callback payloads for audit and replay.
+	// payloadLoader loads them back on /admin/replay, it is nil when they
+	// are not stored.
+	var payloadSink payload.Sink = payload.NopSink{}
+	var payloadLoader payload.Loader
+	if dir := config.GetString("payload.dir"); dir != "" {
+		fileSink := payload.NewFileSink(dir)
+		payloadSink, payloadLoader = fileSink, fileSink
+	}
+
+	// dedupStore keeps the keys of already processed callbacks.
+	dedupStore := dedup.NewMemoryStore()
+	// jobStore keeps the results of bulk jobs.
+	jobStore := jobs.NewMemoryStore()
+
+	// alertTracker notifies on-call when a handler keeps failing.
+	var alerter alert.Alerter = alert.Nop{}
+	if url := config.GetString("alert.webhookURL"); url != "" {
+		alerter = alert.NewWebhook(url)
+	}
+	alertTracker := alert.NewTracker(alerter,
+		config.GetInt("alert.threshold"),
+		config.GetDuration("alert.window"),
+	)
+
+	// metrics of the callback handlers, exposed on /metrics
+	metrics := middleware.NewMetrics(prometheus.DefaultRegisterer)
+	router.Handle("/metrics", promhttp.Handler())
+	// pprof profiles outside of production, where the datadog profiler runs
+	// instead. They must never be exposed in production.
+	switch environment {
+	case "staging", "development":
+		registerPprof(router)
+	}
+	// webhooks received per provider, reported on /admin/throughput
+	throughputCounter := throughput.NewCounter()
+	// last errors logged per provider, reported on /admin/errors
+	errorRecorder := errorlog.NewRecorder(config.GetInt("admin.errorsSize"))
+	// OpenTelemetry spans of the callback handlers, sent to the global tracer
+	// provider registered by the SDK when enabled and no-ops otherwise.
+	var tracerProvider trace.TracerProvider = trace.NewNoopTracerProvider()
+	if config.GetBool("otel.enabled") {
+		tracerProvider = otel.GetTracerProvider()
+	}
+
 	// Add default handler as fallback
 	router.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
 		_, err := w.Write(
@@ -186,39 +342,401 @@ func registerHandler(
 		}
 	})
 
+	// Liveness probe, it must not call any downstream service.
+	router.HandleFunc(health.LivenessPath, health.Liveness(service, version))
+	// Readiness probe, checks the upstream gRPC connection is usable.
+	router.HandleFunc(health.ReadinessPath, health.Readiness(conn))
+	// Build info, checked by the deploy tooling.
+	router.HandleFunc(health.VersionPath, health.Version(health.Build{
+		Service:     service,
+		Version:     version,
+		Environment: environment,
+		Commit:      commit,
+	}))
+
+	// providers are the errors returned while initializing the callback
+	// handlers, reported on /status. A provider that failed is logged and
+	// its routes are not registered instead of taking the server down.
+	providers := make(map[string]error, 3)
+
 	// MileApp handlers
-	mileappHandlers := mileapp.NewMileappHandlers(
-		config.GetString("mileapp.authKey"), taskClient,
+	mileappRoutes, routesErr := handlerRouteConfig(mileapp.HandlerName)
+	mileappStatusStates, statesErr := mileapp.ParseStatusStates(config.GetString("mileapp.statusStates"))
+	mileappTaskTypes, typesErr := mileapp.ParseTaskTypes(config.GetString("mileapp.taskTypes"))
+	// mileappOpts are shared by the live and the self-test handlers, so the
+	// self-test checks the configured behavior.
+	mileappOpts := []mileapp.Option{
+		mileapp.WithStatusStates(mileappStatusStates),
+		mileapp.WithTaskTypes(mileappTaskTypes),
+		mileapp.WithCallTimeout(config.GetDuration("mileapp.callTimeout")),
+		mileapp.WithAuthKeys(splitList(config.GetString("mileapp.previousAuthKeys"))...),
+		mileapp.WithSchemaValidation(config.GetBool("mileapp.schemaValidation")),
+		mileapp.WithDisallowUnknownFields(config.GetBool("mileapp.disallowUnknownFields")),
+		mileapp.WithTracerProvider(tracerProvider),
+	}
+	mileappHandlers := mileapp.NewMileappHandlers(config.GetString("mileapp.authKey"), taskClient, mileappOpts...)
+	providers[mileapp.HandlerName] = firstError(
+		requireConfig("mileapp.authKey"),
+		routesErr,
+		configError("mileapp.statusStates", statesErr),
+		configError("mileapp.taskTypes", typesErr),
 	)
-	mileappRouter := router.PathPrefix("/mileapp").Subrouter()
-	mileappRouter.HandleFunc("/status/{task-type}", mileappHandlers.HandleStatusUpdate)
+	if err := providers[mileapp.HandlerName]; err != nil {
+		logger.Error().Err(err).Msg("failed to initialize mileapp handler")
+	} else {
+		mileappRouter := router.PathPrefix("/mileapp").Subrouter()
+		mileappRouter.Use(
+			middleware.LogLevel(mileappRoutes.logLevel),
+			metrics.Middleware(mileapp.HandlerName),
+			throughputCounter.Middleware(mileapp.HandlerName),
+			errorRecorder.Middleware(mileapp.HandlerName),
+			middleware.AllowCIDRs(mileappRoutes.allowedCIDRs),
+			handlerAcceptJSON(mileapp.HandlerName),
+			middleware.ContentType(validate.MediaTypeJSON),
+			middleware.RateLimit(config.GetFloat("mileapp.rateLimit", 64), config.GetInt("mileapp.rateBurst")),
+			backendBreaker.Middleware,
+			middleware.Alert(alertTracker, mileapp.HandlerName),
+			middleware.BodyReadTimeout(config.GetDuration("mileapp.bodyReadTimeout")),
+			middleware.BufferBody(maxBodyBytes),
+			middleware.Verify(mileappHandlers.Verifier()),
+			payload.Middleware(payloadSink, mileapp.HandlerName),
+		)
+		mileappRouter.HandleFunc("/status/{task-type}", mileappHandlers.HandleStatusUpdate)
+		mileappRouter.HandleFunc("/status/{task-type}/batch", mileappHandlers.HandleBatchStatusUpdate)
+	}
 
 	// Shoptree handlers
-	shoptreeHandlers, err := shoptree.NewHandler(
-		config.GetString("shoptree.authKey"), inventoryClient,
+	shoptreeRoutes, routesErr := handlerRouteConfig(shoptree.HandlerName)
+	shoptreeStockRounding, roundingErr := shoptree.ParseStockRounding(config.GetString("shoptree.stockRounding"))
+	shoptreeDuplicatePolicy, policyErr := shoptree.ParseDuplicatePolicy(config.GetString("shoptree.duplicatePolicy"))
+	// shoptreeOpts are shared by the live and the self-test handlers, so the
+	// self-test checks the configured behavior.
+	shoptreeOpts := []shoptree.Option{
+		shoptree.WithAuthKeys(splitList(config.GetString("shoptree.previousAuthKeys"))...),
+		shoptree.WithCallTimeout(config.GetDuration("shoptree.callTimeout")),
+		shoptree.WithStockUpdateConcurrency(config.GetInt("shoptree.stockUpdateConcurrency")),
+		shoptree.WithStatusUpdateConcurrency(config.GetInt("shoptree.statusUpdateConcurrency")),
+		shoptree.WithStockRounding(shoptreeStockRounding),
+		shoptree.WithDuplicatePolicy(shoptreeDuplicatePolicy),
+		shoptree.WithDryRun(config.GetBool("shoptree.dryRun")),
+		shoptree.WithSchemaValidation(config.GetBool("shoptree.schemaValidation")),
+		shoptree.WithDisallowUnknownFields(config.GetBool("shoptree.disallowUnknownFields")),
+		shoptree.WithTracerProvider(tracerProvider),
+		shoptree.WithIdempotency(
+			config.GetInt("shoptree.idempotencySize"),
+			config.GetDuration("shoptree.idempotencyTTL"),
+		),
+	}
+	shoptreeHandlers, err := shoptree.NewHandler(config.GetString("shoptree.authKey"), inventoryClient, shoptreeOpts...)
+	providers[shoptree.HandlerName] = firstError(
+		routesErr,
+		configError("shoptree.stockRounding", roundingErr),
+		configError("shoptree.duplicatePolicy", policyErr),
+		err,
 	)
-	if err != nil {
-		logger.Fatal().Err(err).Msg("failed to initialize shoptree handler")
+	if err := providers[shoptree.HandlerName]; err != nil {
+		logger.Error().Err(err).Msg("failed to initialize shoptree handler")
+	} else {
+		shoptreeRouter := router.PathPrefix("/shoptree").Subrouter()
+		shoptreeRouter.Use(
+			middleware.LogLevel(shoptreeRoutes.logLevel),
+			metrics.Middleware(shoptree.HandlerName),
+			throughputCounter.Middleware(shoptree.HandlerName),
+			errorRecorder.Middleware(shoptree.HandlerName),
+			middleware.AllowCIDRs(shoptreeRoutes.allowedCIDRs),
+			handlerAcceptJSON(shoptree.HandlerName),
+			middleware.ContentType(validate.MediaTypeJSON),
+			middleware.RateLimit(config.GetFloat("shoptree.rateLimit", 64), config.GetInt("shoptree.rateBurst")),
+			backendBreaker.Middleware,
+			middleware.Alert(alertTracker, shoptree.HandlerName),
+			middleware.BodyReadTimeout(config.GetDuration("shoptree.bodyReadTimeout")),
+			middleware.BufferBody(maxBodyBytes),
+			middleware.Verify(shoptreeHandlers.Verifier()),
+			payload.Middleware(payloadSink, shoptree.HandlerName),
+		)
+		shoptreeRouter.HandleFunc("/stock-update", shoptreeHandlers.HandleStockUpdate)
+		shoptreeRouter.HandleFunc("/product-status-update", shoptreeHandlers.HandleProductStatusUpdate)
 	}
-	shoptreeRouter := router.PathPrefix("/shoptree").Subrouter()
-	shoptreeRouter.HandleFunc("/stock-update", shoptreeHandlers.HandleStockUpdate)
-	shoptreeRouter.HandleFunc("/product-status-update", shoptreeHandlers.HandleProductStatusUpdate)
 
 	// Midtrans handlers
+	midtransRoutes, routesErr := handlerRouteConfig(midtrans.HandlerName)
+	midtransForbiddenStates, forbiddenErr := midtrans.ParseOrderStates(config.GetString("midtrans.forbiddenOrderStates"))
+	midtransForbiddenRefundStates, refundErr := midtrans.ParseOrderStates(config.GetString("midtrans.forbiddenRefundOrderStates"))
+	// the get status calls are made by the transaction package unless the
+	// configurable client is enabled.
+	var midtransHTTPClient *http.Client
+	if config.GetBool("midtrans.httpClientEnabled") {
+		midtransHTTPClient = midtrans.NewHTTPClient(midtrans.ClientConfig{
+			Timeout:               config.GetDuration("midtrans.httpTimeout"),
+			DialTimeout:           config.GetDuration("midtrans.httpDialTimeout"),
+			ResponseHeaderTimeout: config.GetDuration("midtrans.httpResponseHeaderTimeout"),
+			MaxIdleConnsPerHost:   config.GetInt("midtrans.httpMaxIdleConnsPerHost"),
+			IdleConnTimeout:       config.GetDuration("midtrans.httpIdleConnTimeout"),
+		})
+	}
+	// midtransOpts are shared by the live and the self-test handlers, the
+	// self-test one overrides the client and the dedup.
+	midtransOpts := []midtrans.Option{
+		midtrans.WithEnrichedResponse(config.GetBool("midtrans.enrichedResponse")),
+		midtrans.WithSchemaValidation(config.GetBool("midtrans.schemaValidation")),
+		midtrans.WithDisallowUnknownFields(config.GetBool("midtrans.disallowUnknownFields")),
+		midtrans.WithTracerProvider(tracerProvider),
+		midtrans.WithSignatureHeader(config.GetString("midtrans.signatureHeader")),
+		midtrans.WithAPIKey(config.GetString("midtrans.apiKeyHeader"), config.GetString("midtrans.apiKey")),
+		midtrans.WithForbiddenOrderStates(midtransForbiddenStates),
+		midtrans.WithForbiddenRefundOrderStates(midtransForbiddenRefundStates),
+		midtrans.WithContextTimeout(config.GetDuration("midtrans.contextTimeout")),
+		midtrans.WithHTTPClient(midtransHTTPClient),
+		midtrans.WithDedup(dedupStore, config.GetDuration("midtrans.dedupTTL")),
+		midtrans.WithStatusRetry(
+			config.GetInt("midtrans.statusAttempts"),
+			config.GetDuration("midtrans.statusBaseDelay"),
+		),
+	}
 	midtransHandlers, err := midtrans.NewHandler(config.GetString("midtrans.serverKey"),
 		config.GetString("midtrans.chargeURL"),
 		config.GetString("midtrans.getStatusURL"),
-		orderClient, taskClient)
+		orderClient, taskClient,
+		midtransOpts...,
+	)
+	providers[midtrans.HandlerName] = firstError(
+		routesErr,
+		configError("midtrans.forbiddenOrderStates", forbiddenErr),
+		configError("midtrans.forbiddenRefundOrderStates", refundErr),
+		err,
+	)
+	if err := providers[midtrans.HandlerName]; err != nil {
+		logger.Error().Err(err).Msg("failed to initialize midtrans handler")
+	} else {
+		midtransRouter := router.PathPrefix("/midtrans").Subrouter()
+		midtransRouter.Use(
+			middleware.LogLevel(midtransRoutes.logLevel),
+			metrics.Middleware(midtrans.HandlerName),
+			throughputCounter.Middleware(midtrans.HandlerName),
+			errorRecorder.Middleware(midtrans.HandlerName),
+			middleware.AllowCIDRs(midtransRoutes.allowedCIDRs),
+			handlerAcceptJSON(midtrans.HandlerName),
+			middleware.ContentType(validate.MediaTypeJSON),
+			middleware.RateLimit(config.GetFloat("midtrans.rateLimit", 64), config.GetInt("midtrans.rateBurst")),
+			backendBreaker.Middleware,
+			middleware.Alert(alertTracker, midtrans.HandlerName),
+			middleware.BodyReadTimeout(config.GetDuration("midtrans.bodyReadTimeout")),
+			middleware.BufferBody(maxBodyBytes),
+			middleware.Verify(midtransHandlers.Verifier()),
+			payload.Middleware(payloadSink, midtrans.HandlerName,
+				config.GetString("midtrans.apiKeyHeader"),
+				config.GetString("midtrans.signatureHeader"),
+			),
+		)
+		midtransRouter.HandleFunc("/transaction-update", midtransHandlers.HandleTransactionUpdate)
+	}
+
+	// Self tests, the handlers are built with the dry-run clients so the
+	// canned payloads don't write to the backend.
+	selfTest := selftest.NewRunner()
+	if providers[mileapp.HandlerName] == nil {
+		dryRunMileapp := mileapp.NewMileappHandlers(config.GetString("mileapp.authKey"),
+			&selftest.TaskClient{TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PICKING},
+			mileappOpts...,
+		)
+		selfTest.Register(mileapp.HandlerName,
+			selftest.GRPC(conn),
+			selftest.Config("mileapp.authKey", config.GetString("mileapp.authKey")),
+			selftest.DryRun(http.HandlerFunc(dryRunMileapp.HandleStatusUpdate), dryRunMileapp.SelfTestRequest),
+		)
+	}
+	if providers[shoptree.HandlerName] == nil {
+		dryRunShoptree, err := shoptree.NewHandler(config.GetString("shoptree.authKey"), &selftest.InventoryClient{}, shoptreeOpts...)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to initialize shoptree dry-run handler")
+		} else {
+			selfTest.Register(shoptree.HandlerName,
+				selftest.GRPC(conn),
+				selftest.Config("shoptree.authKey", config.GetString("shoptree.authKey")),
+				selftest.DryRun(middleware.Verify(dryRunShoptree.Verifier())(http.HandlerFunc(dryRunShoptree.HandleStockUpdate)),
+					dryRunShoptree.SelfTestRequest),
+			)
+		}
+	}
+	if providers[midtrans.HandlerName] == nil {
+		dryRunMidtrans, err := midtrans.NewHandler(config.GetString("midtrans.serverKey"),
+			config.GetString("midtrans.chargeURL"),
+			config.GetString("midtrans.getStatusURL"),
+			&selftest.OrderClient{},
+			&selftest.TaskClient{TaskType: tpb.OrderTaskType_ORDER_TASK_TYPE_PAYMENT},
+			append(midtransOpts[:len(midtransOpts):len(midtransOpts)],
+				midtrans.WithHTTPClient(midtrans.SelfTestHTTPClient()),
+				midtrans.WithDedup(nil, 0),
+			)...,
+		)
+		if err != nil {
+			logger.Error().Err(err).Msg("failed to initialize midtrans dry-run handler")
+		} else {
+			selfTest.Register(midtrans.HandlerName,
+				selftest.GRPC(conn),
+				selftest.Config("midtrans.serverKey", config.GetString("midtrans.serverKey")),
+				selftest.Config("midtrans.getStatusURL", config.GetString("midtrans.getStatusURL")),
+				selftest.DryRun(middleware.Verify(dryRunMidtrans.Verifier())(http.HandlerFunc(dryRunMidtrans.HandleTransactionUpdate)),
+					dryRunMidtrans.SelfTestRequest),
+			)
+		}
+	}
+
+	// Provider status, reports which callback handlers are ready.
+	router.HandleFunc(health.StatusPath, health.Status(providers))
+
+	// Replay handlers, only the providers that initialized can be replayed.
+	replayHandlers := make(map[string]http.Handler, len(providers))
+	if providers[mileapp.HandlerName] == nil {
+		replayHandlers[mileapp.HandlerName] = replayHandler("/mileapp", "x-api-key", config.GetString("mileapp.authKey"),
+			map[string]http.HandlerFunc{
+				"/status/{task-type}":       mileappHandlers.HandleStatusUpdate,
+				"/status/{task-type}/batch": mileappHandlers.HandleBatchStatusUpdate,
+			},
+		)
+	}
+	if providers[shoptree.HandlerName] == nil {
+		replayHandlers[shoptree.HandlerName] = replayHandler("/shoptree", "X-Client-Api-Key", config.GetString("shoptree.authKey"),
+			map[string]http.HandlerFunc{
+				"/stock-update":          shoptreeHandlers.HandleStockUpdate,
+				"/product-status-update": shoptreeHandlers.HandleProductStatusUpdate,
+			},
+		)
+	}
+	if providers[midtrans.HandlerName] == nil {
+		replayHandlers[midtrans.HandlerName] = replayHandler("/midtrans", config.GetString("midtrans.apiKeyHeader"), config.GetString("midtrans.apiKey"),
+			map[string]http.HandlerFunc{"/transaction-update": midtransHandlers.HandleTransactionUpdate},
+		)
+	}
+
+	// Admin handlers
+	adminHandlers, err := admin.NewHandler(config.GetString("admin.authKey"), dedupStore, jobStore,
+		admin.WithThroughput(throughputCounter),
+		admin.WithErrors(errorRecorder),
+		admin.WithSelfTest(selfTest),
+		admin.WithReplay(config.GetString("admin.replayAuthKey"), payloadLoader, replayHandlers),
+	)
 	if err != nil {
-		logger.Fatal().Err(err).Msg("failed to initialize midtrans handler")
+		logger.Fatal().Err(err).Msg("failed to initialize admin handler")
 	}
-	midtransRouter := router.PathPrefix("/midtrans").Subrouter()
-	midtransRouter.HandleFunc("/transaction-update", midtransHandlers.HandleTransactionUpdate)
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+	adminRouter.HandleFunc("/dedup/{key}", adminHandlers.HandleDedupGet).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/dedup/{key}", adminHandlers.HandleDedupDelete).Methods(http.MethodDelete)
+	adminRouter.HandleFunc("/jobs/{id}/results", adminHandlers.HandleJobResults).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/throughput", adminHandlers.HandleThroughput).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/errors/{provider}", adminHandlers.HandleErrors).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/selftest/{provider}", adminHandlers.HandleSelfTest).Methods(http.MethodPost)
+	adminRouter.HandleFunc("/replay/{provider}/{id}", adminHandlers.HandleReplay).Methods(http.MethodPost)
+
+	var handler http.Handler = router
+	handler = middleware.Recover(logger)(handler)
+	handler = middleware.Gzip(config.GetInt("server.gzipMinBytes"))(handler)
+	handler = middleware.AccessLog(logger)(handler)
+	handler = middleware.LogSample(uint32(config.GetUint64("log.sampleRate", 10, 32)))(handler)
+	handler = middleware.RequestID(logger)(handler)
+	handler = middleware.InFlight(inFlight)(handler)
+
+	return handler
+}
+
+// registerPprof registers the net/http/pprof handlers under /debug/pprof.
+func registerPprof(router *mux.Router) {
+	router.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	router.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	router.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	router.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	// the index also serves the named profiles, e.g. /debug/pprof/heap.
+	router.PathPrefix("/debug/pprof/").HandlerFunc(pprof.Index)
+}
 
-	return router
+// replayHandler routes the replayed payloads of a provider to its handlers
+// by path under prefix, without the provider middlewares. The auth header is
+// restored since it is redacted from the stored payloads.
+func replayHandler(prefix, authHeader, authKey string, routes map[string]http.HandlerFunc) http.Handler {
+	router := mux.NewRouter().PathPrefix(prefix).Subrouter()
+	for path, h := range routes {
+		router.HandleFunc(path, h)
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if authHeader != "" && authKey != "" {
+			r.Header.Set(authHeader, authKey)
+		}
+		router.ServeHTTP(w, r)
+	})
+}
+
+// splitList splits a comma separated config value, empty items are dropped.
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// configError returns err as the error of the config key, nil when err is
+// nil.
+func configError(key string, err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("config key %s: %w", key, err)
+}
+
+// firstError returns the first of errs that is not nil.
+func firstError(errs ...error) error {
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// routeConfig is the config of the middlewares shared by the routes of a
+// handler.
+type routeConfig struct {
+	// logLevel is the log level of the handler from log.levels.<handler>,
+	// it defaults to the global log level.
+	logLevel zerolog.Level
+	// allowedCIDRs are the source ip ranges allowed to call the handler from
+	// <handler>.allowedCIDRs, every address is allowed when empty.
+	allowedCIDRs []*net.IPNet
+}
+
+// handlerRouteConfig parses the routeConfig of handler, a bad value is the
+// error of the handler only.
+func handlerRouteConfig(handler string) (routeConfig, error) {
+	rc := routeConfig{logLevel: logger.GetLevel()}
+	if levelStr := config.GetString("log.levels." + handler); levelStr != "" {
+		level, err := zerolog.ParseLevel(levelStr)
+		if err != nil {
+			return rc, configError("log.levels."+handler, err)
+		}
+		rc.logLevel = level
+	}
+
+	cidrs, err := middleware.ParseCIDRs(splitList(config.GetString(handler + ".allowedCIDRs")))
+	if err != nil {
+		return rc, configError(handler+".allowedCIDRs", err)
+	}
+	rc.allowedCIDRs = cidrs
+	return rc, nil
+}
+
+// handlerAcceptJSON returns middleware.AcceptJSON when <handler>.acceptJSON
+// is set, the requests are let through otherwise.
+func handlerAcceptJSON(handler string) func(http.Handler) http.Handler {
+	if !config.GetBool(handler + ".acceptJSON") {
+		return func(next http.Handler) http.Handler { return next }
+	}
+	return middleware.AcceptJSON
 }
 
-func storefrontAuthInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-	authCtx := metadata.AppendToOutgoingContext(ctx, "x-api-Key", config.GetString("storefront-api.authKey"))
-	return invoker(authCtx, method, req, reply, cc, opts...)
+// insecureGRPC reports whether the backend connection is insecure outside of
+// development, grpc.tls.enabled is expected to be set there.
+func insecureGRPC() bool {
+	return !config.GetBool("grpc.tls.enabled") && environment != "development"
 }
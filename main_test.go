@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/kenshaw/envcfg"
+	"github.com/rs/zerolog"
+
+	"github.com/dropezy/storefront-backend/http/breaker"
+	"github.com/dropezy/storefront-backend/http/internal/validate"
+	"github.com/dropezy/storefront-backend/http/middleware"
+
+	inpbmock "github.com/dropezy/proto/mock/inventory"
+	opbmock "github.com/dropezy/proto/mock/order"
+	tpbmock "github.com/dropezy/proto/mock/task"
+)
+
+func TestMain(m *testing.M) {
+	var err error
+	config, err = envcfg.New(envcfg.ConfigFile("env/sample.config"))
+	if err != nil {
+		log.Fatal(err)
+	}
+	logger = zerolog.Nop()
+
+	os.Exit(m.Run())
+}
+
+// TestRegisterHandler_ContentType checks the media types of the provider
+// routes are enforced by their middleware chain, before the handlers.
+func TestRegisterHandler_ContentType(t *testing.T) {
+	t.Parallel()
+
+	ctrl := gomock.NewController(t)
+	// no call is expected on the clients, the requests are rejected first.
+	handler := registerHandler(&sync.WaitGroup{}, breaker.New(0, 0), nil,
+		opbmock.NewMockOrderServiceClient(ctrl),
+		tpbmock.NewMockTaskServiceClient(ctrl),
+		inpbmock.NewMockInventoryServiceClient(ctrl),
+	)
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		wantCode    int
+		wantMessage string
+	}{
+		{
+			name:        "Mileapp",
+			path:        "/mileapp/status/picking",
+			contentType: validate.MediaTypeCSV,
+			wantCode:    http.StatusUnsupportedMediaType,
+			wantMessage: validate.ErrInvalidContentType.Error(),
+		},
+		{
+			name:        "Shoptree",
+			path:        "/shoptree/stock-update",
+			contentType: validate.MediaTypeNDJSON,
+			wantCode:    http.StatusUnsupportedMediaType,
+			wantMessage: validate.ErrInvalidContentType.Error(),
+		},
+		{
+			name:        "Midtrans",
+			path:        "/midtrans/transaction-update",
+			wantCode:    http.StatusBadRequest,
+			wantMessage: validate.ErrContentTypeIsRequired.Error(),
+		},
+		{
+			// the JSON requests go through to the verifier.
+			name:        "JSON",
+			path:        "/midtrans/transaction-update",
+			contentType: validate.MediaTypeJSON + "; charset=utf-8",
+			wantCode:    http.StatusUnauthorized,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := http.NewRequest(http.MethodPost, test.path, strings.NewReader("{}"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.contentType != "" {
+				r.Header.Set("Content-Type", test.contentType)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			if got := resp.StatusCode; got != test.wantCode {
+				t.Fatalf("registerHandler(), got = %v, want = %v", got, test.wantCode)
+			}
+			if test.wantMessage == "" {
+				return
+			}
+
+			got := &middleware.Response{}
+			if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Message != test.wantMessage {
+				t.Fatalf("registerHandler(), got = %v, want = %v", got.Message, test.wantMessage)
+			}
+		})
+	}
+}
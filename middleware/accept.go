@@ -0,0 +1,23 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/dropezy/storefront-backend/http/internal/validate"
+)
+
+// AcceptJSON returns http 406 when the request Accept header doesn't allow an
+// application/json response, e.g. a provider misconfigured to send
+// Accept: text/html. Requests without an Accept header are let through.
+//
+// It is opt-in per route, like ContentType.
+func AcceptJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if accept := r.Header.Values("Accept"); len(accept) > 0 && !validate.Accepts(accept, validate.MediaTypeJSON) {
+			responseJSON(w, r, http.StatusNotAcceptable, ErrNotAcceptable.Error())
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
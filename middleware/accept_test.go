@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAcceptJSON(t *testing.T) {
+	t.Parallel()
+
+	handler := AcceptJSON(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name     string
+		accept   []string
+		wantCode int
+	}{
+		{name: "Missing", wantCode: http.StatusOK},
+		{name: "JSON", accept: []string{"application/json"}, wantCode: http.StatusOK},
+		{name: "JSONWithCharset", accept: []string{"application/json; charset=utf-8"}, wantCode: http.StatusOK},
+		{name: "Wildcard", accept: []string{"*/*"}, wantCode: http.StatusOK},
+		{name: "ApplicationWildcard", accept: []string{"application/*"}, wantCode: http.StatusOK},
+		{name: "OneOfList", accept: []string{"text/html, application/json;q=0.9"}, wantCode: http.StatusOK},
+		{name: "OneOfValues", accept: []string{"text/html", "application/json"}, wantCode: http.StatusOK},
+		{name: "HTML", accept: []string{"text/html"}, wantCode: http.StatusNotAcceptable},
+		{name: "ZeroQuality", accept: []string{"text/html, application/json;q=0"}, wantCode: http.StatusNotAcceptable},
+		{name: "Malformed", accept: []string{"application/json; ="}, wantCode: http.StatusNotAcceptable},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, v := range test.accept {
+				r.Header.Add("Accept", v)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			if got := resp.StatusCode; got != test.wantCode {
+				t.Fatalf("AcceptJSON(), got = %v, want = %v", got, test.wantCode)
+			}
+			if test.wantCode == http.StatusOK {
+				return
+			}
+
+			got := &Response{}
+			if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Message != ErrNotAcceptable.Error() {
+				t.Fatalf("AcceptJSON(), got = %v, want = %v", got.Message, ErrNotAcceptable.Error())
+			}
+		})
+	}
+}
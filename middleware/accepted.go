@@ -0,0 +1,28 @@
+package middleware
+
+import "context"
+
+type acceptedErrorKey struct{}
+
+// acceptedError holds the internal code of a request acknowledged to the
+// provider with http 200 while it was not processed successfully.
+type acceptedError struct {
+	code string
+}
+
+// withAcceptedError returns a context on which AcceptedWithError can mark the
+// request, along with the holder of the marked code.
+func withAcceptedError(ctx context.Context) (context.Context, *acceptedError) {
+	ae := &acceptedError{}
+	return context.WithValue(ctx, acceptedErrorKey{}, ae), ae
+}
+
+// AcceptedWithError marks the request of ctx as accepted with an error, the
+// provider gets http 200 to stop its retries but the request is still
+// recorded as an error under code. It is a no-op when the request is not
+// handled by the Metrics middleware.
+func AcceptedWithError(ctx context.Context, code string) {
+	if ae, ok := ctx.Value(acceptedErrorKey{}).(*acceptedError); ok {
+		ae.code = code
+	}
+}
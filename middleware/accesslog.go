@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// AccessLog returns a middleware logging every request at info level once it
+// is handled, including the ones rejected by the router or the other
+// middlewares which the handlers never see.
+func AccessLog(logger zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rec := newStatusRecorder(w)
+
+			next.ServeHTTP(rec, r)
+
+			e := logger.Info().
+				Str("method", r.Method).
+				Str("path", r.URL.Path).
+				Str("remote_addr", r.RemoteAddr).
+				Int("status", rec.status).
+				Int("size", rec.size).
+				Dur("duration", time.Since(start))
+			if id, ok := RequestIDFromContext(r.Context()); ok {
+				e = e.Str("request-id", id)
+			}
+			e.Msg("access")
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestAccessLog(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+
+	h := RequestID(logger)(AccessLog(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	})))
+
+	r, err := http.NewRequest(http.MethodGet, "/unknown", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.RemoteAddr = "103.208.23.6:41000"
+	r.Header.Set(RequestIDHeader, "request-1")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	got := map[string]interface{}{}
+	if err := json.Unmarshal(logs.Bytes(), &got); err != nil {
+		t.Fatalf("access log %q: %v", logs.String(), err)
+	}
+	want := map[string]interface{}{
+		"method":      http.MethodGet,
+		"path":        "/unknown",
+		"remote_addr": "103.208.23.6:41000",
+		"status":      float64(http.StatusNotFound),
+		"size":        float64(w.Body.Len()),
+		"request-id":  "request-1",
+		"level":       "info",
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Fatalf("access log %s, got = %v, want = %v", k, got[k], v)
+		}
+	}
+	if _, ok := got["duration"]; !ok {
+		t.Fatal("access log, duration is missing")
+	}
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/alert"
+)
+
+// Alert returns a middleware recording every 5xx response of handler in
+// tracker, so on-call is alerted when the handler keeps failing.
+func Alert(tracker *alert.Tracker, handler string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rec := newStatusRecorder(w)
+			next.ServeHTTP(rec, r)
+
+			if rec.status < http.StatusInternalServerError {
+				return
+			}
+
+			logger := logging.FromContext(r.Context()).With().Str("handler", handler).Logger()
+			// the alert is sent in the background to not delay the response.
+			go func() {
+				if _, err := tracker.Failure(context.Background(), handler); err != nil {
+					logger.Err(err).Msg("failed to send alert")
+				}
+			}()
+		})
+	}
+}
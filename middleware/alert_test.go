@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/dropezy/storefront-backend/http/alert"
+)
+
+type chanAlerter chan string
+
+func (c chanAlerter) Alert(_ context.Context, _ alert.Severity, message string) error {
+	c <- message
+	return nil
+}
+
+func TestAlert(t *testing.T) {
+	t.Parallel()
+
+	alerts := make(chanAlerter, 1)
+	tracker := alert.NewTracker(alerts, 2, time.Minute)
+
+	status := http.StatusOK
+	h := Alert(tracker, "midtrans")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	do := func() {
+		r, err := http.NewRequest(http.MethodPost, "/midtrans/transaction-update", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	// successful and client error responses are not failures
+	do()
+	status = http.StatusBadRequest
+	do()
+
+	status = http.StatusInternalServerError
+	do()
+	do()
+
+	select {
+	case <-alerts:
+	case <-time.After(time.Second):
+		t.Fatal("Alert(), want alert after crossing threshold")
+	}
+}
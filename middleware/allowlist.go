@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/dropezy/internal/logging"
+)
+
+// ParseCIDRs parses a list of CIDRs, a bare IP is allowed as a single
+// address range, e.g. "103.208.23.0/24" or "103.208.23.6".
+func ParseCIDRs(list []string) ([]*net.IPNet, error) {
+	cidrs := make([]*net.IPNet, 0, len(list))
+	for _, s := range list {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			ip := net.ParseIP(s)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid ip: %q", s)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			cidrs = append(cidrs, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+
+		_, cidr, err := net.ParseCIDR(s)
+		if err != nil {
+			return nil, fmt.Errorf("invalid cidr: %q", s)
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
+
+// AllowCIDRs returns a middleware rejecting with http 403 the requests whose
+// remote address is outside cidrs, every address is allowed when cidrs is
+// empty. The remote address is the peer of the connection, so the server
+// must not sit behind a proxy rewriting it.
+func AllowCIDRs(cidrs []*net.IPNet) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if len(cidrs) == 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			if ip := net.ParseIP(host); ip != nil {
+				for _, cidr := range cidrs {
+					if cidr.Contains(ip) {
+						next.ServeHTTP(w, r)
+						return
+					}
+				}
+			}
+
+			logging.FromContext(r.Context()).Err(ErrForbiddenIP).Str("remote_ip", host).Msg("rejected request from outside the allowlist")
+			responseJSON(w, r, http.StatusForbidden, ErrForbiddenIP.Error())
+		})
+	}
+}
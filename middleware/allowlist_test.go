@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowCIDRs(t *testing.T) {
+	t.Parallel()
+
+	cidrs, err := ParseCIDRs([]string{"103.208.23.0/24", "10.0.0.7", "2001:db8::/32"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		wantCode   int
+	}{
+		{name: "InRange", remoteAddr: "103.208.23.6:41000", wantCode: http.StatusOK},
+		{name: "SingleIP", remoteAddr: "10.0.0.7:41000", wantCode: http.StatusOK},
+		{name: "IPv6", remoteAddr: "[2001:db8::1]:41000", wantCode: http.StatusOK},
+		{name: "OutOfRange", remoteAddr: "103.208.24.6:41000", wantCode: http.StatusForbidden},
+		{name: "InvalidRemoteAddr", remoteAddr: "unknown", wantCode: http.StatusForbidden},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			called := false
+			h := AllowCIDRs(cidrs)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			}))
+
+			r := httptest.NewRequest(http.MethodPost, "/midtrans/transaction-update", nil)
+			r.RemoteAddr = test.remoteAddr
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != test.wantCode {
+				t.Fatalf("AllowCIDRs(), got = %v, want = %v", w.Code, test.wantCode)
+			}
+			if called != (test.wantCode == http.StatusOK) {
+				t.Fatalf("AllowCIDRs() called the handler = %v for http %v", called, w.Code)
+			}
+		})
+	}
+}
+
+func TestAllowCIDRs_Empty(t *testing.T) {
+	t.Parallel()
+
+	h := AllowCIDRs(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	r := httptest.NewRequest(http.MethodPost, "/midtrans/transaction-update", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("AllowCIDRs(), got = %v, want = %v", w.Code, http.StatusOK)
+	}
+}
+
+func TestParseCIDRs(t *testing.T) {
+	t.Parallel()
+
+	for _, in := range []string{"103.208.23.0/33", "not-an-ip"} {
+		if _, err := ParseCIDRs([]string{in}); err == nil {
+			t.Fatalf("ParseCIDRs(%q), got nil error", in)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/dropezy/internal/logging"
+)
+
+// DefaultMaxBodyBytes is the body size limit of BufferBody used by the
+// callback routes, the biggest shoptree batches are well below it.
+const DefaultMaxBodyBytes = 1 << 20
+
+type rawBodyKey struct{}
+
+// BufferBody returns a middleware reading the request body into memory, so
+// it can be read more than once, e.g. to verify a signature over the raw
+// body and then decode it. Bodies bigger than maxBytes are rejected with
+// http 413.
+//
+// r.Body is replaced with a reader over the buffered bytes, which are also
+// stored on the request context, see RawBody and ResetBody.
+func BufferBody(maxBytes int64) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// read one more byte than allowed to detect oversized bodies.
+			raw, err := io.ReadAll(io.LimitReader(r.Body, maxBytes+1))
+			r.Body.Close()
+			if errors.Is(err, ErrBodyReadTimeout) {
+				logging.FromContext(r.Context()).Err(err).Msg("failed to read request body")
+				responseJSON(w, r, http.StatusRequestTimeout, ErrBodyReadTimeout.Error())
+				return
+			}
+			if err != nil {
+				logging.FromContext(r.Context()).Err(err).Msg("failed to read request body")
+				responseJSON(w, r, http.StatusBadRequest, ErrReadBody.Error())
+				return
+			}
+			if int64(len(raw)) > maxBytes {
+				responseJSON(w, r, http.StatusRequestEntityTooLarge, ErrBodyTooLarge.Error())
+				return
+			}
+
+			r = r.WithContext(context.WithValue(r.Context(), rawBodyKey{}, raw))
+			ResetBody(r)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RawBody returns the request body buffered by BufferBody.
+func RawBody(ctx context.Context) ([]byte, bool) {
+	raw, ok := ctx.Value(rawBodyKey{}).([]byte)
+	return raw, ok
+}
+
+// ResetBody rewinds r.Body to the start of the body buffered by BufferBody,
+// so the next reader sees the whole body again. It is a no-op when the body
+// is not buffered.
+func ResetBody(r *http.Request) {
+	raw, ok := RawBody(r.Context())
+	if !ok {
+		return
+	}
+	r.Body = io.NopCloser(bytes.NewReader(raw))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(raw)), nil
+	}
+}
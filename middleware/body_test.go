@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBufferBody(t *testing.T) {
+	t.Parallel()
+
+	const (
+		secret = "hmac-secret"
+		body   = `{"order_id":"payment-task-id","transaction_status":"settlement"}`
+	)
+	sign := func(b []byte) string {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(b)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	var hmacBytes, decodedBytes []byte
+
+	// verify reads the whole body to check its signature, as a HMAC layer
+	// would, then rewinds it for the handler.
+	verify := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, err := io.ReadAll(r.Body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			hmacBytes = b
+			if !hmac.Equal([]byte(sign(b)), []byte(r.Header.Get("X-Signature"))) {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			ResetBody(r)
+			next.ServeHTTP(w, r)
+		})
+	}
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var v map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&v); err != nil {
+			t.Fatal(err)
+		}
+		raw, ok := RawBody(r.Context())
+		if !ok {
+			t.Fatal("RawBody(), got no body on the context")
+		}
+		decodedBytes = raw
+		if v["order_id"] != "payment-task-id" {
+			t.Errorf("decoded order_id, got = %v, want = %v", v["order_id"], "payment-task-id")
+		}
+	})
+
+	h := BufferBody(DefaultMaxBodyBytes)(verify(handler))
+
+	r, err := http.NewRequest(http.MethodPost, "/midtrans/transaction-update", strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("X-Signature", sign([]byte(body)))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("BufferBody(), got = %v, want = %v", w.Code, http.StatusOK)
+	}
+	if !bytes.Equal(hmacBytes, []byte(body)) || !bytes.Equal(decodedBytes, hmacBytes) {
+		t.Fatalf("BufferBody(), hmac got %q, decoder got %q, want %q", hmacBytes, decodedBytes, body)
+	}
+}
+
+func TestBufferBody_TooLarge(t *testing.T) {
+	t.Parallel()
+
+	h := BufferBody(8)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("handler called for an oversized body")
+	}))
+
+	r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", strings.NewReader(`[{"reference_id":"1"}]`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("BufferBody(), got = %v, want = %v", w.Code, http.StatusRequestEntityTooLarge)
+	}
+}
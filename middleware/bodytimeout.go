@@ -0,0 +1,79 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"time"
+)
+
+// BodyReadTimeout returns a middleware bounding the time spent reading the
+// request body to d, independently of the server read timeout. Once d is
+// elapsed the body is closed and the read in progress fails with
+// ErrBodyReadTimeout, so a sender trickling or stalling its body can't hold
+// the handler for the whole server read timeout. It is disabled when d is not
+// positive.
+func BodyReadTimeout(d time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if d <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Body != nil && r.Body != http.NoBody {
+				dr := &deadlineReader{ReadCloser: r.Body, expired: make(chan struct{})}
+				timer := time.AfterFunc(d, dr.expire)
+				defer timer.Stop()
+				r.Body = dr
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// readResult is the outcome of a read of the wrapped body.
+type readResult struct {
+	n   int
+	err error
+}
+
+// deadlineReader fails the reads still in progress or happening once expired
+// is closed.
+type deadlineReader struct {
+	io.ReadCloser
+
+	expired chan struct{}
+	// buf is read into by the wrapped body, a read left behind by the
+	// deadline must not write to the buffer of the caller.
+	buf []byte
+}
+
+// expire fails the reads of r and closes the wrapped body, which stops the
+// bodies whose Close interrupts a blocked Read.
+func (r *deadlineReader) expire() {
+	close(r.expired)
+	_ = r.ReadCloser.Close()
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	select {
+	case <-r.expired:
+		return 0, ErrBodyReadTimeout
+	default:
+	}
+
+	if cap(r.buf) < len(p) {
+		r.buf = make([]byte, len(p))
+	}
+	buf := r.buf[:len(p)]
+	done := make(chan readResult, 1)
+	go func() {
+		n, err := r.ReadCloser.Read(buf)
+		done <- readResult{n: n, err: err}
+	}()
+
+	select {
+	case res := <-done:
+		return copy(p, buf[:res.n]), res.err
+	case <-r.expired:
+		return 0, ErrBodyReadTimeout
+	}
+}
@@ -0,0 +1,116 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// slowReader returns its body one byte at a time, waiting delay between
+// each byte, as a slow-loris sender would.
+type slowReader struct {
+	body  []byte
+	delay time.Duration
+}
+
+func (r *slowReader) Read(p []byte) (int, error) {
+	if len(r.body) == 0 {
+		return 0, io.EOF
+	}
+	time.Sleep(r.delay)
+	n := copy(p[:1], r.body)
+	r.body = r.body[n:]
+	return n, nil
+}
+
+// blockingReader returns its first byte then blocks until it is closed, as a
+// sender stalling in the middle of its body would.
+type blockingReader struct {
+	sent   bool
+	once   sync.Once
+	closed chan struct{}
+}
+
+func (r *blockingReader) Read(p []byte) (int, error) {
+	if !r.sent {
+		r.sent = true
+		return copy(p, "["), nil
+	}
+	<-r.closed
+	return 0, io.ErrClosedPipe
+}
+
+func (r *blockingReader) Close() error {
+	r.once.Do(func() { close(r.closed) })
+	return nil
+}
+
+func TestBodyReadTimeout(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		timeout  time.Duration
+		delay    time.Duration
+		wantCode int
+	}{
+		{
+			name:     "SlowBody",
+			timeout:  20 * time.Millisecond,
+			delay:    10 * time.Millisecond,
+			wantCode: http.StatusRequestTimeout,
+		},
+		{
+			name:     "FastBody",
+			timeout:  time.Second,
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Disabled",
+			delay:    time.Millisecond,
+			wantCode: http.StatusOK,
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := BodyReadTimeout(test.timeout)(BufferBody(DefaultMaxBodyBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+			body := &slowReader{body: []byte(`[{"reference_id":"ref-1"}]`), delay: test.delay}
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != test.wantCode {
+				t.Fatalf("BodyReadTimeout(), got = %v, want = %v", w.Code, test.wantCode)
+			}
+		})
+	}
+}
+
+func TestBodyReadTimeout_Blocked(t *testing.T) {
+	t.Parallel()
+
+	h := BodyReadTimeout(20 * time.Millisecond)(BufferBody(DefaultMaxBodyBytes)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})))
+
+	body := &blockingReader{closed: make(chan struct{})}
+	r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+
+	if w.Code != http.StatusRequestTimeout {
+		t.Fatalf("BodyReadTimeout(), got = %v, want = %v", w.Code, http.StatusRequestTimeout)
+	}
+}
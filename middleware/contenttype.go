@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/dropezy/storefront-backend/http/internal/validate"
+)
+
+// ContentType returns a middleware rejecting the requests whose content type
+// is not one of mediaTypes, with http 400 when it is missing and http 415
+// otherwise. The check is validate.ContentType, the one of the handlers.
+//
+// It is set per route, so the JSON routes stay strict while the upload routes
+// accept their own media types, e.g. validate.MediaTypeCSV.
+func ContentType(mediaTypes ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch err := validate.ContentType(r.Header, mediaTypes...); err {
+			case nil:
+				next.ServeHTTP(w, r)
+			case validate.ErrContentTypeIsRequired:
+				responseJSON(w, r, http.StatusBadRequest, err.Error())
+			default:
+				responseJSON(w, r, http.StatusUnsupportedMediaType, err.Error())
+			}
+		})
+	}
+}
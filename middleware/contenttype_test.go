@@ -0,0 +1,93 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+
+	"github.com/dropezy/storefront-backend/http/internal/validate"
+)
+
+func TestContentType(t *testing.T) {
+	t.Parallel()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	// the media types are set on the subrouters, like the provider routes.
+	router := mux.NewRouter()
+	jsonRouter := router.PathPrefix("/json").Subrouter()
+	jsonRouter.Use(ContentType(validate.MediaTypeJSON))
+	jsonRouter.Handle("/upload", ok)
+	csvRouter := router.PathPrefix("/csv").Subrouter()
+	csvRouter.Use(ContentType(validate.MediaTypeCSV))
+	csvRouter.Handle("/upload", ok)
+
+	tests := []struct {
+		name        string
+		path        string
+		contentType string
+		wantCode    int
+		wantMessage string
+	}{
+		{name: "JSONRouteAcceptsJSON", path: "/json/upload", contentType: "application/json; charset=utf-8", wantCode: http.StatusOK},
+		{
+			name:        "JSONRouteRejectsCSV",
+			path:        "/json/upload",
+			contentType: "text/csv",
+			wantCode:    http.StatusUnsupportedMediaType,
+			wantMessage: validate.ErrInvalidContentType.Error(),
+		},
+		{name: "CSVRouteAcceptsCSV", path: "/csv/upload", contentType: "text/csv", wantCode: http.StatusOK},
+		{
+			name:        "CSVRouteRejectsJSON",
+			path:        "/csv/upload",
+			contentType: "application/json",
+			wantCode:    http.StatusUnsupportedMediaType,
+			wantMessage: validate.ErrInvalidContentType.Error(),
+		},
+		{
+			name:        "Missing",
+			path:        "/csv/upload",
+			wantCode:    http.StatusBadRequest,
+			wantMessage: validate.ErrContentTypeIsRequired.Error(),
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := http.NewRequest(http.MethodPost, test.path, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.contentType != "" {
+				r.Header.Set("Content-Type", test.contentType)
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, r)
+
+			resp := w.Result()
+			if got := resp.StatusCode; got != test.wantCode {
+				t.Fatalf("ContentType(), got = %v, want = %v", got, test.wantCode)
+			}
+			if test.wantCode == http.StatusOK {
+				return
+			}
+
+			got := &Response{}
+			if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Message != test.wantMessage {
+				t.Fatalf("ContentType(), got = %v, want = %v", got.Message, test.wantMessage)
+			}
+		})
+	}
+}
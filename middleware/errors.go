@@ -0,0 +1,18 @@
+package middleware
+
+import "errors"
+
+var (
+	ErrNotAcceptable = errors.New("json response not accepted")
+
+	ErrReadBody     = errors.New("failed to read request body")
+	ErrBodyTooLarge = errors.New("request body too large")
+
+	ErrBodyReadTimeout = errors.New("request body read timeout")
+
+	ErrForbiddenIP = errors.New("source ip not allowed")
+	ErrRateLimited = errors.New("rate limit exceeded")
+
+	ErrMarshallingUnsuccessful     = errors.New("marshalling unsuccessful")
+	ErrWriteToResponseUnsuccessful = errors.New("write to response unsuccessful")
+)
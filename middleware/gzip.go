@@ -0,0 +1,162 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Gzip returns a middleware compressing with gzip the responses of the
+// requests accepting it, e.g. the per item results of the large shoptree
+// batches. Only the responses of at least minSize bytes are compressed, the
+// smaller ones aren't worth the overhead. It is disabled when minSize is not
+// positive.
+//
+// The response is buffered up to minSize bytes to choose, a handler flushing
+// before commits to the choice made with what it wrote so far.
+func Gzip(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if minSize <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			AddVary(w.Header(), "Accept-Encoding")
+			if !AcceptsGzip(r.Header) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipWriter{ResponseWriter: w, minSize: minSize, status: http.StatusOK}
+			defer gw.Close()
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// AcceptsGzip reports whether one of the Accept-Encoding header values allows
+// gzip, or any coding, with a non zero quality.
+func AcceptsGzip(h http.Header) bool {
+	for _, v := range h.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(v, ",") {
+			name, params, _ := strings.Cut(strings.TrimSpace(coding), ";")
+			name = strings.ToLower(strings.TrimSpace(name))
+			if name != "gzip" && name != "*" {
+				continue
+			}
+			if params = strings.TrimSpace(params); strings.HasPrefix(params, "q=") {
+				if f, err := strconv.ParseFloat(strings.TrimPrefix(params, "q="), 64); err != nil || f <= 0 {
+					continue
+				}
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// AddVary adds value to the Vary header unless it is already listed there,
+// so handlers and middlewares can both declare it.
+func AddVary(h http.Header, value string) {
+	for _, v := range h.Values("Vary") {
+		for _, field := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(field), value) {
+				return
+			}
+		}
+	}
+	h.Add("Vary", value)
+}
+
+// gzipWriter buffers the response until minSize bytes are written, then
+// compresses it. The responses ending below minSize are written as is.
+type gzipWriter struct {
+	http.ResponseWriter
+
+	minSize int
+	status  int
+	buf     bytes.Buffer
+
+	// committed is set once the header is written, gz is set when the body
+	// is compressed.
+	committed bool
+	gz        *gzip.Writer
+}
+
+func (w *gzipWriter) WriteHeader(code int) {
+	if !w.committed {
+		w.status = code
+	}
+}
+
+func (w *gzipWriter) Write(b []byte) (int, error) {
+	if w.committed {
+		if w.gz != nil {
+			return w.gz.Write(b)
+		}
+		return w.ResponseWriter.Write(b)
+	}
+
+	w.buf.Write(b)
+	if w.buf.Len() >= w.minSize {
+		if err := w.commit(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// commit writes the header and the buffered body, compressed when it reached
+// minSize and nothing else encoded it.
+func (w *gzipWriter) commit() error {
+	w.committed = true
+
+	h := w.Header()
+	if w.buf.Len() >= w.minSize && h.Get("Content-Encoding") == "" {
+		h.Set("Content-Encoding", "gzip")
+		// the length of the compressed body isn't known yet.
+		h.Del("Content-Length")
+		w.gz = gzip.NewWriter(w.ResponseWriter)
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+
+	if w.buf.Len() == 0 {
+		return nil
+	}
+	var err error
+	if w.gz != nil {
+		_, err = w.gz.Write(w.buf.Bytes())
+	} else {
+		_, err = w.ResponseWriter.Write(w.buf.Bytes())
+	}
+	w.buf.Reset()
+	return err
+}
+
+// Flush implements http.Flusher when the wrapped writer does.
+func (w *gzipWriter) Flush() {
+	if !w.committed {
+		_ = w.commit()
+	}
+	if w.gz != nil {
+		_ = w.gz.Flush()
+	}
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Close writes the response still buffered and terminates the compressed
+// body.
+func (w *gzipWriter) Close() error {
+	if !w.committed {
+		if err := w.commit(); err != nil {
+			return err
+		}
+	}
+	if w.gz != nil {
+		return w.gz.Close()
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestGzip(t *testing.T) {
+	t.Parallel()
+
+	const minSize = 64
+	large := `[` + strings.Repeat(`{"status":"success"},`, 10) + `{"status":"success"}]`
+	small := `{"message":"ok"}`
+
+	tests := []struct {
+		name           string
+		acceptEncoding string
+		body           string
+		minSize        int
+		wantGzip       bool
+	}{
+		{name: "Large", acceptEncoding: "gzip, deflate", body: large, minSize: minSize, wantGzip: true},
+		{name: "Small", acceptEncoding: "gzip", body: small, minSize: minSize},
+		{name: "NotAccepted", body: large, minSize: minSize},
+		{name: "Refused", acceptEncoding: "gzip;q=0, br", body: large, minSize: minSize},
+		{name: "Wildcard", acceptEncoding: "*", body: large, minSize: minSize, wantGzip: true},
+		{name: "Disabled", acceptEncoding: "gzip", body: large},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			handler := Gzip(test.minSize)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusMultiStatus)
+				// written in two parts so the threshold is crossed mid body.
+				half := len(test.body) / 2
+				io.WriteString(w, test.body[:half])
+				io.WriteString(w, test.body[half:])
+			}))
+
+			r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.acceptEncoding != "" {
+				r.Header.Set("Accept-Encoding", test.acceptEncoding)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			if resp.StatusCode != http.StatusMultiStatus {
+				t.Fatalf("Gzip() code, got = %v, want = %v", resp.StatusCode, http.StatusMultiStatus)
+			}
+			if got := resp.Header.Get("Content-Encoding") == "gzip"; got != test.wantGzip {
+				t.Fatalf("Gzip() compressed, got = %v, want = %v", got, test.wantGzip)
+			}
+
+			body := resp.Body
+			if test.wantGzip {
+				if body, err = gzip.NewReader(resp.Body); err != nil {
+					t.Fatal(err)
+				}
+			}
+			got, err := io.ReadAll(body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != test.body {
+				t.Fatalf("Gzip() body, got = %q, want = %q", got, test.body)
+			}
+		})
+	}
+}
+
+func TestAddVary(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		vary []string
+		want []string
+	}{
+		{name: "Missing", want: []string{"Accept-Encoding"}},
+		{name: "Other", vary: []string{"Origin"}, want: []string{"Origin", "Accept-Encoding"}},
+		{name: "Present", vary: []string{"Origin, accept-encoding"}, want: []string{"Origin, accept-encoding"}},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := http.Header{}
+			for _, v := range test.vary {
+				h.Add("Vary", v)
+			}
+			AddVary(h, "Accept-Encoding")
+
+			if got := strings.Join(h.Values("Vary"), "|"); got != strings.Join(test.want, "|") {
+				t.Fatalf("AddVary() Vary, got = %q, want = %q", got, strings.Join(test.want, "|"))
+			}
+		})
+	}
+}
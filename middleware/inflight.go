@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"sync"
+)
+
+// InFlight returns a middleware adding every request to wg until its handler
+// returns, so shutdown can wait for the in-flight requests to be drained.
+func InFlight(wg *sync.WaitGroup) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			wg.Add(1)
+			defer wg.Done()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Wait waits for wg, it returns the context error when ctx is done first.
+func Wait(ctx context.Context, wg *sync.WaitGroup) error {
+	done := make(chan struct{})
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
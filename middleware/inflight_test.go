@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestInFlight(t *testing.T) {
+	t.Parallel()
+
+	var wg sync.WaitGroup
+
+	started := make(chan struct{})
+	release := make(chan struct{})
+	h := InFlight(&wg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go h.ServeHTTP(httptest.NewRecorder(), r)
+	<-started
+
+	// the request is still in-flight
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := Wait(ctx, &wg); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Wait(), got = %v, want = %v", err, context.DeadlineExceeded)
+	}
+
+	close(release)
+	if err := Wait(context.Background(), &wg); err != nil {
+		t.Fatalf("Wait(), got = %v, want = nil", err)
+	}
+}
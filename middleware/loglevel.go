@@ -0,0 +1,21 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/dropezy/internal/logging"
+)
+
+// LogLevel returns a middleware setting the minimum level of the context
+// logger, so a single handler can be debugged without the noise of the
+// others.
+func LogLevel(level zerolog.Level) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := logging.FromContext(r.Context()).Level(level)
+			next.ServeHTTP(w, r.WithContext(l.WithContext(r.Context())))
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/dropezy/internal/logging"
+)
+
+func TestLogLevel(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	base := zerolog.New(&logs).Level(zerolog.InfoLevel)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		logging.FromContext(r.Context()).Debug().Msg("debugging " + r.URL.Path)
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/shoptree/stock-update", LogLevel(zerolog.DebugLevel)(handler))
+	mux.Handle("/midtrans/transaction-update", handler)
+
+	for _, path := range []string{"/shoptree/stock-update", "/midtrans/transaction-update"} {
+		r, err := http.NewRequest(http.MethodPost, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r = r.WithContext(base.WithContext(r.Context()))
+		mux.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	if !strings.Contains(logs.String(), "debugging /shoptree/stock-update") {
+		t.Errorf("LogLevel() logs, want the shoptree debug log in %s", logs.String())
+	}
+	if strings.Contains(logs.String(), "debugging /midtrans/transaction-update") {
+		t.Errorf("LogLevel() logs, want no midtrans debug log in %s", logs.String())
+	}
+}
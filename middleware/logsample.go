@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/rs/zerolog"
+
+	"github.com/dropezy/internal/logging"
+)
+
+// LogSample returns a middleware keeping 1 of every n info messages of the
+// context logger, so the success logs of busy handlers don't flood the logs.
+// The other levels are never sampled and the access log is kept since it
+// doesn't use the context logger. It is disabled when n is below 2.
+func LogSample(n uint32) func(http.Handler) http.Handler {
+	if n < 2 {
+		return func(next http.Handler) http.Handler { return next }
+	}
+
+	// the sampler is shared so the count spans the requests.
+	sampler := &zerolog.LevelSampler{InfoSampler: &zerolog.BasicSampler{N: n}}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := logging.FromContext(r.Context()).Sample(sampler)
+			next.ServeHTTP(w, r.WithContext(l.WithContext(r.Context())))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/dropezy/internal/logging"
+)
+
+func TestLogSample(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		n         uint32
+		wantInfos int
+	}{
+		{name: "Disabled", n: 0, wantInfos: 4},
+		{name: "EveryRequest", n: 1, wantInfos: 4},
+		{name: "OneOfTwo", n: 2, wantInfos: 2},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logs bytes.Buffer
+			base := zerolog.New(&logs)
+
+			handler := LogSample(test.n)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				logger := logging.FromContext(r.Context())
+				logger.Info().Msg("success")
+				logger.Warn().Msg("warning")
+				logger.Error().Msg("failure")
+			}))
+
+			for i := 0; i < 4; i++ {
+				r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				r = r.WithContext(base.WithContext(r.Context()))
+				handler.ServeHTTP(httptest.NewRecorder(), r)
+			}
+
+			if got := strings.Count(logs.String(), `"success"`); got != test.wantInfos {
+				t.Errorf("LogSample() info logs, got = %v, want = %v", got, test.wantInfos)
+			}
+			// warn and error are never sampled.
+			if got := strings.Count(logs.String(), `"warning"`); got != 4 {
+				t.Errorf("LogSample() warn logs, got = %v, want = 4", got)
+			}
+			if got := strings.Count(logs.String(), `"failure"`); got != 4 {
+				t.Errorf("LogSample() error logs, got = %v, want = 4", got)
+			}
+		})
+	}
+}
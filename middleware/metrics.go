@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics records prometheus metrics of the requests handled by the
+// callback handlers, labeled by handler name and http status code.
+type Metrics struct {
+	requests       *prometheus.CounterVec
+	latency        *prometheus.HistogramVec
+	acceptedErrors *prometheus.CounterVec
+}
+
+// NewMetrics returns a new Metrics with its collectors registered to reg.
+func NewMetrics(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_webhook_requests_total",
+			Help: "Number of webhook requests handled, by handler and status code.",
+		}, []string{"handler", "code"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_webhook_request_duration_seconds",
+			Help:    "Latency of webhook requests, by handler and status code.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"handler", "code"}),
+		acceptedErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_webhook_accepted_errors_total",
+			Help: "Number of webhook requests acknowledged with an error, by handler and internal code.",
+		}, []string{"handler", "code"}),
+	}
+	reg.MustRegister(m.requests, m.latency, m.acceptedErrors)
+	return m
+}
+
+// Middleware returns a middleware recording the requests of handler, requests
+// marked with AcceptedWithError are also counted by their internal code.
+func (m *Metrics) Middleware(handler string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			ctx, ae := withAcceptedError(r.Context())
+			rec := newStatusRecorder(w)
+			next.ServeHTTP(rec, r.WithContext(ctx))
+
+			code := strconv.Itoa(rec.status)
+			m.requests.WithLabelValues(handler, code).Inc()
+			m.latency.WithLabelValues(handler, code).Observe(time.Since(start).Seconds())
+			if ae.code != "" {
+				m.acceptedErrors.WithLabelValues(handler, ae.code).Inc()
+			}
+		})
+	}
+}
@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestMetrics(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetrics(prometheus.NewRegistry())
+
+	status := http.StatusOK
+	h := m.Middleware("shoptree")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	do := func() {
+		r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	do()
+	do()
+	status = http.StatusBadRequest
+	do()
+
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("shoptree", "200")); got != 2 {
+		t.Errorf("requests{code=200}, got = %v, want = 2", got)
+	}
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("shoptree", "400")); got != 1 {
+		t.Errorf("requests{code=400}, got = %v, want = 1", got)
+	}
+	if got := testutil.CollectAndCount(m.latency); got != 2 {
+		t.Errorf("latency series, got = %v, want = 2", got)
+	}
+}
+
+func TestMetrics_AcceptedWithError(t *testing.T) {
+	t.Parallel()
+
+	m := NewMetrics(prometheus.NewRegistry())
+
+	h := m.Middleware("midtrans")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		AcceptedWithError(r.Context(), "order_not_found")
+		w.WriteHeader(http.StatusOK)
+	}))
+	r, err := http.NewRequest(http.MethodPost, "/midtrans/transaction-update", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if got := testutil.ToFloat64(m.requests.WithLabelValues("midtrans", "200")); got != 1 {
+		t.Errorf("requests{code=200}, got = %v, want = 1", got)
+	}
+	if got := testutil.ToFloat64(m.acceptedErrors.WithLabelValues("midtrans", "order_not_found")); got != 1 {
+		t.Errorf("acceptedErrors{code=order_not_found}, got = %v, want = 1", got)
+	}
+}
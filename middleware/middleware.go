@@ -0,0 +1,36 @@
+// Package middleware contains http middlewares shared by the callback
+// handlers registered in the http server. Every middleware has the
+// func(http.Handler) http.Handler signature so it can be used with
+// mux.Router.Use.
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/dropezy/internal/logging"
+)
+
+// Response is the response body written by the middlewares when they
+// reject a request, it uses the same shape as the callback handlers.
+type Response struct {
+	Message string `json:"message"`
+}
+
+// responseJSON writes a JSON response with the given status code and message.
+func responseJSON(w http.ResponseWriter, r *http.Request, code int, message string) {
+	logger := logging.FromContext(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+
+	res, err := json.Marshal(&Response{Message: message})
+	if err != nil {
+		logger.Err(ErrMarshallingUnsuccessful).Msg(ErrMarshallingUnsuccessful.Error())
+	}
+
+	w.WriteHeader(code)
+
+	if _, err = w.Write(res); err != nil {
+		logger.Err(ErrWriteToResponseUnsuccessful).Msg(ErrWriteToResponseUnsuccessful.Error())
+	}
+}
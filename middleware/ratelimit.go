@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"net/http"
+
+	"golang.org/x/time/rate"
+
+	"github.com/dropezy/internal/logging"
+)
+
+// RateLimit returns a middleware rejecting with http 429 the requests above
+// limit per second, allowing bursts of burst requests. The token bucket is
+// shared by every request going through the middleware, so each provider
+// subrouter gets its own. It is disabled when limit is not positive.
+func RateLimit(limit float64, burst int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limit <= 0 {
+			return next
+		}
+		if burst < 1 {
+			burst = 1
+		}
+		limiter := rate.NewLimiter(rate.Limit(limit), burst)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !limiter.Allow() {
+				logging.FromContext(r.Context()).Warn().Str("path", r.URL.Path).Msg("rate limit exceeded")
+				responseJSON(w, r, http.StatusTooManyRequests, ErrRateLimited.Error())
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
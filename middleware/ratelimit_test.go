@@ -0,0 +1,55 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRateLimit(t *testing.T) {
+	t.Parallel()
+
+	// a very low rate so no token is refilled during the test.
+	shoptree := RateLimit(0.001, 2)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	midtrans := RateLimit(0.001, 1)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	do := func(h http.Handler) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		return w
+	}
+
+	for i, want := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		if got := do(shoptree).Code; got != want {
+			t.Fatalf("request %d, got = %v, want = %v", i, got, want)
+		}
+	}
+
+	// the limiters are independent.
+	if got := do(midtrans).Code; got != http.StatusOK {
+		t.Fatalf("other limiter, got = %v, want = %v", got, http.StatusOK)
+	}
+
+	w := do(shoptree)
+	res := &Response{}
+	if err := json.NewDecoder(w.Body).Decode(res); err != nil {
+		t.Fatal(err)
+	}
+	if res.Message != ErrRateLimited.Error() {
+		t.Fatalf("message, got = %v, want = %v", res.Message, ErrRateLimited.Error())
+	}
+}
+
+func TestRateLimit_Disabled(t *testing.T) {
+	t.Parallel()
+
+	h := RateLimit(0, 0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	for i := 0; i < 10; i++ {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("request %d, got = %v, want = %v", i, w.Code, http.StatusOK)
+		}
+	}
+}
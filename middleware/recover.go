@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"runtime/debug"
+
+	"github.com/rs/zerolog"
+
+	"github.com/dropezy/internal/logging"
+)
+
+// Recover returns a middleware recovering from panics in the wrapped handler,
+// so a single bad payload can not crash the whole server. The panic is logged
+// with its stack trace and the client gets a http 500. It is logged with the
+// context logger, so it carries the request id, or with logger when the
+// request has none.
+func Recover(logger zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				// http.ErrAbortHandler is used to abort a response on purpose.
+				if rec == http.ErrAbortHandler {
+					panic(rec)
+				}
+
+				l := logging.FromContext(r.Context())
+				if l.GetLevel() == zerolog.Disabled {
+					l = &logger
+				}
+				l.Error().
+					Str("path", r.URL.Path).
+					Str("panic", fmt.Sprint(rec)).
+					Bytes("stack", debug.Stack()).
+					Msg("recovered from panic")
+
+				responseJSON(w, r, http.StatusInternalServerError, "internal server error")
+			}()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
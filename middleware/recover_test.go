@@ -0,0 +1,78 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+func TestRecover(t *testing.T) {
+	t.Parallel()
+
+	var logs bytes.Buffer
+	logger := zerolog.New(&logs)
+
+	h := Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var orderTask *struct{ OrderID string }
+		_ = orderTask.OrderID // nil dereference
+	}))
+
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/midtrans/transaction-update", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h.ServeHTTP(w, r)
+
+	resp := w.Result()
+	if resp.StatusCode != http.StatusInternalServerError {
+		t.Fatalf("Recover(), got = %v, want = %v", resp.StatusCode, http.StatusInternalServerError)
+	}
+
+	got := &Response{}
+	if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Message != "internal server error" {
+		t.Fatalf("Recover(), got = %v, want = %v", got.Message, "internal server error")
+	}
+
+	for _, want := range []string{`"level":"error"`, `"path":"/midtrans/transaction-update"`, `"stack"`, "nil pointer dereference"} {
+		if !strings.Contains(logs.String(), want) {
+			t.Errorf("Recover() logs, want %s in %s", want, logs.String())
+		}
+	}
+}
+
+func TestRecover_ContextLogger(t *testing.T) {
+	t.Parallel()
+
+	var logs, ctxLogs bytes.Buffer
+	logger := zerolog.New(&logs)
+
+	h := RequestID(zerolog.New(&ctxLogs))(Recover(logger)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("bad payload")
+	})))
+
+	w := httptest.NewRecorder()
+	r, err := http.NewRequest(http.MethodPost, "/midtrans/transaction-update", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set(RequestIDHeader, "request-id")
+	h.ServeHTTP(w, r)
+
+	if logs.Len() != 0 {
+		t.Errorf("Recover() logs, want none with the injected logger, got %s", logs.String())
+	}
+	for _, want := range []string{`"request-id":"request-id"`, "bad payload"} {
+		if !strings.Contains(ctxLogs.String(), want) {
+			t.Errorf("Recover() logs, want %s in %s", want, ctxLogs.String())
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+)
+
+// RequestIDHeader is the header carrying the correlation ID of a request.
+const RequestIDHeader = "X-Request-Id"
+
+// maxRequestIDLen caps the length of a client provided request ID, longer IDs
+// are replaced by a generated one.
+const maxRequestIDLen = 128
+
+// validRequestID reports whether the client provided id only holds
+// [A-Za-z0-9._-], so it can't inject anything in the logs or the response
+// header.
+func validRequestID(id string) bool {
+	for i := 0; i < len(id); i++ {
+		switch c := id[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+type requestIDKey struct{}
+
+// RequestID returns a middleware tagging every request with a correlation ID.
+// The ID is read from the X-Request-Id header or generated when absent, too
+// long or holding characters outside of [A-Za-z0-9._-]; it is
+// stored on the request context, added to the context logger under
+// "request-id" and echoed back in the X-Request-Id response header.
+func RequestID(logger zerolog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := r.Header.Get(RequestIDHeader)
+			if id == "" || len(id) > maxRequestIDLen || !validRequestID(id) {
+				id = uuid.NewString()
+			}
+			w.Header().Set(RequestIDHeader, id)
+
+			l := logger.With().Str("request-id", id).Logger()
+			ctx := context.WithValue(r.Context(), requestIDKey{}, id)
+			ctx = l.WithContext(ctx)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequestIDFromContext returns the request ID stored on ctx by RequestID.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/dropezy/internal/logging"
+)
+
+func TestRequestID(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{
+			name:   "FromHeader",
+			header: "webhook-1",
+			want:   "webhook-1",
+		},
+		{
+			name: "Generated",
+		},
+		{
+			name:   "TooLong",
+			header: strings.Repeat("a", maxRequestIDLen+1),
+		},
+		{
+			name:   "Charset",
+			header: "Webhook_1.retry-2",
+			want:   "Webhook_1.retry-2",
+		},
+		{
+			name:   "InvalidCharacters",
+			header: `webhook-1","level":"error`,
+		},
+		{
+			name:   "Spaces",
+			header: "webhook 1",
+		},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var logs bytes.Buffer
+			var gotID string
+			h := RequestID(zerolog.New(&logs))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotID, _ = RequestIDFromContext(r.Context())
+				logging.FromContext(r.Context()).Info().Msg("handled")
+			}))
+
+			w := httptest.NewRecorder()
+			r, err := http.NewRequest(http.MethodPost, "/midtrans/transaction-update", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if test.header != "" {
+				r.Header.Set(RequestIDHeader, test.header)
+			}
+			h.ServeHTTP(w, r)
+
+			if gotID == "" {
+				t.Fatal("RequestID(), got empty request id on the context")
+			}
+			if test.want != "" && gotID != test.want {
+				t.Fatalf("RequestID(), got = %v, want = %v", gotID, test.want)
+			}
+			if test.header != "" && test.want == "" && gotID == test.header {
+				t.Fatalf("RequestID(), got the invalid header %v", gotID)
+			}
+			if got := w.Header().Get(RequestIDHeader); got != gotID {
+				t.Fatalf("RequestID() header, got = %v, want = %v", got, gotID)
+			}
+			if want := `"request-id":"` + gotID + `"`; !strings.Contains(logs.String(), want) {
+				t.Fatalf("RequestID() logs, want %s in %s", want, logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/dropezy/internal/logging"
+)
+
+// Verifier authenticates the webhook requests of a provider, e.g. with a
+// shared api key header or a signature over body.
+type Verifier interface {
+	Verify(r *http.Request, body []byte) error
+}
+
+// VerifierFunc is a function used as a Verifier.
+type VerifierFunc func(r *http.Request, body []byte) error
+
+// Verify calls f(r, body).
+func (f VerifierFunc) Verify(r *http.Request, body []byte) error {
+	return f(r, body)
+}
+
+// Verify returns a middleware rejecting with http 401 the requests v fails to
+// verify, the error of v is the response message. v gets the raw body
+// buffered by BufferBody, the body is buffered here with DefaultMaxBodyBytes
+// when it is not yet.
+//
+// It is set per route, so each provider plugs its own auth scheme without
+// parsing the headers in its handlers.
+func Verify(v Verifier) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		verify := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			raw, _ := RawBody(r.Context())
+			if err := v.Verify(r, raw); err != nil {
+				logging.FromContext(r.Context()).Err(err).Msg("failed to verify request")
+				responseJSON(w, r, http.StatusUnauthorized, err.Error())
+				return
+			}
+
+			ResetBody(r)
+			next.ServeHTTP(w, r)
+		})
+		buffered := BufferBody(DefaultMaxBodyBytes)(verify)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, ok := RawBody(r.Context()); ok {
+				verify.ServeHTTP(w, r)
+				return
+			}
+			buffered.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var errInvalidKey = errors.New("invalid key")
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+
+	const body = `{"order_id":"payment-task-id"}`
+
+	// verifier accepts the requests with the key header over a non empty
+	// body.
+	verifier := VerifierFunc(func(r *http.Request, b []byte) error {
+		if string(b) != body {
+			return errors.New("unexpected body")
+		}
+		if r.Header.Get("X-Key") != "secret" {
+			return errInvalidKey
+		}
+		return nil
+	})
+
+	tests := []struct {
+		name     string
+		key      string
+		buffered bool
+		wantCode int
+	}{
+		{name: "Valid", key: "secret", wantCode: http.StatusOK},
+		{name: "ValidBuffered", key: "secret", buffered: true, wantCode: http.StatusOK},
+		{name: "Invalid", key: "other", wantCode: http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		test := test
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				// the handler still reads the whole body.
+				b, err := io.ReadAll(r.Body)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(b) != body {
+					t.Errorf("Verify() body, got = %q, want = %q", b, body)
+				}
+				w.WriteHeader(http.StatusOK)
+			})
+			handler = Verify(verifier)(handler)
+			if test.buffered {
+				handler = BufferBody(DefaultMaxBodyBytes)(handler)
+			}
+
+			r, err := http.NewRequest(http.MethodPost, "/midtrans/transaction-update", strings.NewReader(body))
+			if err != nil {
+				t.Fatal(err)
+			}
+			r.Header.Set("X-Key", test.key)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+
+			resp := w.Result()
+			if resp.StatusCode != test.wantCode {
+				t.Fatalf("Verify(), got = %v, want = %v", resp.StatusCode, test.wantCode)
+			}
+			if test.wantCode == http.StatusOK {
+				return
+			}
+
+			got := &Response{}
+			if err := json.NewDecoder(resp.Body).Decode(got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Message != errInvalidKey.Error() {
+				t.Fatalf("Verify() message, got = %q, want = %q", got.Message, errInvalidKey.Error())
+			}
+		})
+	}
+}
+
+func TestVerify_UnbufferedTooLarge(t *testing.T) {
+	t.Parallel()
+
+	verifier := VerifierFunc(func(*http.Request, []byte) error {
+		t.Error("Verify() called the verifier with an oversized body")
+		return nil
+	})
+	handler := Verify(verifier)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Verify() called the handler with an oversized body")
+	}))
+
+	body := strings.Repeat("a", DefaultMaxBodyBytes+1)
+	r := httptest.NewRequest(http.MethodPost, "/shoptree/stock-update", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, r)
+
+	if got := w.Result().StatusCode; got != http.StatusRequestEntityTooLarge {
+		t.Fatalf("Verify(), got = %v, want = %v", got, http.StatusRequestEntityTooLarge)
+	}
+}
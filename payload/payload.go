@@ -0,0 +1,165 @@
+// Package payload keeps the raw bodies of the received callbacks, so a
+// failed callback can be reproduced with the exact bytes the provider sent.
+package payload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/dropezy/internal/logging"
+	"github.com/dropezy/storefront-backend/http/middleware"
+)
+
+// ErrPayloadNotFound happens when loading a payload that was not stored.
+var ErrPayloadNotFound = errors.New("payload not found")
+
+// redactedHeaders are the headers holding secrets, they are not stored.
+var redactedHeaders = []string{
+	"Authorization",
+	"Cookie",
+	"X-Api-Key",
+	"X-Client-Api-Key",
+	"X-Admin-Key",
+}
+
+// Meta describes a stored payload.
+type Meta struct {
+	// ID identifies the payload among the ones of its provider.
+	ID         string      `json:"id"`
+	Method     string      `json:"method"`
+	Path       string      `json:"path"`
+	Header     http.Header `json:"header"`
+	RequestID  string      `json:"request_id,omitempty"`
+	ReceivedAt time.Time   `json:"received_at"`
+}
+
+// Sink stores the raw callback payloads. Implementations must be safe for
+// concurrent use.
+type Sink interface {
+	// Store stores body received from provider.
+	Store(ctx context.Context, provider string, body []byte, meta Meta) error
+}
+
+// Loader loads the payloads stored by a Sink.
+type Loader interface {
+	// Load returns the payload id of provider, or ErrPayloadNotFound.
+	Load(ctx context.Context, provider, id string) (*Record, error)
+}
+
+// NopSink is a Sink discarding the payloads.
+type NopSink struct{}
+
+// Store implements Sink.
+func (NopSink) Store(context.Context, string, []byte, Meta) error { return nil }
+
+// Record is a payload with its meta as written by FileSink.
+type Record struct {
+	Meta
+	Provider string `json:"provider"`
+	Body     []byte `json:"body"`
+}
+
+// FileSink stores each payload as a JSON Record in <dir>/<provider>/<id>.json.
+type FileSink struct {
+	dir string
+}
+
+// NewFileSink returns a new FileSink writing to dir.
+func NewFileSink(dir string) *FileSink {
+	return &FileSink{dir: dir}
+}
+
+// Store implements Sink.
+func (s *FileSink) Store(_ context.Context, provider string, body []byte, meta Meta) error {
+	dir := filepath.Join(s.dir, filepath.Base(provider))
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(&Record{Meta: meta, Provider: provider, Body: body})
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, filepath.Base(meta.ID)+".json"), b, 0o600)
+}
+
+// Load implements Loader.
+func (s *FileSink) Load(_ context.Context, provider, id string) (*Record, error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, filepath.Base(provider), filepath.Base(id)+".json"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrPayloadNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rec := &Record{}
+	if err := json.Unmarshal(b, rec); err != nil {
+		return nil, err
+	}
+	return rec, nil
+}
+
+// Request rebuilds the http request of rec, its redacted headers are not
+// restored.
+func (rec *Record) Request(ctx context.Context) (*http.Request, error) {
+	r, err := http.NewRequestWithContext(ctx, rec.Method, rec.Path, bytes.NewReader(rec.Body))
+	if err != nil {
+		return nil, err
+	}
+	r.Header = rec.Header.Clone()
+	if r.Header == nil {
+		r.Header = http.Header{}
+	}
+	return r, nil
+}
+
+// Middleware returns a middleware storing the body of each request of
+// provider in sink before it is handled, the body must be buffered by
+// middleware.BufferBody first. It must come after the middleware.Verify of
+// provider, so the unauthenticated requests don't fill sink. Failing to store
+// a payload doesn't fail the request.
+//
+// The redactedHeaders are never stored, nor the redact headers, e.g. the
+// configurable api key header of provider. The empty names are ignored.
+func Middleware(sink Sink, provider string, redact ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, ok := middleware.RawBody(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			meta := Meta{
+				ID:         uuid.NewString(),
+				Method:     r.Method,
+				Path:       r.URL.RequestURI(),
+				Header:     r.Header.Clone(),
+				ReceivedAt: time.Now().UTC(),
+			}
+			for _, h := range redactedHeaders {
+				meta.Header.Del(h)
+			}
+			for _, h := range redact {
+				if h != "" {
+					meta.Header.Del(h)
+				}
+			}
+			meta.RequestID, _ = middleware.RequestIDFromContext(r.Context())
+
+			if err := sink.Store(r.Context(), provider, body, meta); err != nil {
+				logging.FromContext(r.Context()).Err(err).Str("provider", provider).Msg("failed to store callback payload")
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package payload
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+
+	"github.com/dropezy/storefront-backend/http/middleware"
+)
+
+// memorySink keeps the stored payloads in memory.
+type memorySink struct {
+	mu      sync.Mutex
+	records []*Record
+}
+
+func (s *memorySink) Store(_ context.Context, provider string, body []byte, meta Meta) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, &Record{Meta: meta, Provider: provider, Body: body})
+	return nil
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Parallel()
+
+	const body = `[{"reference_id": "ref-1"}]`
+
+	sink := &memorySink{}
+	var handled []byte
+	h := middleware.BufferBody(middleware.DefaultMaxBodyBytes)(
+		Middleware(sink, "shoptree")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the handler still reads the whole body.
+			handled, _ = io.ReadAll(r.Body)
+		})),
+	)
+
+	r, err := http.NewRequest(http.MethodPost, "/shoptree/stock-update?source=test", bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Client-Api-Key", "secret")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if string(handled) != body {
+		t.Fatalf("handler body, got = %s, want = %s", handled, body)
+	}
+	if len(sink.records) != 1 {
+		t.Fatalf("stored payloads, got = %d, want = 1", len(sink.records))
+	}
+	got := sink.records[0]
+	if got.Provider != "shoptree" || string(got.Body) != body {
+		t.Fatalf("stored payload, got = %s %s", got.Provider, got.Body)
+	}
+	if got.ID == "" || got.Method != http.MethodPost || got.Path != "/shoptree/stock-update?source=test" {
+		t.Fatalf("stored meta, got = %+v", got.Meta)
+	}
+	if v := got.Header.Get("X-Client-Api-Key"); v != "" {
+		t.Fatalf("stored api key header, got = %q, want it redacted", v)
+	}
+	if v := got.Header.Get("Content-Type"); v != "application/json" {
+		t.Fatalf("stored content type, got = %q", v)
+	}
+}
+
+func TestMiddleware_Redact(t *testing.T) {
+	t.Parallel()
+
+	sink := &memorySink{}
+	h := middleware.BufferBody(middleware.DefaultMaxBodyBytes)(
+		Middleware(sink, "midtrans", "X-Shared-Key", "")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the handler still gets the redacted headers.
+			if v := r.Header.Get("X-Shared-Key"); v != "secret" {
+				t.Errorf("handler header, got = %q, want = secret", v)
+			}
+		})),
+	)
+
+	r, err := http.NewRequest(http.MethodPost, "/midtrans/transaction-update", bytes.NewBufferString(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Content-Type", "application/json")
+	r.Header.Set("X-Shared-Key", "secret")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	if len(sink.records) != 1 {
+		t.Fatalf("stored payloads, got = %d, want = 1", len(sink.records))
+	}
+	got := sink.records[0]
+	if v := got.Header.Get("X-Shared-Key"); v != "" {
+		t.Fatalf("stored custom header, got = %q, want it redacted", v)
+	}
+	if v := got.Header.Get("Content-Type"); v != "application/json" {
+		t.Fatalf("stored content type, got = %q", v)
+	}
+}
+
+func TestFileSink(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	sink := NewFileSink(dir)
+
+	meta := Meta{ID: "payload-1", Method: http.MethodPost, Path: "/midtrans/transaction-update"}
+	if err := sink.Store(context.Background(), "midtrans", []byte(`{"order_id":"1"}`), meta); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, "midtrans", "payload-1.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := &Record{}
+	if err := json.Unmarshal(b, got); err != nil {
+		t.Fatal(err)
+	}
+	want := &Record{Meta: meta, Provider: "midtrans", Body: []byte(`{"order_id":"1"}`)}
+	if diff := cmp.Diff(want, got); diff != "" {
+		t.Fatalf("stored record mismatch (-want +got):\n%s", diff)
+	}
+
+	loaded, err := sink.Load(context.Background(), "midtrans", "payload-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(want, loaded); diff != "" {
+		t.Fatalf("loaded record mismatch (-want +got):\n%s", diff)
+	}
+	if _, err := sink.Load(context.Background(), "midtrans", "unknown"); !errors.Is(err, ErrPayloadNotFound) {
+		t.Fatalf("Load(unknown), got = %v, want = %v", err, ErrPayloadNotFound)
+	}
+}
@@ -0,0 +1,72 @@
+package selftest
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+
+	// protobuf
+	inpb "github.com/dropezy/proto/v1/inventory"
+	opb "github.com/dropezy/proto/v1/order"
+	tpb "github.com/dropezy/proto/v1/task"
+)
+
+// DryRunTaskID is the order task returned by the dry-run task client.
+const DryRunTaskID = "selftest-task-id"
+
+// TaskClient is a dry-run task service client, it returns a pending task of
+// the requested type and discards the updates. The other methods are not
+// implemented and panic.
+type TaskClient struct {
+	tpb.TaskServiceClient
+
+	// TaskType is the type of the returned task.
+	TaskType tpb.OrderTaskType
+}
+
+func (c *TaskClient) GetOrderTask(ctx context.Context, in *tpb.GetOrderTaskRequest, opts ...grpc.CallOption) (*tpb.GetOrderTaskResponse, error) {
+	return &tpb.GetOrderTaskResponse{
+		Tasks: []*tpb.OrderTask{{
+			TaskId:   DryRunTaskID,
+			OrderId:  in.OrderId,
+			TaskType: c.TaskType,
+		}},
+	}, nil
+}
+
+func (c *TaskClient) UpdateOrderTask(ctx context.Context, in *tpb.UpdateOrderTaskRequest, opts ...grpc.CallOption) (*tpb.UpdateOrderTaskResponse, error) {
+	return &tpb.UpdateOrderTaskResponse{}, nil
+}
+
+// DryRunOrderTotal is the total amount of the order returned by the dry-run
+// order client.
+const DryRunOrderTotal = 1
+
+// OrderClient is a dry-run order service client, it returns an order waiting
+// for its payment of DryRunOrderTotal. The other methods are not implemented
+// and panic.
+type OrderClient struct {
+	opb.OrderServiceClient
+}
+
+func (c *OrderClient) Get(ctx context.Context, in *opb.GetRequest, opts ...grpc.CallOption) (*opb.GetResponse, error) {
+	return &opb.GetResponse{OrderData: &opb.OrderData{Order: &opb.Order{
+		OrderId:     in.OrderId,
+		State:       opb.OrderState_ORDER_STATE_WAITING_FOR_PAYMENT,
+		TotalAmount: DryRunOrderTotal,
+	}}}, nil
+}
+
+// InventoryClient is a dry-run inventory service client discarding the
+// updates. The other methods are not implemented and panic.
+type InventoryClient struct {
+	inpb.InventoryServiceClient
+}
+
+func (c *InventoryClient) UpdateStock(ctx context.Context, in *inpb.UpdateStockRequest, opts ...grpc.CallOption) (*inpb.UpdateStockResponse, error) {
+	return &inpb.UpdateStockResponse{}, nil
+}
+
+func (c *InventoryClient) UpdateStatus(ctx context.Context, in *inpb.UpdateStatusRequest, opts ...grpc.CallOption) (*inpb.UpdateStatusResponse, error) {
+	return &inpb.UpdateStatusResponse{}, nil
+}
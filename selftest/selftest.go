@@ -0,0 +1,129 @@
+// Package selftest checks a provider webhook setup end-to-end: the backend
+// is reachable, the keys are configured and a canned valid payload goes
+// through the handler in dry-run mode.
+package selftest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+
+	"google.golang.org/grpc/connectivity"
+
+	"github.com/dropezy/storefront-backend/http/health"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown provider")
+	ErrNotConfigured   = errors.New("not configured")
+)
+
+// Step is a single check of a self test.
+type Step struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// StepResult is the result of a Step.
+type StepResult struct {
+	Name  string `json:"name"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+// Runner runs the self test steps registered per provider.
+type Runner struct {
+	providers map[string][]Step
+}
+
+// NewRunner returns a new Runner without any provider.
+func NewRunner() *Runner {
+	return &Runner{providers: map[string][]Step{}}
+}
+
+// Register adds the steps of provider, they run in the given order.
+func (r *Runner) Register(provider string, steps ...Step) {
+	r.providers[provider] = append(r.providers[provider], steps...)
+}
+
+// Providers returns the registered providers sorted by name.
+func (r *Runner) Providers() []string {
+	providers := make([]string, 0, len(r.providers))
+	for p := range r.providers {
+		providers = append(providers, p)
+	}
+	sort.Strings(providers)
+	return providers
+}
+
+// Run runs every step of provider, a failing step doesn't stop the next
+// ones so the report is complete.
+func (r *Runner) Run(ctx context.Context, provider string) ([]*StepResult, error) {
+	steps, ok := r.providers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	results := make([]*StepResult, 0, len(steps))
+	for _, s := range steps {
+		res := &StepResult{Name: s.Name, OK: true}
+		if err := s.Check(ctx); err != nil {
+			res.OK = false
+			res.Error = err.Error()
+		}
+		results = append(results, res)
+	}
+	return results, nil
+}
+
+// GRPC checks the backend connection is usable.
+func GRPC(conn health.ConnStater) Step {
+	return Step{
+		Name: "grpc",
+		Check: func(ctx context.Context) error {
+			switch s := conn.GetState(); s {
+			case connectivity.TransientFailure, connectivity.Shutdown:
+				return fmt.Errorf("grpc connection is %s", s)
+			}
+			return nil
+		},
+	}
+}
+
+// Config checks the config key holds a value.
+func Config(key, value string) Step {
+	return Step{
+		Name: "config:" + key,
+		Check: func(ctx context.Context) error {
+			if value == "" {
+				return fmt.Errorf("%s: %w", key, ErrNotConfigured)
+			}
+			return nil
+		},
+	}
+}
+
+// DryRun serves the request returned by newRequest with handler, which must
+// be built with the dry-run clients of this package so nothing is written to
+// the backend. The step fails unless the handler responds with http 2xx.
+func DryRun(handler http.Handler, newRequest func(ctx context.Context) (*http.Request, error)) Step {
+	return Step{
+		Name: "dry_run",
+		Check: func(ctx context.Context) error {
+			r, err := newRequest(ctx)
+			if err != nil {
+				return err
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code < 200 || w.Code > 299 {
+				return fmt.Errorf("handler responded with http %d: %s", w.Code, w.Body.String())
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,70 @@
+package selftest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"google.golang.org/grpc/connectivity"
+)
+
+type connState connectivity.State
+
+func (s connState) GetState() connectivity.State { return connectivity.State(s) }
+
+func TestRunner(t *testing.T) {
+	t.Parallel()
+
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+	badRequest := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	})
+	newRequest := func(ctx context.Context) (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodPost, "/shoptree/stock-update", nil)
+	}
+
+	r := NewRunner()
+	r.Register("shoptree",
+		GRPC(connState(connectivity.Ready)),
+		Config("shoptree.authKey", "key"),
+		DryRun(ok, newRequest),
+	)
+	r.Register("midtrans",
+		GRPC(connState(connectivity.TransientFailure)),
+		Config("midtrans.serverKey", ""),
+		DryRun(badRequest, newRequest),
+	)
+
+	if got, want := r.Providers(), []string{"midtrans", "shoptree"}; !cmp.Equal(got, want) {
+		t.Fatalf("Providers(), got = %v, want = %v", got, want)
+	}
+
+	got, err := r.Run(context.Background(), "shoptree")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []*StepResult{
+		{Name: "grpc", OK: true},
+		{Name: "config:shoptree.authKey", OK: true},
+		{Name: "dry_run", OK: true},
+	}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("Run(shoptree), got = %v", cmp.Diff(want, got))
+	}
+
+	got, err = r.Run(context.Background(), "midtrans")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range got {
+		if res.OK || res.Error == "" {
+			t.Errorf("Run(midtrans), got = %+v, want a failed step", res)
+		}
+	}
+
+	if _, err := r.Run(context.Background(), "unknown"); !errors.Is(err, ErrUnknownProvider) {
+		t.Fatalf("Run(unknown), got = %v, want = %v", err, ErrUnknownProvider)
+	}
+}
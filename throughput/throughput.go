@@ -0,0 +1,127 @@
+// Package throughput counts the webhooks received per provider over rolling
+// time windows, it complements the prometheus metrics with a human readable
+// snapshot served by the admin endpoints.
+package throughput
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// buckets is the number of one minute buckets kept per provider, enough to
+// cover the longest window.
+const buckets = 24 * 60
+
+// Windows reported by Snapshot, in minutes.
+const (
+	minuteWindow = 1
+	hourWindow   = 60
+	dayWindow    = 24 * 60
+)
+
+// bucket counts the requests of a single minute.
+type bucket struct {
+	minute int64
+	count  uint64
+}
+
+// ring is the rolling window of a single provider.
+type ring [buckets]bucket
+
+// add records n requests at minute.
+func (r *ring) add(minute int64, n uint64) {
+	b := &r[minute%buckets]
+	if b.minute != minute {
+		b.minute = minute
+		b.count = 0
+	}
+	b.count += n
+}
+
+// sum returns the requests recorded over the last window minutes, the
+// current minute included.
+func (r *ring) sum(minute int64, window int64) uint64 {
+	var total uint64
+	for _, b := range r {
+		if b.minute > minute-window && b.minute <= minute {
+			total += b.count
+		}
+	}
+	return total
+}
+
+// Counts are the requests of a provider over the last minute, hour and day.
+// The windows have a one minute resolution and include the current minute.
+type Counts struct {
+	Provider string `json:"provider"`
+	Minute   uint64 `json:"last_minute"`
+	Hour     uint64 `json:"last_hour"`
+	Day      uint64 `json:"last_day"`
+}
+
+// Counter counts requests per provider, it is safe for concurrent use. The
+// memory used is bounded by the number of providers, which are the fixed
+// handler names.
+type Counter struct {
+	mu        sync.Mutex
+	providers map[string]*ring
+
+	now func() time.Time
+}
+
+// NewCounter returns a new Counter.
+func NewCounter() *Counter {
+	return &Counter{
+		providers: map[string]*ring{},
+		now:       time.Now,
+	}
+}
+
+// Record records a request of provider.
+func (c *Counter) Record(provider string) {
+	minute := c.now().Unix() / 60
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, ok := c.providers[provider]
+	if !ok {
+		r = &ring{}
+		c.providers[provider] = r
+	}
+	r.add(minute, 1)
+}
+
+// Snapshot returns the counts of every provider sorted by provider name.
+func (c *Counter) Snapshot() []*Counts {
+	minute := c.now().Unix() / 60
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	counts := make([]*Counts, 0, len(c.providers))
+	for provider, r := range c.providers {
+		counts = append(counts, &Counts{
+			Provider: provider,
+			Minute:   r.sum(minute, minuteWindow),
+			Hour:     r.sum(minute, hourWindow),
+			Day:      r.sum(minute, dayWindow),
+		})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Provider < counts[j].Provider
+	})
+	return counts
+}
+
+// Middleware returns a middleware recording every request under provider.
+func (c *Counter) Middleware(provider string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			c.Record(provider)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
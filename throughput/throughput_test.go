@@ -0,0 +1,82 @@
+package throughput
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCounter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2022, 6, 1, 12, 0, 30, 0, time.UTC)
+	c := NewCounter()
+	c.now = func() time.Time { return now }
+
+	at := func(d time.Duration, provider string, n int) {
+		now = now.Add(d)
+		for i := 0; i < n; i++ {
+			c.Record(provider)
+		}
+	}
+
+	at(0, "midtrans", 3)
+	at(0, "shoptree", 1)
+	// 30 minutes later
+	at(30*time.Minute, "midtrans", 2)
+	// 2 hours after the beginning, the first requests left the hour window
+	at(90*time.Minute, "midtrans", 1)
+
+	want := []*Counts{
+		{Provider: "midtrans", Minute: 1, Hour: 1, Day: 6},
+		{Provider: "shoptree", Minute: 0, Hour: 0, Day: 1},
+	}
+	if got := c.Snapshot(); !cmp.Equal(got, want) {
+		t.Fatalf("Snapshot(), got = %v", cmp.Diff(want, got))
+	}
+
+	// almost a day later only the last request is left in the day window,
+	// the reused buckets must not keep the counts of the previous day.
+	at(24*time.Hour-time.Minute, "midtrans", 1)
+	want = []*Counts{
+		{Provider: "midtrans", Minute: 1, Hour: 1, Day: 2},
+		{Provider: "shoptree", Minute: 0, Hour: 0, Day: 0},
+	}
+	if got := c.Snapshot(); !cmp.Equal(got, want) {
+		t.Fatalf("Snapshot(), got = %v", cmp.Diff(want, got))
+	}
+
+	// the next minute, the last minute window is empty again and the request
+	// of the previous day expired.
+	at(time.Minute, "shoptree", 0)
+	want = []*Counts{
+		{Provider: "midtrans", Minute: 0, Hour: 1, Day: 1},
+		{Provider: "shoptree", Minute: 0, Hour: 0, Day: 0},
+	}
+	if got := c.Snapshot(); !cmp.Equal(got, want) {
+		t.Fatalf("Snapshot(), got = %v", cmp.Diff(want, got))
+	}
+}
+
+func TestCounter_Middleware(t *testing.T) {
+	t.Parallel()
+
+	c := NewCounter()
+	h := c.Middleware("mileapp")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for i := 0; i < 2; i++ {
+		r, err := http.NewRequest(http.MethodPost, "/mileapp/status/delivery", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), r)
+	}
+
+	want := []*Counts{{Provider: "mileapp", Minute: 2, Hour: 2, Day: 2}}
+	if got := c.Snapshot(); !cmp.Equal(got, want) {
+		t.Fatalf("Snapshot(), got = %v", cmp.Diff(want, got))
+	}
+}